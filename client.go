@@ -161,6 +161,23 @@ func newClient(
 				return nil, fmt.Errorf("%s is not a valid QUIC version", v)
 			}
 		}
+		if err := validateTransportParameters(config.TransportParameters); err != nil {
+			return nil, err
+		}
+		if err := validateConnectionIDLength(config.ConnectionIDLength); err != nil {
+			return nil, err
+		}
+		if err := validateDSCP(config.DSCP); err != nil {
+			return nil, err
+		}
+		if err := validateInitialRTT(config.InitialRTT); err != nil {
+			return nil, err
+		}
+		if config.DSCP != 0 {
+			if err := setDSCP(pconn, config.DSCP); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	srcConnID, err := generateConnectionID(config.ConnectionIDLength)
@@ -174,7 +191,7 @@ func newClient(
 	c := &client{
 		srcConnID:         srcConnID,
 		destConnID:        destConnID,
-		conn:              &conn{pconn: pconn, currentAddr: remoteAddr},
+		conn:              newConn(pconn, remoteAddr, config.EnableGSO),
 		createdPacketConn: createdPacketConn,
 		tlsConf:           tlsConf,
 		config:            config,
@@ -225,6 +242,14 @@ func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxAckRanges := config.MaxAckRanges
+	if maxAckRanges <= 0 {
+		maxAckRanges = protocol.DefaultMaxAckRanges
+	}
+	ptoProbePackets := config.PTOProbePackets
+	if ptoProbePackets <= 0 {
+		ptoProbePackets = protocol.DefaultNumPTOProbePackets
+	}
 	connIDLen := config.ConnectionIDLength
 	if connIDLen == 0 && !createdPacketConn {
 		connIDLen = protocol.DefaultConnectionIDLength
@@ -239,7 +264,17 @@ func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
 		MaxIncomingStreams:                    maxIncomingStreams,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
+		MaxAckRanges:                          maxAckRanges,
+		TransportParameters:                   config.TransportParameters,
 		KeepAlive:                             config.KeepAlive,
+		WriteCoalescingDelay:                  config.WriteCoalescingDelay,
+		EnableGSO:                             config.EnableGSO,
+		PTOProbePackets:                       ptoProbePackets,
+		PTOProbeWithPing:                      config.PTOProbeWithPing,
+		DSCP:                                  config.DSCP,
+		KeyUpdateInterval:                     config.KeyUpdateInterval,
+		KeyUpdateBytes:                        config.KeyUpdateBytes,
+		InitialRTT:                            config.InitialRTT,
 		StatelessResetKey:                     config.StatelessResetKey,
 	}
 }
@@ -353,7 +388,9 @@ func (c *client) createNewTLSSession(version protocol.VersionNumber) error {
 		MaxBidiStreams:                 uint64(c.config.MaxIncomingStreams),
 		MaxUniStreams:                  uint64(c.config.MaxIncomingUniStreams),
 		AckDelayExponent:               protocol.AckDelayExponent,
+		MaxAckDelay:                    protocol.DefaultMaxAckDelay,
 		DisableMigration:               true,
+		CustomParameters:               marshalTransportParameters(c.config.TransportParameters),
 	}
 
 	c.mutex.Lock()
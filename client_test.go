@@ -153,6 +153,39 @@ var _ = Describe("Client", func() {
 			Eventually(remoteAddrChan).Should(Receive(Equal("127.0.0.1:17890")))
 		})
 
+		It("uses a zero-length connection ID when dialing an address", func() {
+			generateConnectionID = origGenerateConnectionID
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Add(gomock.Any(), gomock.Any())
+			manager.EXPECT().Close()
+			mockMultiplexer.EXPECT().AddConn(gomock.Any(), 0, gomock.Any()).Return(manager, nil)
+
+			srcConnIDChan := make(chan protocol.ConnectionID, 1)
+			newClientSession = func(
+				_ connection,
+				_ sessionRunner,
+				_ protocol.ConnectionID,
+				srcConnID protocol.ConnectionID,
+				_ *Config,
+				_ *tls.Config,
+				_ protocol.PacketNumber,
+				_ *handshake.TransportParameters,
+				_ protocol.VersionNumber,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) (quicSession, error) {
+				srcConnIDChan <- srcConnID
+				sess := NewMockQuicSession(mockCtrl)
+				sess.EXPECT().run()
+				return sess, nil
+			}
+			_, err := DialAddr("localhost:17890", nil, &Config{ConnectionIDLength: 0})
+			Expect(err).ToNot(HaveOccurred())
+			var srcConnID protocol.ConnectionID
+			Eventually(srcConnIDChan).Should(Receive(&srcConnID))
+			Expect(srcConnID.Len()).To(BeZero())
+		})
+
 		It("uses the tls.Config.ServerName as the hostname, if present", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
@@ -401,6 +434,13 @@ var _ = Describe("Client", func() {
 					MaxIncomingStreams:    1234,
 					MaxIncomingUniStreams: 4321,
 					ConnectionIDLength:    13,
+					MaxAckRanges:          17,
+					PTOProbePackets:       5,
+					PTOProbeWithPing:      true,
+					DSCP:                  46,
+					KeyUpdateInterval:     1000,
+					KeyUpdateBytes:        1 << 20,
+					InitialRTT:            42 * time.Millisecond,
 					StatelessResetKey:     []byte("foobar"),
 				}
 				c := populateClientConfig(config, false)
@@ -409,6 +449,13 @@ var _ = Describe("Client", func() {
 				Expect(c.MaxIncomingStreams).To(Equal(1234))
 				Expect(c.MaxIncomingUniStreams).To(Equal(4321))
 				Expect(c.ConnectionIDLength).To(Equal(13))
+				Expect(c.MaxAckRanges).To(Equal(17))
+				Expect(c.PTOProbePackets).To(Equal(5))
+				Expect(c.PTOProbeWithPing).To(BeTrue())
+				Expect(c.DSCP).To(BeEquivalentTo(46))
+				Expect(c.KeyUpdateInterval).To(BeEquivalentTo(1000))
+				Expect(c.KeyUpdateBytes).To(BeEquivalentTo(1 << 20))
+				Expect(c.InitialRTT).To(Equal(42 * time.Millisecond))
 				Expect(c.StatelessResetKey).To(Equal([]byte("foobar")))
 			})
 
@@ -421,6 +468,38 @@ var _ = Describe("Client", func() {
 				Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
 			})
 
+			It("errors when the Config contains an invalid transport parameter", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", &tls.Config{}, &Config{TransportParameters: []TransportParameter{{ID: 0x42}}})
+				Expect(err).To(MatchError("transport parameter ID 0x42 is not in the private-use range"))
+			})
+
+			It("errors when the Config contains an invalid connection ID length", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", &tls.Config{}, &Config{ConnectionIDLength: 2})
+				Expect(err).To(MatchError("invalid connection ID length: 2 (must be 0, or between 4 and 18)"))
+			})
+
+			It("errors when the Config contains an invalid DSCP", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", &tls.Config{}, &Config{DSCP: 64})
+				Expect(err).To(MatchError("invalid DSCP: 64 (maximum 63)"))
+			})
+
+			It("errors when the Config contains a negative initial RTT", func() {
+				manager := NewMockPacketHandlerManager(mockCtrl)
+				mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+				_, err := Dial(packetConn, nil, "localhost:1234", &tls.Config{}, &Config{InitialRTT: -time.Millisecond})
+				Expect(err).To(MatchError("invalid initial RTT: -1ms (must be positive)"))
+			})
+
 			It("disables bidirectional streams", func() {
 				config := &Config{
 					MaxIncomingStreams:    -1,
@@ -6,7 +6,9 @@ import (
 )
 
 type connection interface {
+	// Write sends a packet. It might be batched with other packets, until Flush is called.
 	Write([]byte) error
+	Flush() error
 	Read([]byte) (int, net.Addr, error)
 	Close() error
 	LocalAddr() net.Addr
@@ -19,15 +21,38 @@ type conn struct {
 
 	pconn       net.PacketConn
 	currentAddr net.Addr
+
+	gso *gsoBatch // nil if GSO is not used
 }
 
 var _ connection = &conn{}
 
+func newConn(pconn net.PacketConn, remoteAddr net.Addr, enableGSO bool) *conn {
+	c := &conn{pconn: pconn, currentAddr: remoteAddr}
+	if enableGSO {
+		if writeGSO := newGSOWriteFunc(pconn); writeGSO != nil {
+			c.gso = newGSOBatch(writeGSO, pconn.WriteTo)
+		}
+	}
+	return c
+}
+
 func (c *conn) Write(p []byte) error {
-	_, err := c.pconn.WriteTo(p, c.currentAddr)
+	if c.gso != nil {
+		return c.gso.Add(p, c.RemoteAddr())
+	}
+	_, err := c.pconn.WriteTo(p, c.RemoteAddr())
 	return err
 }
 
+// Flush sends all packets that were batched for sending using GSO.
+func (c *conn) Flush() error {
+	if c.gso == nil {
+		return nil
+	}
+	return c.gso.Flush()
+}
+
 func (c *conn) Read(p []byte) (int, net.Addr, error) {
 	return c.pconn.ReadFrom(p)
 }
@@ -19,6 +19,7 @@ type mockPacketConn struct {
 	dataToRead   chan []byte
 	dataReadFrom net.Addr
 	readErr      error
+	readErrs     chan error // errors returned by ReadFrom, before the next packet is read
 	dataWritten  chan mockPacketConnWrite
 	closed       bool
 }
@@ -26,6 +27,7 @@ type mockPacketConn struct {
 func newMockPacketConn() *mockPacketConn {
 	return &mockPacketConn{
 		dataToRead:  make(chan []byte, 1000),
+		readErrs:    make(chan error, 10),
 		dataWritten: make(chan mockPacketConnWrite, 1000),
 	}
 }
@@ -34,7 +36,13 @@ func (c *mockPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
 	if c.readErr != nil {
 		return 0, nil, c.readErr
 	}
-	data, ok := <-c.dataToRead
+	var data []byte
+	var ok bool
+	select {
+	case err := <-c.readErrs:
+		return 0, nil, err
+	case data, ok = <-c.dataToRead:
+	}
 	if !ok {
 		return 0, nil, errors.New("connection closed")
 	}
@@ -89,6 +97,18 @@ var _ = Describe("Connection", func() {
 		Expect(write.data).To(Equal([]byte("foobar")))
 	})
 
+	It("doesn't batch packets if GSO is not used", func() {
+		Expect(c.Write([]byte("foo"))).To(Succeed())
+		Expect(packetConn.dataWritten).To(Receive())
+		Expect(c.Flush()).To(Succeed())
+		Expect(packetConn.dataWritten).ToNot(Receive())
+	})
+
+	It("doesn't use GSO for connections that are not UDP connections", func() {
+		c = newConn(packetConn, c.RemoteAddr(), true)
+		Expect(c.gso).To(BeNil())
+	})
+
 	It("reads", func() {
 		packetConn.dataToRead <- []byte("foo")
 		packetConn.dataReadFrom = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1336}
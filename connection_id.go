@@ -0,0 +1,19 @@
+package quic
+
+import (
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// validateConnectionIDLength checks that a connection ID of the configured length can be encoded in the long header.
+// A length of 0 is valid, and means that a zero-length connection ID is used.
+func validateConnectionIDLength(l int) error {
+	if l == 0 {
+		return nil
+	}
+	if l < protocol.MinConnectionIDLen || l > protocol.MaxConnectionIDLen {
+		return fmt.Errorf("invalid connection ID length: %d (must be 0, or between %d and %d)", l, protocol.MinConnectionIDLen, protocol.MaxConnectionIDLen)
+	}
+	return nil
+}
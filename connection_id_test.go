@@ -0,0 +1,24 @@
+package quic
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection ID length", func() {
+	It("accepts zero-length connection IDs", func() {
+		Expect(validateConnectionIDLength(0)).To(Succeed())
+	})
+
+	It("accepts lengths that can be encoded in the long header", func() {
+		for l := 4; l <= 18; l++ {
+			Expect(validateConnectionIDLength(l)).To(Succeed())
+		}
+	})
+
+	It("rejects lengths that can't be encoded in the long header", func() {
+		Expect(validateConnectionIDLength(1)).To(MatchError("invalid connection ID length: 1 (must be 0, or between 4 and 18)"))
+		Expect(validateConnectionIDLength(3)).To(MatchError("invalid connection ID length: 3 (must be 0, or between 4 and 18)"))
+		Expect(validateConnectionIDLength(19)).To(MatchError("invalid connection ID length: 19 (must be 0, or between 4 and 18)"))
+	})
+})
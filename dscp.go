@@ -0,0 +1,39 @@
+package quic
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// maxDSCP is the largest DSCP value.
+// The DSCP occupies the upper 6 bits of the IPv4 TOS and the IPv6 Traffic Class field.
+const maxDSCP = 63
+
+// validateDSCP checks that the configured DSCP value fits into 6 bits.
+func validateDSCP(dscp uint8) error {
+	if dscp > maxDSCP {
+		return fmt.Errorf("invalid DSCP: %d (maximum %d)", dscp, maxDSCP)
+	}
+	return nil
+}
+
+// setDSCP sets the DSCP value of packets sent on the packet conn.
+// It sets both the IP_TOS and the IPV6_TCLASS socket option, since a dual-stack socket sends IPv4 and IPv6 packets.
+// Only one of them needs to succeed. It does nothing if the packet conn is not a UDP conn.
+func setDSCP(pconn net.PacketConn, dscp uint8) error {
+	c, ok := pconn.(*net.UDPConn)
+	if !ok {
+		return nil
+	}
+	// the lower 2 bits of the field are used for ECN
+	tos := int(dscp) << 2
+	err4 := ipv4.NewConn(c).SetTOS(tos)
+	err6 := ipv6.NewConn(c).SetTrafficClass(tos)
+	if err4 != nil && err6 != nil {
+		return fmt.Errorf("setting the DSCP failed: %s", err4)
+	}
+	return nil
+}
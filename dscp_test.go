@@ -0,0 +1,50 @@
+package quic
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DSCP", func() {
+	It("accepts values that fit into 6 bits", func() {
+		Expect(validateDSCP(0)).To(Succeed())
+		Expect(validateDSCP(46)).To(Succeed())
+		Expect(validateDSCP(63)).To(Succeed())
+	})
+
+	It("rejects values that don't fit into 6 bits", func() {
+		Expect(validateDSCP(64)).To(MatchError("invalid DSCP: 64 (maximum 63)"))
+		Expect(validateDSCP(255)).To(MatchError("invalid DSCP: 255 (maximum 63)"))
+	})
+
+	It("sets the TOS on an IPv4 socket", func() {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		Expect(setDSCP(conn, 46)).To(Succeed())
+		tos, err := ipv4.NewConn(conn).TOS()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tos).To(Equal(46 << 2))
+	})
+
+	It("sets the traffic class on an IPv6 socket", func() {
+		conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+		if err != nil {
+			Skip("IPv6 not available")
+		}
+		defer conn.Close()
+		Expect(setDSCP(conn, 10)).To(Succeed())
+		tclass, err := ipv6.NewConn(conn).TrafficClass()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tclass).To(Equal(10 << 2))
+	})
+
+	It("doesn't do anything for packet conns that are not UDP conns", func() {
+		Expect(setDSCP(newMockPacketConn(), 46)).To(Succeed())
+	})
+})
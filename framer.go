@@ -23,6 +23,8 @@ type framerI struct {
 
 	activeStreams map[protocol.StreamID]struct{}
 	streamQueue   []protocol.StreamID
+	// the number of STREAM frames the stream at the front of the streamQueue sent in its current turn
+	numFramesInTurn int
 
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
@@ -83,20 +85,23 @@ func (f *framerI) AppendStreamFrames(frames []wire.Frame, maxLen protocol.ByteCo
 			break
 		}
 		id := f.streamQueue[0]
-		f.streamQueue = f.streamQueue[1:]
 		// This should never return an error. Better check it anyway.
 		// The stream will only be in the streamQueue, if it enqueued itself there.
 		str, err := f.streamGetter.GetOrOpenSendStream(id)
 		// The stream can be nil if it completed after it said it had data.
 		if str == nil || err != nil {
+			f.endTurn()
 			delete(f.activeStreams, id)
 			continue
 		}
 		frame, hasMoreData := str.popStreamFrame(maxLen - length)
-		if hasMoreData { // put the stream back in the queue (at the end)
-			f.streamQueue = append(f.streamQueue, id)
-		} else { // no more data to send. Stream is not active any more
+		f.numFramesInTurn++
+		if !hasMoreData { // no more data to send. Stream is not active any more
+			f.endTurn()
 			delete(f.activeStreams, id)
+		} else if f.numFramesInTurn >= int(str.getWeight()) { // put the stream back in the queue (at the end)
+			f.endTurn()
+			f.streamQueue = append(f.streamQueue, id)
 		}
 		if frame == nil { // can happen if the receiveStream was canceled after it said it had data
 			continue
@@ -107,3 +112,9 @@ func (f *framerI) AppendStreamFrames(frames []wire.Frame, maxLen protocol.ByteCo
 	f.mutex.Unlock()
 	return frames
 }
+
+// endTurn removes the stream at the front of the streamQueue.
+func (f *framerI) endTurn() {
+	f.streamQueue = f.streamQueue[1:]
+	f.numFramesInTurn = 0
+}
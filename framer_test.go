@@ -2,10 +2,14 @@ package quic
 
 import (
 	"bytes"
+	"errors"
 
 	"github.com/golang/mock/gomock"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -20,6 +24,7 @@ var _ = Describe("Stream Framer", func() {
 	var (
 		framer           framer
 		stream1, stream2 *MockSendStreamI
+		weight1, weight2 uint8
 		streamGetter     *MockStreamGetter
 		version          protocol.VersionNumber
 	)
@@ -30,6 +35,9 @@ var _ = Describe("Stream Framer", func() {
 		stream1.EXPECT().StreamID().Return(protocol.StreamID(5)).AnyTimes()
 		stream2 = NewMockSendStreamI(mockCtrl)
 		stream2.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
+		weight1, weight2 = 1, 1
+		stream1.EXPECT().getWeight().DoAndReturn(func() uint8 { return weight1 }).AnyTimes()
+		stream2.EXPECT().getWeight().DoAndReturn(func() uint8 { return weight2 }).AnyTimes()
 		framer = newFramer(streamGetter, version)
 	})
 
@@ -225,5 +233,147 @@ var _ = Describe("Stream Framer", func() {
 			fs := framer.AppendStreamFrames(nil, 500)
 			Expect(fs).To(Equal([]wire.Frame{f}))
 		})
+
+		It("lets a stream send as many frames in a row as its weight", func() {
+			weight1 = 3
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).AnyTimes()
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).AnyTimes()
+			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(f1, true).AnyTimes()
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(f2, true).AnyTimes()
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			var frames []wire.Frame
+			for i := 0; i < 8; i++ {
+				frames = framer.AppendStreamFrames(frames, protocol.MinStreamFrameSize)
+			}
+			Expect(frames).To(Equal([]wire.Frame{f1, f1, f1, f2, f1, f1, f1, f2}))
+		})
+
+		It("ends a stream's turn when it doesn't have any more data", func() {
+			weight1 = 3
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).Times(2)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).Times(2)
+			f11 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar")}
+			f12 := &wire.StreamFrame{StreamID: id1, Data: []byte("foobaz")}
+			f21 := &wire.StreamFrame{StreamID: id2, Data: []byte("raboof")}
+			f22 := &wire.StreamFrame{StreamID: id2, Data: []byte("zaboof")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(f11, true)
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(f12, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(f21, true)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(f22, false)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			var frames []wire.Frame
+			for i := 0; i < 4; i++ {
+				frames = framer.AppendStreamFrames(frames, protocol.MinStreamFrameSize)
+			}
+			Expect(frames).To(Equal([]wire.Frame{f11, f12, f21, f22}))
+			Expect(framer.AppendStreamFrames(nil, protocol.MinStreamFrameSize)).To(BeEmpty())
+		})
+	})
+
+	Context("prioritizing streams", func() {
+		newStream := func(id protocol.StreamID, weight uint8) *sendStream {
+			connFC := flowcontrol.NewConnectionFlowController(protocol.MaxByteCount, protocol.MaxByteCount, func() {}, &congestion.RTTStats{}, utils.DefaultLogger)
+			connFC.UpdateSendWindow(protocol.MaxByteCount)
+			fc := flowcontrol.NewStreamFlowController(id, connFC, protocol.MaxByteCount, protocol.MaxByteCount, protocol.MaxByteCount, func(protocol.StreamID) {}, &congestion.RTTStats{}, utils.DefaultLogger)
+			fc.UpdateSendWindow(protocol.MaxByteCount)
+			sender := NewMockStreamSender(mockCtrl)
+			sender.EXPECT().onHasStreamData(id).Do(framer.AddActiveStream).AnyTimes()
+			str := newSendStream(id, sender, fc, version)
+			str.SetPriority(weight)
+			streamGetter.EXPECT().GetOrOpenSendStream(id).Return(str, nil).AnyTimes()
+			return str
+		}
+
+		It("gives a stream with a higher weight proportionally more bandwidth", func() {
+			str1 := newStream(id1, 3)
+			str2 := newStream(id2, 1)
+			for _, str := range []*sendStream{str1, str2} {
+				go func(str *sendStream) {
+					defer GinkgoRecover()
+					str.Write(bytes.Repeat([]byte{'a'}, 1<<20))
+				}(str)
+			}
+			Eventually(func() bool { return str1.hasData() && str2.hasData() }).Should(BeTrue())
+
+			sent := make(map[protocol.StreamID]int)
+			for i := 0; i < 100; i++ {
+				for _, f := range framer.AppendStreamFrames(nil, protocol.MaxPacketSizeIPv4) {
+					sf := f.(*wire.StreamFrame)
+					sent[sf.StreamID] += len(sf.Data)
+				}
+			}
+			Expect(sent[id1] + sent[id2]).To(BeNumerically(">", 90*protocol.MinStreamFrameSize))
+			Expect(float64(sent[id1]) / float64(sent[id2])).To(BeNumerically("~", 3, 0.1))
+			// unblock the Write calls
+			str1.closeForShutdown(errors.New("shutdown"))
+			str2.closeForShutdown(errors.New("shutdown"))
+		})
+	})
+
+	Context("resuming streams blocked by connection-level flow control", func() {
+		var (
+			connFC flowcontrol.ConnectionFlowController
+			sender *MockStreamSender
+		)
+
+		newStream := func(id protocol.StreamID) *sendStream {
+			fc := flowcontrol.NewStreamFlowController(id, connFC, 1000, 1000, 10000, func(protocol.StreamID) {}, &congestion.RTTStats{}, utils.DefaultLogger)
+			str := newSendStream(id, sender, fc, version)
+			streamGetter.EXPECT().GetOrOpenSendStream(id).Return(str, nil).AnyTimes()
+			return str
+		}
+
+		BeforeEach(func() {
+			connFC = flowcontrol.NewConnectionFlowController(1000, 1000, func() {}, &congestion.RTTStats{}, utils.DefaultLogger)
+			sender = NewMockStreamSender(mockCtrl)
+			sender.EXPECT().onHasStreamData(gomock.Any()).Do(func(id protocol.StreamID) {
+				framer.AddActiveStream(id)
+			}).AnyTimes()
+			sender.EXPECT().queueControlFrame(gomock.Any()).AnyTimes()
+		})
+
+		It("resumes sending on all streams after the connection-level send window is increased", func() {
+			connFC.UpdateSendWindow(100)
+			str1 := newStream(id1)
+			str2 := newStream(id2)
+			done := make(chan struct{}, 2)
+			for _, str := range []*sendStream{str1, str2} {
+				go func(str *sendStream) {
+					defer GinkgoRecover()
+					_, err := str.Write(bytes.Repeat([]byte{'a'}, 500))
+					Expect(err).ToNot(HaveOccurred())
+					done <- struct{}{}
+				}(str)
+			}
+			Eventually(func() bool { return str1.hasData() && str2.hasData() }).Should(BeTrue())
+
+			getDataLen := func(frames []wire.Frame) map[protocol.StreamID]int {
+				lens := make(map[protocol.StreamID]int)
+				for _, f := range frames {
+					sf := f.(*wire.StreamFrame)
+					lens[sf.StreamID] += len(sf.Data)
+				}
+				return lens
+			}
+			sent := getDataLen(framer.AppendStreamFrames(nil, protocol.MaxPacketSizeIPv4))
+			Expect(sent[id1] + sent[id2]).To(Equal(100))
+			// the connection is now blocked
+			Expect(framer.AppendStreamFrames(nil, protocol.MaxPacketSizeIPv4)).To(BeEmpty())
+			Consistently(done).ShouldNot(Receive())
+
+			connFC.UpdateSendWindow(1000)
+			for i := 0; i < 10; i++ {
+				for id, l := range getDataLen(framer.AppendStreamFrames(nil, protocol.MaxPacketSizeIPv4)) {
+					sent[id] += l
+				}
+			}
+			Expect(sent).To(HaveKeyWithValue(id1, 500))
+			Expect(sent).To(HaveKeyWithValue(id2, 500))
+			Eventually(done).Should(HaveLen(2))
+		})
 	})
 })
@@ -0,0 +1,105 @@
+package quic
+
+import (
+	"net"
+)
+
+const (
+	// maxGSOSegments is the maximum number of segments the kernel accepts in a single GSO send
+	maxGSOSegments = 64
+	// maxGSOBufferSize is the maximum size of a single GSO send, limited by the maximum size of a UDP datagram
+	maxGSOBufferSize = 65507
+)
+
+// A gsoWriteFunc sends b as a batch of UDP datagrams of segmentSize bytes each.
+// The last datagram may be shorter.
+type gsoWriteFunc func(b []byte, segmentSize int, addr net.Addr) error
+
+// A gsoBatch collects packets that are sent to the same address,
+// such that they can be sent using UDP GSO (Generic Segmentation Offload) in a single system call.
+// All packets in a batch must have the same size, only the last packet may be shorter.
+type gsoBatch struct {
+	writeGSO gsoWriteFunc
+	writeTo  func([]byte, net.Addr) (int, error) // used when sending a single packet, and as a fallback
+
+	buf         []byte
+	addr        net.Addr
+	segmentSize int
+	numSegments int
+	completed   bool // set when a packet shorter than the segment size was added
+}
+
+func newGSOBatch(writeGSO gsoWriteFunc, writeTo func([]byte, net.Addr) (int, error)) *gsoBatch {
+	return &gsoBatch{
+		writeGSO: writeGSO,
+		writeTo:  writeTo,
+		buf:      make([]byte, 0, maxGSOBufferSize),
+	}
+}
+
+// Add adds a packet to the batch.
+// If the packet can't be sent in the same batch as the packets added before, that batch is sent first.
+func (b *gsoBatch) Add(p []byte, addr net.Addr) error {
+	if b.numSegments > 0 && !b.fits(p, addr) {
+		if err := b.Flush(); err != nil {
+			return err
+		}
+	}
+	if b.numSegments == 0 {
+		b.addr = addr
+		b.segmentSize = len(p)
+	} else if len(p) < b.segmentSize {
+		b.completed = true
+	}
+	b.buf = append(b.buf, p...)
+	b.numSegments++
+	return nil
+}
+
+func (b *gsoBatch) fits(p []byte, addr net.Addr) bool {
+	return !b.completed &&
+		len(p) <= b.segmentSize &&
+		b.numSegments < maxGSOSegments &&
+		len(b.buf)+len(p) <= maxGSOBufferSize &&
+		addr.String() == b.addr.String()
+}
+
+// Flush sends all packets in the batch.
+func (b *gsoBatch) Flush() error {
+	if b.numSegments == 0 {
+		return nil
+	}
+	defer b.reset()
+
+	if b.numSegments == 1 {
+		_, err := b.writeTo(b.buf, b.addr)
+		return err
+	}
+	if b.writeGSO != nil {
+		if err := b.writeGSO(b.buf, b.segmentSize, b.addr); err == nil {
+			return nil
+		}
+	}
+	// GSO failed, e.g. because the network interface doesn't support it.
+	// Send the packets one by one. If that works, don't try GSO again.
+	for buf := b.buf; len(buf) > 0; {
+		n := b.segmentSize
+		if len(buf) < n {
+			n = len(buf)
+		}
+		if _, err := b.writeTo(buf[:n], b.addr); err != nil {
+			return err
+		}
+		buf = buf[n:]
+	}
+	b.writeGSO = nil
+	return nil
+}
+
+func (b *gsoBatch) reset() {
+	b.buf = b.buf[:0]
+	b.addr = nil
+	b.segmentSize = 0
+	b.numSegments = 0
+	b.completed = false
+}
@@ -0,0 +1,56 @@
+// +build linux
+
+package quic
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// UDP_SEGMENT is not defined in the syscall package
+const udpSegment = 103
+
+// newGSOWriteFunc returns a function that sends packets using GSO on the packet conn.
+// It returns nil if the packet conn is not a UDP conn, or if the kernel doesn't support GSO.
+func newGSOWriteFunc(pconn net.PacketConn) gsoWriteFunc {
+	c, ok := pconn.(*net.UDPConn)
+	if !ok || !gsoSupported(c) {
+		return nil
+	}
+	return func(b []byte, segmentSize int, addr net.Addr) error {
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			return errors.New("GSO requires a UDP address")
+		}
+		_, _, err := c.WriteMsgUDP(b, gsoControlMessage(segmentSize), udpAddr)
+		return err
+	}
+}
+
+// gsoSupported checks if the kernel supports the UDP_SEGMENT socket option.
+func gsoSupported(c *net.UDPConn) bool {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return false
+	}
+	var serr error
+	if err := rawConn.Control(func(fd uintptr) {
+		_, serr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_UDP, udpSegment)
+	}); err != nil {
+		return false
+	}
+	return serr == nil
+}
+
+// gsoControlMessage returns the UDP_SEGMENT control message, which tells the kernel to split the buffer into segments.
+func gsoControlMessage(segmentSize int) []byte {
+	b := make([]byte, syscall.CmsgSpace(2))
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = syscall.IPPROTO_UDP
+	h.Type = udpSegment
+	h.SetLen(syscall.CmsgLen(2))
+	*(*uint16)(unsafe.Pointer(&b[syscall.CmsgLen(0)])) = uint16(segmentSize)
+	return b
+}
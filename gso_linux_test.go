@@ -0,0 +1,110 @@
+// +build linux
+
+package quic
+
+import (
+	"bytes"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GSO, on Linux", func() {
+	var sender, receiver *net.UDPConn
+
+	BeforeEach(func() {
+		var err error
+		receiver, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		sender, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		if !gsoSupported(sender) {
+			Skip("the kernel doesn't support GSO")
+		}
+	})
+
+	AfterEach(func() {
+		sender.Close()
+		receiver.Close()
+	})
+
+	getPackets := func() [][]byte {
+		var packets [][]byte
+		for i := 0; i < 10; i++ {
+			packets = append(packets, bytes.Repeat([]byte{byte(i)}, 1200))
+		}
+		return append(packets, bytes.Repeat([]byte{'f'}, 500))
+	}
+
+	receive := func(n int) [][]byte {
+		var packets [][]byte
+		for i := 0; i < n; i++ {
+			b := make([]byte, 1500)
+			receiver.SetReadDeadline(time.Now().Add(time.Second))
+			l, _, err := receiver.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			packets = append(packets, b[:l])
+		}
+		return packets
+	}
+
+	send := func(c *conn, packets [][]byte) {
+		for _, p := range packets {
+			Expect(c.Write(p)).To(Succeed())
+		}
+		Expect(c.Flush()).To(Succeed())
+	}
+
+	It("doesn't use GSO unless enabled", func() {
+		Expect(newConn(sender, receiver.LocalAddr(), false).gso).To(BeNil())
+	})
+
+	It("sends the same packets on the wire as without GSO", func() {
+		packets := getPackets()
+		c := newConn(sender, receiver.LocalAddr(), false)
+		send(c, packets)
+		withoutGSO := receive(len(packets))
+
+		c = newConn(sender, receiver.LocalAddr(), true)
+		Expect(c.gso).ToNot(BeNil())
+		send(c, packets)
+		// make sure that the packets were actually sent using GSO
+		Expect(c.gso.writeGSO).ToNot(BeNil())
+		withGSO := receive(len(packets))
+
+		Expect(withGSO).To(Equal(withoutGSO))
+		Expect(withGSO).To(Equal(packets))
+	})
+
+	Measure("sending packets", func(b Benchmarker) {
+		const numBatches = 100
+		packets := make([][]byte, 32)
+		for i := range packets {
+			packets[i] = make([]byte, 1252)
+		}
+		// drain the receiver, packets dropped in the kernel don't matter for this measurement
+		go func(receiver *net.UDPConn) {
+			b := make([]byte, 1500)
+			for {
+				if _, _, err := receiver.ReadFrom(b); err != nil {
+					return
+				}
+			}
+		}(receiver)
+
+		for _, enableGSO := range []bool{false, true} {
+			c := newConn(sender, receiver.LocalAddr(), enableGSO)
+			name := "without GSO"
+			if enableGSO {
+				name = "with GSO"
+			}
+			b.Time(name, func() {
+				for i := 0; i < numBatches; i++ {
+					send(c, packets)
+				}
+			})
+		}
+	}, 5)
+})
@@ -0,0 +1,10 @@
+// +build !linux
+
+package quic
+
+import "net"
+
+// GSO is only supported on Linux.
+func newGSOWriteFunc(net.PacketConn) gsoWriteFunc {
+	return nil
+}
@@ -0,0 +1,160 @@
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GSO batch", func() {
+	type gsoWrite struct {
+		data        []byte
+		segmentSize int
+		addr        net.Addr
+	}
+
+	var (
+		batch     *gsoBatch
+		gsoWrites []gsoWrite
+		writes    []mockPacketConnWrite
+		gsoErr    error
+		addr      net.Addr
+	)
+
+	packet := func(b byte, l int) []byte { return bytes.Repeat([]byte{b}, l) }
+
+	BeforeEach(func() {
+		gsoWrites = nil
+		writes = nil
+		gsoErr = nil
+		addr = &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
+		batch = newGSOBatch(
+			func(b []byte, segmentSize int, addr net.Addr) error {
+				if gsoErr != nil {
+					return gsoErr
+				}
+				data := make([]byte, len(b))
+				copy(data, b)
+				gsoWrites = append(gsoWrites, gsoWrite{data: data, segmentSize: segmentSize, addr: addr})
+				return nil
+			},
+			func(b []byte, addr net.Addr) (int, error) {
+				data := make([]byte, len(b))
+				copy(data, b)
+				writes = append(writes, mockPacketConnWrite{data: data, to: addr})
+				return len(b), nil
+			},
+		)
+	})
+
+	It("doesn't write anything when flushing an empty batch", func() {
+		Expect(batch.Flush()).To(Succeed())
+		Expect(gsoWrites).To(BeEmpty())
+		Expect(writes).To(BeEmpty())
+	})
+
+	It("sends a single packet without GSO", func() {
+		Expect(batch.Add(packet('a', 100), addr)).To(Succeed())
+		Expect(writes).To(BeEmpty())
+		Expect(batch.Flush()).To(Succeed())
+		Expect(gsoWrites).To(BeEmpty())
+		Expect(writes).To(Equal([]mockPacketConnWrite{{data: packet('a', 100), to: addr}}))
+	})
+
+	It("sends packets of the same size in a single GSO write", func() {
+		Expect(batch.Add(packet('a', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('b', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('c', 100), addr)).To(Succeed())
+		Expect(batch.Flush()).To(Succeed())
+		Expect(writes).To(BeEmpty())
+		Expect(gsoWrites).To(HaveLen(1))
+		Expect(gsoWrites[0].segmentSize).To(Equal(100))
+		Expect(gsoWrites[0].addr).To(Equal(addr))
+		Expect(gsoWrites[0].data).To(Equal(append(append(packet('a', 100), packet('b', 100)...), packet('c', 100)...)))
+	})
+
+	It("allows the last packet to be shorter", func() {
+		Expect(batch.Add(packet('a', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('b', 50), addr)).To(Succeed())
+		// this packet can't be added to the batch any more
+		Expect(batch.Add(packet('c', 50), addr)).To(Succeed())
+		Expect(gsoWrites).To(HaveLen(1))
+		Expect(gsoWrites[0].segmentSize).To(Equal(100))
+		Expect(gsoWrites[0].data).To(Equal(append(packet('a', 100), packet('b', 50)...)))
+		Expect(batch.Flush()).To(Succeed())
+		Expect(writes).To(Equal([]mockPacketConnWrite{{data: packet('c', 50), to: addr}}))
+	})
+
+	It("starts a new batch when a larger packet is added", func() {
+		Expect(batch.Add(packet('a', 50), addr)).To(Succeed())
+		Expect(batch.Add(packet('b', 50), addr)).To(Succeed())
+		Expect(batch.Add(packet('c', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('d', 100), addr)).To(Succeed())
+		Expect(batch.Flush()).To(Succeed())
+		Expect(gsoWrites).To(HaveLen(2))
+		Expect(gsoWrites[0].segmentSize).To(Equal(50))
+		Expect(gsoWrites[1].segmentSize).To(Equal(100))
+	})
+
+	It("starts a new batch when the address changes", func() {
+		addr2 := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+		Expect(batch.Add(packet('a', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('b', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('c', 100), addr2)).To(Succeed())
+		Expect(batch.Flush()).To(Succeed())
+		Expect(gsoWrites).To(HaveLen(1))
+		Expect(gsoWrites[0].addr).To(Equal(addr))
+		Expect(writes).To(Equal([]mockPacketConnWrite{{data: packet('c', 100), to: addr2}}))
+	})
+
+	It("limits the number of segments", func() {
+		for i := 0; i < maxGSOSegments+1; i++ {
+			Expect(batch.Add(packet('a', 10), addr)).To(Succeed())
+		}
+		Expect(gsoWrites).To(HaveLen(1))
+		Expect(gsoWrites[0].data).To(HaveLen(10 * maxGSOSegments))
+	})
+
+	It("limits the size of a batch", func() {
+		const size = 1400
+		for i := 0; i <= maxGSOBufferSize/size; i++ {
+			Expect(batch.Add(packet('a', size), addr)).To(Succeed())
+		}
+		Expect(gsoWrites).To(HaveLen(1))
+		Expect(len(gsoWrites[0].data)).To(BeNumerically("<=", maxGSOBufferSize))
+	})
+
+	It("falls back to sending packets individually if GSO fails", func() {
+		gsoErr = errors.New("EIO")
+		Expect(batch.Add(packet('a', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('b', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('c', 80), addr)).To(Succeed())
+		Expect(batch.Flush()).To(Succeed())
+		Expect(writes).To(Equal([]mockPacketConnWrite{
+			{data: packet('a', 100), to: addr},
+			{data: packet('b', 100), to: addr},
+			{data: packet('c', 80), to: addr},
+		}))
+		// GSO is not used any more
+		gsoErr = nil
+		Expect(batch.Add(packet('d', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('e', 100), addr)).To(Succeed())
+		Expect(batch.Flush()).To(Succeed())
+		Expect(gsoWrites).To(BeEmpty())
+		Expect(writes).To(HaveLen(5))
+	})
+
+	It("returns the error if sending packets individually fails", func() {
+		testErr := errors.New("test error")
+		gsoErr = errors.New("EIO")
+		batch.writeTo = func([]byte, net.Addr) (int, error) { return 0, testErr }
+		Expect(batch.Add(packet('a', 100), addr)).To(Succeed())
+		Expect(batch.Add(packet('b', 100), addr)).To(Succeed())
+		Expect(batch.Flush()).To(MatchError(testErr))
+		// the batch is empty now
+		Expect(batch.Flush()).To(Succeed())
+	})
+})
@@ -0,0 +1,143 @@
+package http3
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultAltSvcMaxAge is the freshness lifetime of an alternative service without a ma parameter (RFC 7838, section 3.1).
+const defaultAltSvcMaxAge = 24 * time.Hour
+
+// altSvcProtocols are the ALPN protocol IDs of alternative services that are used for HTTP/3.
+// The quic protocol ID is used by Server.SetQuicHeaders.
+var altSvcProtocols = []string{nextProtoH3, "quic"}
+
+type altSvcEntry struct {
+	authority string
+	expires   time.Time
+}
+
+// The altSvcCache remembers the alternative services that origins advertised in the Alt-Svc header (RFC 7838).
+// It is used to dial the alternative service instead of the origin.
+type altSvcCache struct {
+	mutex sync.Mutex
+
+	entries map[string]altSvcEntry // the key is the authority of the origin
+}
+
+func newAltSvcCache() *altSvcCache {
+	return &altSvcCache{entries: make(map[string]altSvcEntry)}
+}
+
+// Update processes the Alt-Svc header that the origin sent.
+// If it doesn't advertise an alternative service using HTTP/3, the previous entry is kept,
+// unless the header clears all alternative services.
+func (c *altSvcCache) Update(origin, header string) {
+	if header == "" {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if strings.TrimSpace(header) == "clear" {
+		delete(c.entries, origin)
+		return
+	}
+	authority, maxAge, ok := parseAltSvc(header)
+	if !ok {
+		return
+	}
+	// an alternative authority without a host refers to the host of the origin
+	if strings.HasPrefix(authority, ":") {
+		host, _, err := net.SplitHostPort(origin)
+		if err != nil {
+			return
+		}
+		authority = net.JoinHostPort(host, authority[1:])
+	}
+	c.entries[origin] = altSvcEntry{authority: authority, expires: time.Now().Add(maxAge)}
+}
+
+// Get returns the alternative authority for the origin, if there's a fresh entry.
+func (c *altSvcCache) Get(origin string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[origin]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, origin)
+		return "", false
+	}
+	return entry.authority, true
+}
+
+// parseAltSvc returns the authority and the max age of the first alternative service using HTTP/3.
+func parseAltSvc(header string) (string, time.Duration, bool) {
+	for _, alternative := range splitQuoted(header, ',') {
+		params := splitQuoted(alternative, ';')
+		proto, authority, ok := parseAltSvcParam(params[0])
+		if !ok || !isAltSvcProtocol(proto) {
+			continue
+		}
+		maxAge := defaultAltSvcMaxAge
+		for _, param := range params[1:] {
+			key, val, ok := parseAltSvcParam(param)
+			if !ok || key != "ma" {
+				continue
+			}
+			if secs, err := strconv.ParseUint(val, 10, 32); err == nil {
+				maxAge = time.Duration(secs) * time.Second
+			}
+		}
+		return authority, maxAge, true
+	}
+	return "", 0, false
+}
+
+// parseAltSvcParam parses a key=value pair. The value may be quoted.
+func parseAltSvcParam(param string) (string, string, bool) {
+	i := strings.IndexByte(param, '=')
+	if i < 0 {
+		return "", "", false
+	}
+	key := strings.TrimSpace(param[:i])
+	val := strings.TrimSpace(param[i+1:])
+	if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
+		val = val[1 : len(val)-1]
+	}
+	return key, val, key != ""
+}
+
+func isAltSvcProtocol(proto string) bool {
+	for _, p := range altSvcProtocols {
+		if proto == p {
+			return true
+		}
+	}
+	return false
+}
+
+// splitQuoted splits s at every sep that is not enclosed in double quotes.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	var quoted bool
+	var start int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
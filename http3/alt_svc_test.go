@@ -0,0 +1,92 @@
+package http3
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alt-Svc", func() {
+	Context("parsing", func() {
+		It("parses an alternative service", func() {
+			authority, maxAge, ok := parseAltSvc(`h3-19="alt.example.com:8443"; ma=3600`)
+			Expect(ok).To(BeTrue())
+			Expect(authority).To(Equal("alt.example.com:8443"))
+			Expect(maxAge).To(Equal(time.Hour))
+		})
+
+		It("parses the header sent by SetQuicHeaders", func() {
+			authority, maxAge, ok := parseAltSvc(`quic=":443"; ma=2592000; v="44,43,39"`)
+			Expect(ok).To(BeTrue())
+			Expect(authority).To(Equal(":443"))
+			Expect(maxAge).To(Equal(2592000 * time.Second))
+		})
+
+		It("uses the default max age", func() {
+			_, maxAge, ok := parseAltSvc(`h3-19=":443"`)
+			Expect(ok).To(BeTrue())
+			Expect(maxAge).To(Equal(defaultAltSvcMaxAge))
+		})
+
+		It("skips alternatives that don't use HTTP/3", func() {
+			authority, _, ok := parseAltSvc(`h2="alt.example.com:443"; ma=60, quic=":8443"; v="44,43"`)
+			Expect(ok).To(BeTrue())
+			Expect(authority).To(Equal(":8443"))
+		})
+
+		It("doesn't return anything if there's no HTTP/3 alternative", func() {
+			_, _, ok := parseAltSvc(`h2="alt.example.com:443"`)
+			Expect(ok).To(BeFalse())
+			_, _, ok = parseAltSvc("foobar")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("caching", func() {
+		var cache *altSvcCache
+
+		BeforeEach(func() {
+			cache = newAltSvcCache()
+		})
+
+		It("returns the alternative authority", func() {
+			cache.Update("www.example.com:443", `h3-19="alt.example.com:8443"`)
+			alt, ok := cache.Get("www.example.com:443")
+			Expect(ok).To(BeTrue())
+			Expect(alt).To(Equal("alt.example.com:8443"))
+			_, ok = cache.Get("other.example.com:443")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("uses the host of the origin, if the alternative doesn't contain a host", func() {
+			cache.Update("www.example.com:443", `quic=":8443"`)
+			alt, ok := cache.Get("www.example.com:443")
+			Expect(ok).To(BeTrue())
+			Expect(alt).To(Equal("www.example.com:8443"))
+		})
+
+		It("expires entries", func() {
+			cache.Update("www.example.com:443", `h3-19="alt.example.com:8443"; ma=0`)
+			time.Sleep(time.Millisecond)
+			_, ok := cache.Get("www.example.com:443")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("clears entries", func() {
+			cache.Update("www.example.com:443", `h3-19="alt.example.com:8443"`)
+			cache.Update("www.example.com:443", "clear")
+			_, ok := cache.Get("www.example.com:443")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("keeps entries when a response doesn't advertise an alternative", func() {
+			cache.Update("www.example.com:443", `h3-19="alt.example.com:8443"`)
+			cache.Update("www.example.com:443", "")
+			cache.Update("www.example.com:443", `h2=":443"`)
+			alt, ok := cache.Get("www.example.com:443")
+			Expect(ok).To(BeTrue())
+			Expect(alt).To(Equal("alt.example.com:8443"))
+		})
+	})
+})
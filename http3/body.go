@@ -3,6 +3,7 @@ package http3
 import (
 	"errors"
 	"io"
+	"io/ioutil"
 )
 
 // The body of a http.Request or http.Response.
@@ -11,35 +12,57 @@ type body struct {
 
 	isRequest bool
 
+	// emptyFrames counts the frames that don't carry any body data.
+	// onTooManyEmptyFrames is called when the limit is exceeded.
+	emptyFrames          *frameCounter
+	onTooManyEmptyFrames func()
+
 	bytesRemainingInFrame uint64
 }
 
 var _ io.ReadCloser = &body{}
 
-func newRequestBody(str io.ReadCloser) *body {
+func newRequestBody(str io.ReadCloser, emptyFrames *frameCounter, onTooManyEmptyFrames func()) *body {
 	return &body{
-		str:       str,
-		isRequest: true,
+		str:                  str,
+		isRequest:            true,
+		emptyFrames:          emptyFrames,
+		onTooManyEmptyFrames: onTooManyEmptyFrames,
 	}
 }
 
-func newResponseBody(str io.ReadCloser) *body {
-	return &body{str: str}
+func newResponseBody(str io.ReadCloser, emptyFrames *frameCounter, onTooManyEmptyFrames func()) *body {
+	return &body{
+		str:                  str,
+		emptyFrames:          emptyFrames,
+		onTooManyEmptyFrames: onTooManyEmptyFrames,
+	}
 }
 
 func (r *body) Read(b []byte) (int, error) {
 	if r.bytesRemainingInFrame == 0 {
 	parseLoop:
 		for {
-			frame, err := parseNextFrame(r.str)
+			frame, err := parseNextFrameWithCounter(r.str, r.emptyFrames)
 			if err != nil {
-				return 0, err
+				return 0, r.handleError(err)
 			}
 			switch f := frame.(type) {
 			case *headersFrame:
-				// skip HEADERS frames
+				// skip HEADERS frames, e.g. trailers
+				if _, err := io.CopyN(ioutil.Discard, r.str, int64(f.Length)); err != nil {
+					return 0, err
+				}
+				if err := r.emptyFrames.add(); err != nil {
+					return 0, r.handleError(err)
+				}
 				continue
 			case *dataFrame:
+				if f.Length == 0 {
+					if err := r.emptyFrames.add(); err != nil {
+						return 0, r.handleError(err)
+					}
+				}
 				r.bytesRemainingInFrame = f.Length
 				break parseLoop
 			default:
@@ -59,6 +82,13 @@ func (r *body) Read(b []byte) (int, error) {
 	return n, err
 }
 
+func (r *body) handleError(err error) error {
+	if err == errTooManyEmptyFrames && r.onTooManyEmptyFrames != nil {
+		r.onTooManyEmptyFrames()
+	}
+	return err
+}
+
 func (r *body) Close() error {
 	// quic.Stream.Close() closes the write side, not the read side
 	if r.isRequest {
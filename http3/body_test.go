@@ -3,6 +3,7 @@ package http3
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -45,9 +46,9 @@ var _ = Describe("Body", func() {
 			cb := &closingBuffer{Buffer: buf}
 			switch bodyType {
 			case bodyTypeRequest:
-				rb = newRequestBody(cb)
+				rb = newRequestBody(cb, nil, nil)
 			case bodyTypeResponse:
-				rb = newResponseBody(cb)
+				rb = newResponseBody(cb, nil, nil)
 			}
 		})
 
@@ -121,6 +122,20 @@ var _ = Describe("Body", func() {
 			Expect(b).To(Equal([]byte("foobar")))
 		})
 
+		It("skips the payload of HEADERS frames", func() {
+			buf.Write(getDataFrame([]byte("foo")))
+			(&headersFrame{Length: 6}).Write(buf)
+			buf.Write([]byte("foobar"))
+			buf.Write(getDataFrame([]byte("bar")))
+			b := make([]byte, 6)
+			n, err := io.ReadFull(rb, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+			Expect(b).To(Equal([]byte("foobar")))
+			_, err = rb.Read(b)
+			Expect(err).To(Equal(io.EOF))
+		})
+
 		It("errors when it can't parse the frame", func() {
 			buf.Write([]byte("invalid"))
 			_, err := rb.Read([]byte{0})
@@ -136,15 +151,92 @@ var _ = Describe("Body", func() {
 
 	It("closes requests", func() {
 		cb := &closingBuffer{Buffer: buf}
-		rb := newRequestBody(cb)
+		rb := newRequestBody(cb, nil, nil)
 		Expect(rb.Close()).To(Succeed())
 		Expect(cb.closed).To(BeFalse())
 	})
 
 	It("closes responses", func() {
 		cb := &closingBuffer{Buffer: buf}
-		rb := newResponseBody(cb)
+		rb := newResponseBody(cb, nil, nil)
 		Expect(rb.Close()).To(Succeed())
 		Expect(cb.closed).To(BeTrue())
 	})
+
+	Context("limiting frames without body data", func() {
+		var tooManyEmptyFrames bool
+
+		BeforeEach(func() {
+			tooManyEmptyFrames = false
+			rb = newRequestBody(&closingBuffer{Buffer: buf}, newFrameCounter(3), func() { tooManyEmptyFrames = true })
+		})
+
+		It("allows empty frames up to the limit", func() {
+			for i := 0; i < 3; i++ {
+				(&dataFrame{}).Write(buf)
+			}
+			buf.Write(getDataFrame([]byte("foobar")))
+			data, err := ioutil.ReadAll(rb)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(tooManyEmptyFrames).To(BeFalse())
+		})
+
+		It("errors when too many empty DATA frames are received", func() {
+			for i := 0; i < 4; i++ {
+				(&dataFrame{}).Write(buf)
+			}
+			buf.Write(getDataFrame([]byte("foobar")))
+			_, err := ioutil.ReadAll(rb)
+			Expect(err).To(MatchError(errTooManyEmptyFrames))
+			Expect(tooManyEmptyFrames).To(BeTrue())
+		})
+
+		It("counts HEADERS frames", func() {
+			(&dataFrame{}).Write(buf)
+			(&headersFrame{Length: 2}).Write(buf)
+			buf.Write([]byte("fo"))
+			(&dataFrame{}).Write(buf)
+			(&headersFrame{}).Write(buf)
+			buf.Write(getDataFrame([]byte("foobar")))
+			_, err := ioutil.ReadAll(rb)
+			Expect(err).To(MatchError(errTooManyEmptyFrames))
+			Expect(tooManyEmptyFrames).To(BeTrue())
+		})
+
+		It("counts unknown frames", func() {
+			for i := 0; i < 4; i++ {
+				buf.Write([]byte{0x21, 0}) // a reserved frame type with an empty payload
+			}
+			buf.Write(getDataFrame([]byte("foobar")))
+			_, err := ioutil.ReadAll(rb)
+			Expect(err).To(MatchError(errTooManyEmptyFrames))
+			Expect(tooManyEmptyFrames).To(BeTrue())
+		})
+
+		It("doesn't count DATA frames that carry data", func() {
+			for i := 0; i < 10; i++ {
+				buf.Write(getDataFrame([]byte("foo")))
+			}
+			data, err := ioutil.ReadAll(rb)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(HaveLen(30))
+		})
+
+		It("doesn't limit the number of frames if the limit is negative", func() {
+			rb = newRequestBody(&closingBuffer{Buffer: buf}, newFrameCounter(-1), func() { tooManyEmptyFrames = true })
+			for i := 0; i < 1000; i++ {
+				(&dataFrame{}).Write(buf)
+			}
+			buf.Write(getDataFrame([]byte("foobar")))
+			data, err := ioutil.ReadAll(rb)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(tooManyEmptyFrames).To(BeFalse())
+		})
+
+		It("uses the default limit if the limit is zero", func() {
+			Expect(newFrameCounter(0).max).To(Equal(defaultMaxEmptyFrames))
+		})
+	})
 })
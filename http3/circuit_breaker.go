@@ -0,0 +1,79 @@
+package http3
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a host is not dialed, because too many consecutive dials failed.
+var ErrCircuitOpen = errors.New("http3: circuit breaker open, not dialing host")
+
+const defaultCircuitBreakerCooldown = 30 * time.Second
+
+type circuitState struct {
+	failures int
+	openedAt time.Time
+	probing  bool // the circuit is half-open, and a probe dial is in flight
+}
+
+// The circuitBreaker tracks consecutive dial failures per host.
+// After threshold failures, the circuit for that host opens, and no new dials are allowed.
+// When the cooldown expires, the circuit is half-open: a single dial is allowed.
+// If it succeeds, the circuit closes, otherwise it opens again.
+type circuitBreaker struct {
+	mutex sync.Mutex
+
+	threshold int
+	cooldown  time.Duration
+
+	hosts map[string]*circuitState
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if cooldown == 0 {
+		cooldown = defaultCircuitBreakerCooldown
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     make(map[string]*circuitState),
+	}
+}
+
+// Allow says if a new dial to host may be attempted.
+func (b *circuitBreaker) Allow(host string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state, ok := b.hosts[host]
+	if !ok || state.failures < b.threshold {
+		return nil
+	}
+	if state.probing || time.Since(state.openedAt) < b.cooldown {
+		return ErrCircuitOpen
+	}
+	state.probing = true
+	return nil
+}
+
+// Record records the outcome of a dial to host.
+func (b *circuitBreaker) Record(host string, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if err == nil {
+		delete(b.hosts, host)
+		return
+	}
+	state, ok := b.hosts[host]
+	if !ok {
+		state = &circuitState{}
+		b.hosts[host] = state
+	}
+	state.failures++
+	state.probing = false
+	if state.failures >= b.threshold {
+		state.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,78 @@
+package http3
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Circuit Breaker", func() {
+	const host = "quic.clemente.io:443"
+	var b *circuitBreaker
+
+	BeforeEach(func() {
+		b = newCircuitBreaker(3, 50*time.Millisecond)
+	})
+
+	It("uses the default cooldown", func() {
+		Expect(newCircuitBreaker(3, 0).cooldown).To(Equal(defaultCircuitBreakerCooldown))
+	})
+
+	It("allows dials while the number of failures is below the threshold", func() {
+		for i := 0; i < 2; i++ {
+			Expect(b.Allow(host)).To(Succeed())
+			b.Record(host, errors.New("dial error"))
+		}
+		Expect(b.Allow(host)).To(Succeed())
+	})
+
+	It("opens after the threshold is reached", func() {
+		for i := 0; i < 3; i++ {
+			b.Record(host, errors.New("dial error"))
+		}
+		Expect(b.Allow(host)).To(MatchError(ErrCircuitOpen))
+		// other hosts are not affected
+		Expect(b.Allow("example.org:443")).To(Succeed())
+	})
+
+	It("resets the failure count after a successful dial", func() {
+		for i := 0; i < 2; i++ {
+			b.Record(host, errors.New("dial error"))
+		}
+		b.Record(host, nil)
+		b.Record(host, errors.New("dial error"))
+		Expect(b.Allow(host)).To(Succeed())
+	})
+
+	It("allows a single dial after the cooldown", func() {
+		for i := 0; i < 3; i++ {
+			b.Record(host, errors.New("dial error"))
+		}
+		Expect(b.Allow(host)).To(MatchError(ErrCircuitOpen))
+		Eventually(func() error { return b.Allow(host) }).Should(Succeed())
+		// the probe is still in flight
+		Expect(b.Allow(host)).To(MatchError(ErrCircuitOpen))
+	})
+
+	It("closes when the probe succeeds", func() {
+		for i := 0; i < 3; i++ {
+			b.Record(host, errors.New("dial error"))
+		}
+		Eventually(func() error { return b.Allow(host) }).Should(Succeed())
+		b.Record(host, nil)
+		Expect(b.Allow(host)).To(Succeed())
+		Expect(b.Allow(host)).To(Succeed())
+	})
+
+	It("opens again when the probe fails", func() {
+		for i := 0; i < 3; i++ {
+			b.Record(host, errors.New("dial error"))
+		}
+		Eventually(func() error { return b.Allow(host) }).Should(Succeed())
+		b.Record(host, errors.New("dial error"))
+		Expect(b.Allow(host)).To(MatchError(ErrCircuitOpen))
+		Eventually(func() error { return b.Allow(host) }).Should(Succeed())
+	})
+})
@@ -21,8 +21,12 @@ var defaultQuicConfig = &quic.Config{KeepAlive: true}
 
 var dialAddr = quic.DialAddr
 
+var errClientDraining = errors.New("http3: client is draining")
+
 type roundTripperOpts struct {
 	DisableCompression bool
+	DisableKeepAlives  bool
+	MaxEmptyFrames     int
 }
 
 // client is a HTTP3 client doing requests
@@ -34,10 +38,17 @@ type client struct {
 	dialer       func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error)
 	handshakeErr error
 
+	mutex          sync.Mutex
+	controlStr     quic.SendStream
+	activeRequests int
+	draining       bool
+
 	requestWriter *requestWriter
 
 	decoder *qpack.Decoder
 
+	opts *roundTripperOpts
+
 	hostname string
 	session  quic.Session
 
@@ -47,14 +58,14 @@ type client struct {
 func newClient(
 	hostname string,
 	tlsConf *tls.Config,
-	_ *roundTripperOpts, // TODO: implement gzip compression
+	opts *roundTripperOpts,
 	quicConfig *quic.Config,
 	dialer func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error),
 ) *client {
 	if tlsConf == nil {
 		tlsConf = &tls.Config{}
 	}
-	tlsConf.NextProtos = []string{"h3-19"}
+	tlsConf.NextProtos = []string{nextProtoH3}
 	if quicConfig == nil {
 		quicConfig = defaultQuicConfig
 	}
@@ -67,6 +78,7 @@ func newClient(
 		requestWriter: newRequestWriter(logger),
 		decoder:       qpack.NewDecoder(func(hf qpack.HeaderField) {}),
 		config:        quicConfig,
+		opts:          opts,
 		dialer:        dialer,
 		logger:        logger,
 	}
@@ -108,16 +120,85 @@ func (c *client) setupSession() error {
 		return err
 	}
 
+	c.mutex.Lock()
+	c.controlStr = str
+	draining := c.draining
+	c.mutex.Unlock()
+	// drain was called before the control stream was opened
+	if draining {
+		c.sendGoAway(str)
+	}
 	return nil
 }
 
+// drain initiates a graceful shutdown of the connection.
+// New requests are rejected, and the session is closed as soon as all outstanding requests have completed.
+func (c *client) drain() {
+	// make sure that we never dial, if we haven't done so yet
+	c.dialOnce.Do(func() {
+		c.handshakeErr = errClientDraining
+	})
+
+	c.mutex.Lock()
+	if c.draining || c.session == nil {
+		c.mutex.Unlock()
+		return
+	}
+	c.draining = true
+	str := c.controlStr
+	closeSession := c.activeRequests == 0
+	c.mutex.Unlock()
+
+	if str != nil {
+		c.sendGoAway(str)
+	}
+	if closeSession {
+		c.session.Close()
+	}
+}
+
+// sendGoAway sends a GOAWAY frame on the control stream.
+// The client never allows the server to push, so the GOAWAY frame carries Push ID 0.
+// Write coalescing is disabled for the control stream, so that Write only returns once the frame was packed.
+// This way, the GOAWAY frame is sent out before the session is closed.
+func (c *client) sendGoAway(str quic.SendStream) {
+	str.SetNoDelay(true)
+	buf := &bytes.Buffer{}
+	(&goAwayFrame{ID: 0}).Write(buf)
+	if _, err := str.Write(buf.Bytes()); err != nil {
+		c.logger.Debugf("Error sending GOAWAY: %s", err)
+	}
+}
+
+// requestDone is called when a request has completed, i.e. the response body was read or closed, or the request failed.
+func (c *client) requestDone() {
+	c.mutex.Lock()
+	c.activeRequests--
+	closeSession := c.activeRequests == 0 && (c.draining || c.opts.DisableKeepAlives)
+	c.mutex.Unlock()
+	if closeSession {
+		c.session.Close()
+	}
+}
+
+// closeWithExcessiveLoad closes the session when the server sent too many frames without body data.
+func (c *client) closeWithExcessiveLoad() {
+	c.logger.Debugf("Server sent too many frames without body data. Closing the session.")
+	c.session.CloseWithError(quic.ErrorCode(errorExcessiveLoad), errTooManyEmptyFrames)
+}
+
 func (c *client) Close() error {
 	return c.session.Close()
 }
 
-// Roundtrip executes a request and returns a response
-// TODO: handle request cancelations
+// RoundTrip executes a request and returns a response
 func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.RoundTripOpt(req, RoundTripOpt{})
+}
+
+// RoundTripOpt is like RoundTrip, but takes options.
+// TODO: handle request cancelations
+func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
 	if req.URL.Scheme != "https" {
 		return nil, errors.New("http3: unsupported scheme")
 	}
@@ -133,18 +214,61 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 		return nil, c.handshakeErr
 	}
 
+	c.mutex.Lock()
+	if c.draining {
+		c.mutex.Unlock()
+		return nil, errClientDraining
+	}
+	c.activeRequests++
+	c.mutex.Unlock()
+
+	rsp, err := c.doRequest(req, opt)
+	if err != nil {
+		c.requestDone()
+		return nil, err
+	}
+	if rsp.Body == http.NoBody {
+		// There's no body that the caller could read or close, so the request is already done.
+		c.requestDone()
+		return rsp, nil
+	}
+	rsp.Body = &requestDoneBody{ReadCloser: rsp.Body, onDone: c.requestDone}
+	return rsp, nil
+}
+
+func (c *client) doRequest(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
 	str, err := c.session.OpenStreamSync()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := c.requestWriter.WriteRequest(str, req); err != nil {
+	var requestGzip bool
+	if !c.opts.DisableCompression && req.Method != "HEAD" && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
+		// Request gzip only, not deflate. Deflate is ambiguous and
+		// not as universally supported anyway.
+		// See: http://www.gzip.org/zlib/zlib_faq.html#faq38
+		//
+		// Note that we don't request this for HEAD requests,
+		// due to a bug in nginx:
+		//   http://trac.nginx.org/nginx/ticket/358
+		//   https://golang.org/issue/5522
+		//
+		// We don't request gzip if the request is for a range, since
+		// auto-decoding a portion of a gzipped document will just fail
+		// anyway. See https://golang.org/issue/8923
+		requestGzip = true
+	}
+	if err := c.requestWriter.WriteRequest(str, req, requestGzip, opt.OmitContentLength); err != nil {
 		return nil, err
 	}
 
-	frame, err := parseNextFrame(str)
+	emptyFrames := newFrameCounter(c.opts.MaxEmptyFrames)
+	frame, err := parseNextFrameWithCounter(str, emptyFrames)
 	if err != nil {
-		return nil, err
+		if err == errTooManyEmptyFrames {
+			c.closeWithExcessiveLoad()
+		}
+		return nil, streamResetError(err)
 	}
 	hf, ok := frame.(*headersFrame)
 	if !ok {
@@ -153,7 +277,7 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 	// TODO: check size
 	headerBlock := make([]byte, hf.Length)
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
-		return nil, err
+		return nil, streamResetError(err)
 	}
 	hfs, err := c.decoder.DecodeFull(headerBlock)
 	if err != nil {
@@ -163,7 +287,6 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 		Proto:      "HTTP/3",
 		ProtoMajor: 3,
 		Header:     http.Header{},
-		Body:       newResponseBody(&responseBody{str}),
 	}
 	for _, hf := range hfs {
 		switch hf.Name {
@@ -178,5 +301,29 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 			res.Header.Add(hf.Name, hf.Value)
 		}
 	}
+	res.ContentLength = -1
+	if clens := res.Header["Content-Length"]; len(clens) == 1 {
+		if cl, err := strconv.ParseInt(clens[0], 10, 64); err == nil && cl >= 0 {
+			res.ContentLength = cl
+		}
+	}
+	if req.Method == http.MethodHead || !bodyAllowedForStatus(res.StatusCode) {
+		// The response doesn't have a body, so don't wait for DATA frames.
+		// A response to a HEAD request keeps the Content-Length of the resource.
+		if req.Method != http.MethodHead {
+			res.ContentLength = 0
+		}
+		res.Body = http.NoBody
+		str.CancelRead(0)
+		return res, nil
+	}
+	res.Body = newResponseBody(&responseBody{str}, emptyFrames, c.closeWithExcessiveLoad)
+	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Body = newGzipReader(res.Body)
+		res.Uncompressed = true
+	}
 	return res, nil
 }
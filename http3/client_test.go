@@ -2,10 +2,14 @@ package http3
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -18,6 +22,17 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// streamCanceledError is the error returned when reading from a stream that was reset by the peer
+type streamCanceledError struct {
+	code quic.ErrorCode
+}
+
+var _ quic.StreamError = &streamCanceledError{}
+
+func (e *streamCanceledError) Error() string             { return "stream canceled" }
+func (e *streamCanceledError) Canceled() bool            { return true }
+func (e *streamCanceledError) ErrorCode() quic.ErrorCode { return e.code }
+
 var _ = Describe("Client", func() {
 	var (
 		client       *client
@@ -132,9 +147,10 @@ var _ = Describe("Client", func() {
 
 	Context("Doing requests", func() {
 		var (
-			request *http.Request
-			str     *mockquic.MockStream
-			sess    *mockquic.MockSession
+			request    *http.Request
+			str        *mockquic.MockStream
+			controlStr *mockquic.MockStream
+			sess       *mockquic.MockSession
 		)
 
 		decodeHeader := func(str io.Reader) map[string]string {
@@ -157,7 +173,7 @@ var _ = Describe("Client", func() {
 		}
 
 		BeforeEach(func() {
-			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr = mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Write([]byte{0x0}).Return(1, nil).MaxTimes(1)
 			controlStr.EXPECT().Write(gomock.Any()).MaxTimes(1) // SETTINGS frame
 			str = mockquic.NewMockStream(mockCtrl)
@@ -190,7 +206,7 @@ var _ = Describe("Client", func() {
 
 		It("returns a response", func() {
 			rspBuf := &bytes.Buffer{}
-			rw := newResponseWriter(rspBuf, utils.DefaultLogger)
+			rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
 			rw.WriteHeader(418)
 
 			sess.EXPECT().OpenStreamSync().Return(str, nil)
@@ -206,6 +222,451 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		It("sets the ContentLength of the response", func() {
+			rspBuf := &bytes.Buffer{}
+			rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
+			rw.Header().Set("Content-Length", "6")
+			rw.Write([]byte("foobar"))
+
+			request.Header.Set("Accept-Encoding", "identity")
+			sess.EXPECT().OpenStreamSync().Return(str, nil)
+			str.EXPECT().Write(gomock.Any()).AnyTimes()
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return rspBuf.Read(p)
+			}).AnyTimes()
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.ContentLength).To(BeEquivalentTo(6))
+			data, err := ioutil.ReadAll(rsp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+		})
+
+		It("sets the ContentLength to -1 if the response doesn't contain a Content-Length", func() {
+			rspBuf := &bytes.Buffer{}
+			rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
+			rw.Write([]byte("foobar"))
+
+			sess.EXPECT().OpenStreamSync().Return(str, nil)
+			str.EXPECT().Write(gomock.Any()).AnyTimes()
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return rspBuf.Read(p)
+			}).AnyTimes()
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.ContentLength).To(BeEquivalentTo(-1))
+		})
+
+		Context("responses without a body", func() {
+			// respond writes the response headers to the stream.
+			// The stream fails the test if the client tries to read a DATA frame.
+			respond := func(rw func(http.ResponseWriter)) {
+				rspBuf := &bytes.Buffer{}
+				rw(newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger))
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					if rspBuf.Len() == 0 {
+						Fail("read past the HEADERS frame")
+					}
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				str.EXPECT().CancelRead(quic.ErrorCode(0))
+			}
+
+			for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+				status := status
+
+				It(fmt.Sprintf("returns an empty body for a %d response, without waiting for DATA frames", status), func() {
+					respond(func(w http.ResponseWriter) {
+						w.Header().Set("Content-Length", "6")
+						w.WriteHeader(status)
+					})
+					rsp, err := client.RoundTrip(request)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rsp.StatusCode).To(Equal(status))
+					Expect(rsp.ContentLength).To(BeZero())
+					data, err := ioutil.ReadAll(rsp.Body)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(BeEmpty())
+					Expect(rsp.Body.Close()).To(Succeed())
+				})
+			}
+
+			It("returns an empty body for a response to a HEAD request, keeping the Content-Length", func() {
+				request.Method = http.MethodHead
+				respond(func(w http.ResponseWriter) {
+					w.Header().Set("Content-Length", "6")
+					w.WriteHeader(http.StatusOK)
+				})
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+				Expect(rsp.ContentLength).To(BeEquivalentTo(6))
+				data, err := ioutil.ReadAll(rsp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(BeEmpty())
+			})
+		})
+
+		Context("stream resets", func() {
+			It("returns a StreamResetError when the server resets the stream before sending the response", func() {
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).Return(0, &streamCanceledError{code: quic.ErrorCode(errorRequestRejected)})
+				_, err := client.RoundTrip(request)
+				Expect(err).To(Equal(&StreamResetError{ErrorCode: quic.ErrorCode(errorRequestRejected)}))
+				Expect(err.(*StreamResetError).Retryable()).To(BeTrue())
+			})
+
+			It("returns a StreamResetError when the server resets the stream while sending the body", func() {
+				rspBuf := &bytes.Buffer{}
+				rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
+				rw.Write([]byte("foo"))
+
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					if rspBuf.Len() == 0 {
+						return 0, &streamCanceledError{code: quic.ErrorCode(errorInternalError)}
+					}
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = ioutil.ReadAll(rsp.Body)
+				Expect(err).To(Equal(&StreamResetError{ErrorCode: quic.ErrorCode(errorInternalError)}))
+				Expect(err.(*StreamResetError).Retryable()).To(BeFalse())
+			})
+		})
+
+		Context("limiting frames without body data", func() {
+			BeforeEach(func() {
+				client = newClient("quic.clemente.io:1337", nil, &roundTripperOpts{MaxEmptyFrames: 10}, nil, nil)
+			})
+
+			It("closes the session when the server floods the response body with empty DATA frames", func() {
+				rspBuf := &bytes.Buffer{}
+				rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
+				rw.Write([]byte("foo"))
+				for i := 0; i < 11; i++ {
+					(&dataFrame{}).Write(rspBuf)
+				}
+
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorExcessiveLoad), errTooManyEmptyFrames)
+				_, err = ioutil.ReadAll(rsp.Body)
+				Expect(err).To(MatchError(errTooManyEmptyFrames))
+			})
+
+			It("closes the session when the server sends too many unknown frames before the HEADERS frame", func() {
+				rspBuf := &bytes.Buffer{}
+				for i := 0; i < 11; i++ {
+					rspBuf.Write([]byte{0x21, 0}) // a reserved frame type with an empty payload
+				}
+				rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
+				rw.WriteHeader(200)
+
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorExcessiveLoad), errTooManyEmptyFrames)
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError(errTooManyEmptyFrames))
+			})
+		})
+
+		Context("gzip compression", func() {
+			var gzippedData []byte // a gzipped foobar
+
+			BeforeEach(func() {
+				var b bytes.Buffer
+				w := gzip.NewWriter(&b)
+				w.Write([]byte("foobar"))
+				w.Close()
+				gzippedData = b.Bytes()
+			})
+
+			It("adds the gzip header to requests", func() {
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				buf := &bytes.Buffer{}
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return buf.Write(p)
+				})
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).Return(0, errors.New("test done"))
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("test done"))
+				hfs := decodeHeader(buf)
+				Expect(hfs).To(HaveKeyWithValue("accept-encoding", "gzip"))
+			})
+
+			It("doesn't add gzip if compression is disabled", func() {
+				client = newClient("quic.clemente.io:1337", nil, &roundTripperOpts{DisableCompression: true}, nil, nil)
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				buf := &bytes.Buffer{}
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return buf.Write(p)
+				})
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).Return(0, errors.New("test done"))
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("test done"))
+				hfs := decodeHeader(buf)
+				Expect(hfs).ToNot(HaveKey("accept-encoding"))
+			})
+
+			It("doesn't add gzip for HEAD and Range requests", func() {
+				sess.EXPECT().OpenStreamSync().Return(str, nil).Times(2)
+				buf := &bytes.Buffer{}
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return buf.Write(p)
+				}).Times(2)
+				str.EXPECT().Close().Times(2)
+				str.EXPECT().Read(gomock.Any()).Return(0, errors.New("test done")).Times(2)
+				request.Method = "HEAD"
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("test done"))
+				Expect(decodeHeader(buf)).ToNot(HaveKey("accept-encoding"))
+				request.Method = "GET"
+				request.Header.Set("Range", "bytes=0-10")
+				_, err = client.RoundTrip(request)
+				Expect(err).To(MatchError("test done"))
+				Expect(decodeHeader(buf)).ToNot(HaveKey("accept-encoding"))
+			})
+
+			It("decompresses the response", func() {
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				buf := &bytes.Buffer{}
+				rw := newResponseWriter(nopFlusher{buf}, utils.DefaultLogger)
+				rw.Header().Set("Content-Encoding", "gzip")
+				rw.Header().Set("Content-Length", strconv.Itoa(len(gzippedData)))
+				rw.Write(gzippedData)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return buf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				data, err := ioutil.ReadAll(rsp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.ContentLength).To(BeEquivalentTo(-1))
+				Expect(string(data)).To(Equal("foobar"))
+				Expect(rsp.Header.Get("Content-Encoding")).To(BeEmpty())
+				Expect(rsp.Header.Get("Content-Length")).To(BeEmpty())
+				Expect(rsp.Uncompressed).To(BeTrue())
+			})
+
+			It("only decompresses the response if the response contains the right content-encoding header", func() {
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				buf := &bytes.Buffer{}
+				rw := newResponseWriter(nopFlusher{buf}, utils.DefaultLogger)
+				rw.Write([]byte("not gzipped"))
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return buf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				data, err := ioutil.ReadAll(rsp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(data)).To(Equal("not gzipped"))
+				Expect(rsp.Uncompressed).To(BeFalse())
+			})
+
+			It("doesn't decompress the response if the request set its own Accept-Encoding", func() {
+				request.Header.Set("Accept-Encoding", "gzip")
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				buf := &bytes.Buffer{}
+				rw := newResponseWriter(nopFlusher{buf}, utils.DefaultLogger)
+				rw.Header().Set("Content-Encoding", "gzip")
+				rw.Write(gzippedData)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return buf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				data, err := ioutil.ReadAll(rsp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(gzippedData))
+				Expect(rsp.Header.Get("Content-Encoding")).To(Equal("gzip"))
+				Expect(rsp.Uncompressed).To(BeFalse())
+			})
+		})
+
+		Context("disabling keep-alives", func() {
+			BeforeEach(func() {
+				client = newClient("quic.clemente.io:1337", nil, &roundTripperOpts{DisableKeepAlives: true}, nil, nil)
+			})
+
+			It("closes the session after the response body was read", func() {
+				rspBuf := &bytes.Buffer{}
+				rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
+				rw.Write([]byte("foobar"))
+
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				sess.EXPECT().Close()
+				data, err := ioutil.ReadAll(rsp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+				// closing the body doesn't close the session a second time
+				str.EXPECT().CancelRead(gomock.Any())
+				Expect(rsp.Body.Close()).To(Succeed())
+			})
+
+			It("closes the session when the response body is closed", func() {
+				rspBuf := &bytes.Buffer{}
+				rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
+				rw.WriteHeader(200)
+
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				str.EXPECT().CancelRead(gomock.Any())
+				sess.EXPECT().Close()
+				Expect(rsp.Body.Close()).To(Succeed())
+			})
+
+			It("closes the session when the request fails", func() {
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).Return(0, errors.New("test done"))
+				sess.EXPECT().Close()
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("test done"))
+			})
+		})
+
+		Context("draining", func() {
+			var goAwayChan chan []byte
+
+			BeforeEach(func() {
+				goAwayChan = make(chan []byte, 1)
+				// write coalescing is disabled before the GOAWAY frame is written
+				noDelay := controlStr.EXPECT().SetNoDelay(true).MaxTimes(1)
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					goAwayChan <- p
+					return len(p), nil
+				}).After(noDelay).MaxTimes(1)
+			})
+
+			expectGoAway := func() {
+				var data []byte
+				Eventually(goAwayChan).Should(Receive(&data))
+				frame, err := parseNextFrame(bytes.NewReader(data))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&goAwayFrame{ID: 0}))
+			}
+
+			It("sends a GOAWAY and closes the session after the outstanding request completes", func() {
+				rspBuf := &bytes.Buffer{}
+				rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
+				rw.Write([]byte("foobar"))
+
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+
+				client.drain()
+				expectGoAway()
+				// new requests are rejected
+				_, err = client.RoundTrip(request)
+				Expect(err).To(MatchError(errClientDraining))
+				// the session is closed once the response body was read
+				sessionClosed := make(chan struct{})
+				sess.EXPECT().Close().Do(func() { close(sessionClosed) })
+				Consistently(sessionClosed).ShouldNot(BeClosed())
+				data, err := ioutil.ReadAll(rsp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+				Expect(sessionClosed).To(BeClosed())
+			})
+
+			It("closes the session immediately if no requests are outstanding", func() {
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).Return(0, errors.New("test done"))
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("test done"))
+
+				sess.EXPECT().Close()
+				client.drain()
+				expectGoAway()
+			})
+
+			It("closes the session immediately after a response without a body", func() {
+				rspBuf := &bytes.Buffer{}
+				rw := newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger)
+				rw.WriteHeader(http.StatusNoContent)
+
+				sess.EXPECT().OpenStreamSync().Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.Body).To(Equal(http.NoBody))
+
+				// the response body is never closed, but the request is not outstanding any more
+				sess.EXPECT().Close()
+				client.drain()
+				expectGoAway()
+			})
+
+			It("doesn't dial after draining", func() {
+				var dialed bool
+				dialAddr = func(string, *tls.Config, *quic.Config) (quic.Session, error) {
+					dialed = true
+					return sess, nil
+				}
+				client.drain()
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError(errClientDraining))
+				Expect(dialed).To(BeFalse())
+			})
+		})
+
 		Context("validating the address", func() {
 			It("refuses to do requests for the wrong host", func() {
 				req, err := http.NewRequest("https", "https://quic.clemente.io:1336/foobar.html", nil)
@@ -253,6 +714,34 @@ var _ = Describe("Client", func() {
 				Expect(hfs).To(HaveKeyWithValue(":path", "/upload"))
 			})
 
+			It("sends the content-length, if the length of the body is known", func() {
+				request.ContentLength = 12
+				done := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(done) })
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+					<-done
+					return 0, errors.New("test done")
+				})
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("test done"))
+				hfs := decodeHeader(strBuf)
+				Expect(hfs).To(HaveKeyWithValue("content-length", "12"))
+			})
+
+			It("omits the content-length, if requested", func() {
+				request.ContentLength = 12
+				done := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(done) })
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+					<-done
+					return 0, errors.New("test done")
+				})
+				_, err := client.RoundTripOpt(request, RoundTripOpt{OmitContentLength: true})
+				Expect(err).To(MatchError("test done"))
+				hfs := decodeHeader(strBuf)
+				Expect(hfs).ToNot(HaveKey("content-length"))
+			})
+
 			It("returns the error that occurred when reading the body", func() {
 				request.Body.(*mockBody).readErr = errors.New("testErr")
 				done := make(chan struct{})
@@ -86,3 +86,29 @@ func (e errorCode) String() string {
 		return fmt.Sprintf("unknown error code: %#x", uint16(e))
 	}
 }
+
+// A StreamResetError is returned by the RoundTripper when the server resets the request stream.
+type StreamResetError struct {
+	ErrorCode quic.ErrorCode
+}
+
+var _ error = &StreamResetError{}
+
+func (e *StreamResetError) Error() string {
+	return fmt.Sprintf("http3: request stream reset by the server: %s", errorCode(e.ErrorCode))
+}
+
+// Retryable says if the server reset the stream with HTTP_REQUEST_REJECTED.
+// This tells the client that the request wasn't processed,
+// and that it can safely be retried, e.g. on a different connection.
+func (e *StreamResetError) Retryable() bool {
+	return errorCode(e.ErrorCode) == errorRequestRejected
+}
+
+// streamResetError converts the error returned when reading from a stream that was reset by the peer to a StreamResetError.
+func streamResetError(err error) error {
+	if serr, ok := err.(quic.StreamError); ok && serr.Canceled() {
+		return &StreamResetError{ErrorCode: serr.ErrorCode()}
+	}
+	return err
+}
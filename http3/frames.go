@@ -2,6 +2,7 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -27,7 +28,43 @@ func (br *byteReaderImpl) ReadByte() (byte, error) {
 
 type frame interface{}
 
+// defaultMaxEmptyFrames is the default number of frames without any body data the peer may send on a request stream
+const defaultMaxEmptyFrames = 100
+
+var errTooManyEmptyFrames = errors.New("too many frames without body data")
+
+// A frameCounter counts the frames on a stream that don't carry any body data,
+// i.e. empty DATA frames, HEADERS frames following the first one, and unknown frames.
+// A nil frameCounter doesn't count.
+type frameCounter struct {
+	count int
+	max   int // a negative value disables the limit
+}
+
+func newFrameCounter(max int) *frameCounter {
+	if max == 0 {
+		max = defaultMaxEmptyFrames
+	}
+	return &frameCounter{max: max}
+}
+
+func (c *frameCounter) add() error {
+	if c == nil {
+		return nil
+	}
+	c.count++
+	if c.max > 0 && c.count > c.max {
+		return errTooManyEmptyFrames
+	}
+	return nil
+}
+
 func parseNextFrame(b io.Reader) (frame, error) {
+	return parseNextFrameWithCounter(b, nil)
+}
+
+// parseNextFrameWithCounter parses the next frame, and adds every unknown frame that is skipped to the counter.
+func parseNextFrameWithCounter(b io.Reader, counter *frameCounter) (frame, error) {
 	br, ok := b.(byteReader)
 	if !ok {
 		br = &byteReaderImpl{b}
@@ -48,14 +85,14 @@ func parseNextFrame(b io.Reader) (frame, error) {
 		return &headersFrame{Length: l}, nil
 	case 0x4:
 		return parseSettingsFrame(br, l)
+	case 0x7:
+		return parseGoAwayFrame(br, l)
 	case 0x2: // PRIORITY
 		fallthrough
 	case 0x3: // CANCEL_PUSH
 		fallthrough
 	case 0x5: // PUSH_PROMISE
 		fallthrough
-	case 0x7: // GOAWAY
-		fallthrough
 	case 0xd: // MAX_PUSH_ID
 		fallthrough
 	case 0xe: // DUPLICATE_PUSH
@@ -65,7 +102,10 @@ func parseNextFrame(b io.Reader) (frame, error) {
 		if _, err := io.CopyN(ioutil.Discard, br, int64(l)); err != nil {
 			return nil, err
 		}
-		return parseNextFrame(b)
+		if err := counter.add(); err != nil {
+			return nil, err
+		}
+		return parseNextFrameWithCounter(b, counter)
 	}
 }
 
@@ -133,3 +173,37 @@ func (f *settingsFrame) Write(b *bytes.Buffer) {
 		utils.WriteVarInt(b, val)
 	}
 }
+
+// A goAwayFrame is sent to initiate a graceful shutdown of a connection.
+// When sent by the server, the ID is a Stream ID, when sent by the client, it is a Push ID.
+type goAwayFrame struct {
+	ID uint64
+}
+
+func parseGoAwayFrame(r io.Reader, l uint64) (*goAwayFrame, error) {
+	if l > 8 {
+		return nil, fmt.Errorf("unexpected size for GOAWAY frame: %d", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	b := bytes.NewReader(buf)
+	id, err := utils.ReadVarInt(b)
+	if err != nil {
+		return nil, err
+	}
+	if b.Len() > 0 {
+		return nil, errors.New("invalid GOAWAY frame")
+	}
+	return &goAwayFrame{ID: id}, nil
+}
+
+func (f *goAwayFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0x7)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.ID)))
+	utils.WriteVarInt(b, f.ID)
+}
@@ -133,4 +133,45 @@ var _ = Describe("Frames", func() {
 			}
 		})
 	})
+
+	Context("GOAWAY frames", func() {
+		It("parses", func() {
+			payload := appendVarInt(nil, 0x1337)
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, uint64(len(payload)))
+			data = append(data, payload...)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{ID: 0x1337}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{ID: 0xdeadbeef}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{ID: 0xdeadbeef}))
+			Expect(buf.Len()).To(BeZero())
+		})
+
+		It("rejects frames that contain more than the ID", func() {
+			payload := appendVarInt(nil, 0x1337)
+			payload = append(payload, 0x42)
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, uint64(len(payload)))
+			data = append(data, payload...)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("invalid GOAWAY frame"))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{ID: 0xdecafbad}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				_, err := parseNextFrame(bytes.NewReader(data[:i]))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
 })
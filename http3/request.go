@@ -3,6 +3,7 @@ package http3
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -41,7 +42,15 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		return nil, errors.New(":path, :authority and :method must not be empty")
 	}
 
-	u, err := url.Parse(path)
+	if !validPath(path) {
+		return nil, fmt.Errorf("invalid :path %q", path)
+	}
+	// The asterisk-form is only used for server-wide OPTIONS requests (RFC 7230, section 5.3.4).
+	if path == "*" && method != "OPTIONS" {
+		return nil, errors.New("the asterisk-form :path is only allowed for OPTIONS requests")
+	}
+	// Use ParseRequestURI, so that a :path starting with "//" isn't interpreted as an authority.
+	u, err := url.ParseRequestURI(path)
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +78,17 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	}, nil
 }
 
+// validPath checks that the path only contains characters that are allowed in a request target (RFC 3986).
+// Whitespace, control characters and non-ASCII characters must be percent-encoded.
+func validPath(path string) bool {
+	for i := 0; i < len(path); i++ {
+		if c := path[i]; c <= ' ' || c >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
 func hostnameFromRequest(req *http.Request) string {
 	if req.URL != nil {
 		return req.URL.Host
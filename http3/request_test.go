@@ -0,0 +1,122 @@
+package http3
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request", func() {
+	It("populates request", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo?bar=baz"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+			{Name: "content-length", Value: "42"},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Method).To(Equal("GET"))
+		Expect(req.URL.Path).To(Equal("/foo"))
+		Expect(req.URL.RawQuery).To(Equal("bar=baz"))
+		Expect(req.URL.Host).To(BeEmpty())
+		Expect(req.Proto).To(Equal("HTTP/3"))
+		Expect(req.ProtoMajor).To(Equal(3))
+		Expect(req.ContentLength).To(Equal(int64(42)))
+		Expect(req.Host).To(Equal("quic.clemente.io"))
+		Expect(req.RequestURI).To(Equal("/foo?bar=baz"))
+	})
+
+	It("concatenates the cookie headers", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+			{Name: "cookie", Value: "cookie1=foobar1"},
+			{Name: "cookie", Value: "cookie2=foobar2"},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Header).To(HaveKeyWithValue("Cookie", []string{"cookie1=foobar1; cookie2=foobar2"}))
+	})
+
+	It("doesn't interpret a path starting with two slashes as an authority", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "//foo/bar?baz"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.URL).To(Equal(&url.URL{Path: "//foo/bar", RawQuery: "baz"}))
+		Expect(req.Host).To(Equal("quic.clemente.io"))
+		Expect(req.RequestURI).To(Equal("//foo/bar?baz"))
+	})
+
+	It("handles the asterisk-form for OPTIONS requests", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "*"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "OPTIONS"},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Method).To(Equal("OPTIONS"))
+		Expect(req.URL).To(Equal(&url.URL{Path: "*"}))
+		Expect(req.RequestURI).To(Equal("*"))
+		Expect(req.Host).To(Equal("quic.clemente.io"))
+	})
+
+	It("rejects the asterisk-form for requests other than OPTIONS", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "*"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("the asterisk-form :path is only allowed for OPTIONS requests"))
+	})
+
+	It("rejects paths containing whitespace, control characters or non-ASCII characters", func() {
+		for _, p := range []string{"/foo bar", "/foo\tbar", "/foo\x00", "/foo\x7f", "/föö"} {
+			headers := []qpack.HeaderField{
+				{Name: ":path", Value: p},
+				{Name: ":authority", Value: "quic.clemente.io"},
+				{Name: ":method", Value: "GET"},
+			}
+			_, err := requestFromHeaders(headers)
+			Expect(err).To(MatchError(fmt.Sprintf("invalid :path %q", p)))
+		}
+	})
+
+	It("errors with missing path", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError(":path, :authority and :method must not be empty"))
+	})
+
+	It("errors with missing method", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError(":path, :authority and :method must not be empty"))
+	})
+
+	It("errors with missing authority", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":method", Value: "GET"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError(":path, :authority and :method must not be empty"))
+	})
+})
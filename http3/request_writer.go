@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -36,16 +37,20 @@ func newRequestWriter(logger utils.Logger) *requestWriter {
 	}
 }
 
-func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request) error {
-	headers, err := w.getHeaders(req)
+// WriteRequest writes the request headers and body to the stream.
+// If omitContentLength is set, the content-length header is not sent, even if the length of the body is known.
+func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip, omitContentLength bool) error {
+	headers, err := w.getHeaders(req, gzip, omitContentLength)
 	if err != nil {
 		return err
 	}
 	if _, err := str.Write(headers); err != nil {
 		return err
 	}
-	// TODO: add support for trailers
 	if req.Body == nil {
+		if err := w.writeTrailers(str, req); err != nil {
+			return err
+		}
 		str.Close()
 		return nil
 	}
@@ -56,21 +61,67 @@ func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request) error {
 			w.logger.Errorf("Error writing request: %s", err)
 			return
 		}
+		if err := w.writeTrailers(str, req); err != nil {
+			w.logger.Errorf("Error writing request trailers: %s", err)
+			return
+		}
 		str.Close()
 	}()
 
 	return nil
 }
 
-func (w *requestWriter) getHeaders(req *http.Request) ([]byte, error) {
+func (w *requestWriter) getHeaders(req *http.Request, gzip, omitContentLength bool) ([]byte, error) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 	defer w.encoder.Close()
 
-	if err := w.encodeHeaders(req, false, "", actualContentLength(req)); err != nil {
+	trailers, err := commaSeparatedTrailers(req)
+	if err != nil {
+		return nil, err
+	}
+	contentLength := actualContentLength(req)
+	if omitContentLength {
+		contentLength = -1 // treat the length as unknown
+	}
+	if err := w.encodeHeaders(req, gzip, trailers, contentLength); err != nil {
 		return nil, err
 	}
+	return w.getHeadersFrame()
+}
+
+// writeTrailers sends a HEADERS frame containing the request trailers.
+// It is called after the request body has been sent,
+// so that trailer values set while sending the body are included.
+func (w *requestWriter) writeTrailers(str quic.Stream, req *http.Request) error {
+	if len(req.Trailer) == 0 {
+		return nil
+	}
+	trailers, err := w.getTrailers(req)
+	if err != nil {
+		return err
+	}
+	_, err = str.Write(trailers)
+	return err
+}
 
+func (w *requestWriter) getTrailers(req *http.Request) ([]byte, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	defer w.encoder.Close()
+
+	for k, vv := range req.Trailer {
+		// Transfer-Encoding, etc.. have already been filtered when encoding the headers
+		lowKey := strings.ToLower(k)
+		for _, v := range vv {
+			w.encoder.WriteField(qpack.HeaderField{Name: lowKey, Value: v})
+		}
+	}
+	return w.getHeadersFrame()
+}
+
+// getHeadersFrame returns a HEADERS frame containing the encoded header block
+func (w *requestWriter) getHeadersFrame() ([]byte, error) {
 	buf := &bytes.Buffer{}
 	hf := headersFrame{Length: uint64(w.headerBuf.Len())}
 	hf.Write(buf)
@@ -238,6 +289,24 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	return nil
 }
 
+// copied from net/transport.go
+func commaSeparatedTrailers(req *http.Request) (string, error) {
+	keys := make([]string, 0, len(req.Trailer))
+	for k := range req.Trailer {
+		k = http.CanonicalHeaderKey(k)
+		switch k {
+		case "Transfer-Encoding", "Trailer", "Content-Length":
+			return "", fmt.Errorf("invalid Trailer key %q", k)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return strings.Join(keys, ","), nil
+	}
+	return "", nil
+}
+
 // authorityAddr returns a given authority (a host/IP, or host:port / ip:port)
 // and returns a host:port. The port 443 is added if needed.
 func authorityAddr(scheme string, authority string) (addr string) {
@@ -3,6 +3,7 @@ package http3
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 
@@ -16,6 +17,20 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// trailerSettingBody calls onEOF when the body has been read completely
+type trailerSettingBody struct {
+	*mockBody
+	onEOF func()
+}
+
+func (b *trailerSettingBody) Read(p []byte) (int, error) {
+	n, err := b.mockBody.Read(p)
+	if err == io.EOF {
+		b.onEOF()
+	}
+	return n, err
+}
+
 var _ = Describe("Request Writer", func() {
 	var (
 		rw     *requestWriter
@@ -54,7 +69,7 @@ var _ = Describe("Request Writer", func() {
 		str.EXPECT().Close()
 		req, err := http.NewRequest("GET", "https://quic.clemente.io/index.html?foo=bar", nil)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(rw.WriteRequest(str, req)).To(Succeed())
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
 		headerFields := decode(strBuf)
 		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io"))
 		Expect(headerFields).To(HaveKeyWithValue(":method", "GET"))
@@ -63,13 +78,58 @@ var _ = Describe("Request Writer", func() {
 		Expect(headerFields).ToNot(HaveKey("accept-encoding"))
 	})
 
+	It("writes a request with an absolute-form target", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest("GET", "https://quic.clemente.io", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.URL.Opaque = "//quic.clemente.io/index.html"
+		req.URL.RawQuery = "foo=bar"
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io"))
+		Expect(headerFields).To(HaveKeyWithValue(":path", "/index.html?foo=bar"))
+		Expect(headerFields).To(HaveKeyWithValue(":scheme", "https"))
+	})
+
+	It("writes a request with a proxy-style absolute-form target for a different host", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest("GET", "https://proxy.example.com", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Host = "quic.clemente.io"
+		req.URL.Opaque = "//quic.clemente.io/index.html"
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io"))
+		Expect(headerFields).To(HaveKeyWithValue(":path", "/index.html"))
+	})
+
+	It("rejects an absolute-form target that doesn't match the authority", func() {
+		req, err := http.NewRequest("GET", "https://quic.clemente.io", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.URL.Opaque = "//example.com/index.html"
+		err = rw.WriteRequest(str, req, false, false)
+		Expect(err).To(MatchError(`invalid request :path "https://example.com/index.html" from URL.Opaque = "//example.com/index.html"`))
+	})
+
+	It("writes an OPTIONS request with an asterisk-form target", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest("OPTIONS", "https://quic.clemente.io", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.URL.Opaque = "*"
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io"))
+		Expect(headerFields).To(HaveKeyWithValue(":method", "OPTIONS"))
+		Expect(headerFields).To(HaveKeyWithValue(":path", "*"))
+	})
+
 	It("writes a POST request", func() {
 		closed := make(chan struct{})
 		str.EXPECT().Close().Do(func() { close(closed) })
 		postData := bytes.NewReader([]byte("foobar"))
 		req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", postData)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(rw.WriteRequest(str, req)).To(Succeed())
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
 		headerFields := decode(strBuf)
 		Expect(headerFields).To(HaveKeyWithValue(":method", "POST"))
 		Expect(headerFields).To(HaveKey("content-length"))
@@ -84,6 +144,138 @@ var _ = Describe("Request Writer", func() {
 		Expect(frame.(*dataFrame).Length).To(BeEquivalentTo(6))
 	})
 
+	Context("sending the content-length", func() {
+		It("sends the content-length if the length of the body is known", func() {
+			closed := make(chan struct{})
+			str.EXPECT().Close().Do(func() { close(closed) })
+			req, err := http.NewRequest("PUT", "https://quic.clemente.io/upload.html", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+			Eventually(closed).Should(BeClosed())
+			Expect(decode(strBuf)).To(HaveKeyWithValue("content-length", "6"))
+		})
+
+		It("sends a zero content-length for methods that permit a body", func() {
+			str.EXPECT().Close()
+			req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+			Expect(decode(strBuf)).To(HaveKeyWithValue("content-length", "0"))
+		})
+
+		It("doesn't send a zero content-length for methods that don't permit a body", func() {
+			str.EXPECT().Close()
+			req, err := http.NewRequest("GET", "https://quic.clemente.io/index.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+			Expect(decode(strBuf)).ToNot(HaveKey("content-length"))
+		})
+
+		It("omits the content-length, if requested", func() {
+			closed := make(chan struct{})
+			str.EXPECT().Close().Do(func() { close(closed) })
+			req, err := http.NewRequest("PUT", "https://quic.clemente.io/upload.html", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(req.ContentLength).To(BeEquivalentTo(6))
+			Expect(rw.WriteRequest(str, req, false, true)).To(Succeed())
+			Eventually(closed).Should(BeClosed())
+			Expect(decode(strBuf)).ToNot(HaveKey("content-length"))
+			frame, err := parseNextFrame(strBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.(*dataFrame).Length).To(BeEquivalentTo(6))
+		})
+
+		It("omits a zero content-length, if requested", func() {
+			str.EXPECT().Close()
+			req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rw.WriteRequest(str, req, false, true)).To(Succeed())
+			Expect(decode(strBuf)).ToNot(HaveKey("content-length"))
+		})
+
+		It("doesn't send a content-length header set by the user, if the content-length is omitted", func() {
+			str.EXPECT().Close()
+			req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Length", "42")
+			Expect(rw.WriteRequest(str, req, false, true)).To(Succeed())
+			Expect(decode(strBuf)).ToNot(HaveKey("content-length"))
+		})
+	})
+
+	It("sends trailers after the request body", func() {
+		closed := make(chan struct{})
+		str.EXPECT().Close().Do(func() { close(closed) })
+		req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", bytes.NewReader([]byte("foobar")))
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{
+			"Grpc-Status":  []string{"0"},
+			"Grpc-Message": []string{"ok"},
+		}
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+		Eventually(closed).Should(BeClosed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue("trailer", "Grpc-Message,Grpc-Status"))
+		frame, err := parseNextFrame(strBuf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
+		data := make([]byte, frame.(*dataFrame).Length)
+		_, err = io.ReadFull(strBuf, data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+		trailerFields := decode(strBuf)
+		Expect(trailerFields).To(HaveLen(2))
+		Expect(trailerFields).To(HaveKeyWithValue("grpc-status", "0"))
+		Expect(trailerFields).To(HaveKeyWithValue("grpc-message", "ok"))
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("sends trailer values that are set while the body is read", func() {
+		closed := make(chan struct{})
+		str.EXPECT().Close().Do(func() { close(closed) })
+		req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Checksum": nil}
+		body := &mockBody{}
+		body.SetData([]byte("foobar"))
+		req.Body = &trailerSettingBody{mockBody: body, onEOF: func() { req.Trailer.Set("Checksum", "1234") }}
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+		Eventually(closed).Should(BeClosed())
+		Expect(decode(strBuf)).To(HaveKeyWithValue("trailer", "Checksum"))
+		frame, err := parseNextFrame(strBuf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
+		_, err = io.CopyN(ioutil.Discard, strBuf, int64(frame.(*dataFrame).Length))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decode(strBuf)).To(Equal(map[string]string{"checksum": "1234"}))
+	})
+
+	It("sends trailers for requests without a body", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Foo": []string{"bar"}}
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+		Expect(decode(strBuf)).To(HaveKeyWithValue("trailer", "Foo"))
+		Expect(decode(strBuf)).To(Equal(map[string]string{"foo": "bar"}))
+	})
+
+	It("doesn't send a trailer header if the request has no trailers", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
+		Expect(decode(strBuf)).ToNot(HaveKey("trailer"))
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("rejects invalid trailer keys", func() {
+		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Content-Length": []string{"42"}}
+		Expect(rw.WriteRequest(str, req, false, false)).To(MatchError(`invalid Trailer key "Content-Length"`))
+	})
+
 	It("sends cookies", func() {
 		str.EXPECT().Close()
 		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
@@ -98,7 +290,7 @@ var _ = Describe("Request Writer", func() {
 		}
 		req.AddCookie(cookie1)
 		req.AddCookie(cookie2)
-		Expect(rw.WriteRequest(str, req)).To(Succeed())
+		Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
 		headerFields := decode(strBuf)
 		Expect(headerFields).To(HaveKeyWithValue("cookie", `Cookie #1="Value #1"; Cookie #2="Value #2"`))
 	})
@@ -2,6 +2,7 @@ package http3
 
 import (
 	"io"
+	"sync"
 
 	quic "github.com/lucas-clemente/quic-go"
 )
@@ -12,7 +13,37 @@ type responseBody struct {
 
 var _ io.ReadCloser = &responseBody{}
 
+func (rb *responseBody) Read(p []byte) (int, error) {
+	n, err := rb.Stream.Read(p)
+	return n, streamResetError(err)
+}
+
 func (rb *responseBody) Close() error {
 	rb.Stream.CancelRead(0)
 	return nil
 }
+
+// requestDoneBody calls onDone when the response body
+// has been read completely or is closed.
+type requestDoneBody struct {
+	io.ReadCloser
+
+	onDone   func()
+	doneOnce sync.Once
+}
+
+var _ io.ReadCloser = &requestDoneBody{}
+
+func (b *requestDoneBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.doneOnce.Do(b.onDone)
+	}
+	return n, err
+}
+
+func (b *requestDoneBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.doneOnce.Do(b.onDone)
+	return err
+}
@@ -2,32 +2,51 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
 )
 
+// The responseStream is the stream that the response is written to.
+// Data written to it may be buffered, if write coalescing is enabled (see quic.Config.WriteCoalescingDelay).
+type responseStream interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// errRequestRefused is returned by Write after the handler called RefuseRequest
+var errRequestRefused = errors.New("http3: write after RefuseRequest")
+
 type responseWriter struct {
-	stream io.Writer
+	stream responseStream
 
 	header        http.Header
 	status        int // status code passed to WriteHeader
 	headerWritten bool
+	rejected      bool // set by RefuseRequest
+	isHead        bool // the response to a HEAD request doesn't contain a body
+
+	contentLength int64 // the Content-Length set by the handler, -1 if unknown
+	numWritten    int64 // number of body bytes written
+	finished      bool  // set when the stream was closed, after the complete body was written
 
 	logger utils.Logger
 }
 
 var _ http.ResponseWriter = &responseWriter{}
 
-func newResponseWriter(stream io.Writer, logger utils.Logger) *responseWriter {
+func newResponseWriter(stream responseStream, logger utils.Logger) *responseWriter {
 	return &responseWriter{
-		header: http.Header{},
-		stream: stream,
-		logger: logger,
+		header:        http.Header{},
+		stream:        stream,
+		contentLength: -1,
+		logger:        logger,
 	}
 }
 
@@ -42,6 +61,16 @@ func (w *responseWriter) WriteHeader(status int) {
 	w.headerWritten = true
 	w.status = status
 
+	if cl := w.header.Get("Content-Length"); cl != "" {
+		v, err := strconv.ParseInt(cl, 10, 64)
+		if err == nil && v >= 0 {
+			w.contentLength = v
+		} else {
+			w.logger.Errorf("http3: invalid Content-Length of %q", cl)
+			w.header.Del("Content-Length")
+		}
+	}
+
 	var headers bytes.Buffer
 	enc := qpack.NewEncoder(&headers)
 	enc.WriteField(qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
@@ -61,25 +90,87 @@ func (w *responseWriter) WriteHeader(status int) {
 	if _, err := w.stream.Write(headers.Bytes()); err != nil {
 		w.logger.Errorf("could not write header frame payload: %s", err.Error())
 	}
+	w.maybeFinish()
 }
 
 func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.rejected {
+		return 0, errRequestRefused
+	}
 	if !w.headerWritten {
 		w.WriteHeader(200)
 	}
 	if !bodyAllowedForStatus(w.status) {
 		return 0, http.ErrBodyNotAllowed
 	}
+	if w.contentLength != -1 && w.numWritten+int64(len(p)) > w.contentLength {
+		return 0, http.ErrContentLength
+	}
+	if w.finished {
+		return 0, nil
+	}
+	if w.isHead {
+		// Like net/http, silently discard the body of a response to a HEAD request.
+		w.numWritten += int64(len(p))
+		return len(p), nil
+	}
 	df := &dataFrame{Length: uint64(len(p))}
 	buf := &bytes.Buffer{}
 	df.Write(buf)
 	if _, err := w.stream.Write(buf.Bytes()); err != nil {
 		return 0, err
 	}
-	return w.stream.Write(p)
+	n, err := w.stream.Write(p)
+	w.numWritten += int64(n)
+	if err == nil {
+		w.maybeFinish()
+	}
+	return n, err
+}
+
+// maybeFinish closes the stream as soon as the number of body bytes given by the Content-Length was written,
+// such that the client receives the FIN without waiting for the handler to return.
+// Closing the stream cancels the stream context, and therefore the context of the request.
+func (w *responseWriter) maybeFinish() {
+	if w.finished || w.isHead || !bodyAllowedForStatus(w.status) {
+		return
+	}
+	if w.contentLength == -1 || w.numWritten != w.contentLength {
+		return
+	}
+	w.finished = true
+	if err := w.stream.Close(); err != nil {
+		w.logger.Errorf("could not close stream: %s", err.Error())
+	}
 }
 
-func (w *responseWriter) Flush() {}
+// wroteShortBody says if the handler set a Content-Length, but wrote fewer bytes.
+// This doesn't apply to responses that don't have a body.
+func (w *responseWriter) wroteShortBody() bool {
+	if w.isHead || !bodyAllowedForStatus(w.status) {
+		return false
+	}
+	return w.contentLength != -1 && w.numWritten < w.contentLength
+}
+
+// Flush sends the response headers, if they haven't been sent yet.
+// Every call to Write is written to the stream in a DATA frame.
+// If the stream delays writes for coalescing, Flush sends the buffered data right away.
+func (w *responseWriter) Flush() {
+	if w.rejected {
+		return
+	}
+	if !w.headerWritten {
+		w.WriteHeader(200)
+	}
+	if w.finished {
+		// closing the stream flushed all data
+		return
+	}
+	if err := w.stream.Flush(); err != nil {
+		w.logger.Errorf("could not flush to stream: %s", err.Error())
+	}
+}
 
 // This is a NOP. Use http.Request.Context
 func (w *responseWriter) CloseNotify() <-chan bool { return make(<-chan bool) }
@@ -87,6 +178,33 @@ func (w *responseWriter) CloseNotify() <-chan bool { return make(<-chan bool) }
 // test that we implement http.Flusher
 var _ http.Flusher = &responseWriter{}
 
+// RejectRequest replies to the request with a 503 (Service Unavailable) status code.
+// The Retry-After header tells the client to wait for retryAfter before retrying the request.
+// It must be called before the handler has written any response data.
+func RejectRequest(w http.ResponseWriter, retryAfter time.Duration) {
+	secs := (retryAfter + time.Second - 1) / time.Second
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(secs), 10))
+	w.WriteHeader(http.StatusServiceUnavailable)
+}
+
+// RefuseRequest resets the request stream with HTTP_REQUEST_REJECTED,
+// signaling the client that the request was not processed and can safely be retried,
+// e.g. on a different connection.
+// It must be called before the handler has written any response data,
+// and only works with the http.ResponseWriter passed to the handler by the http3.Server.
+func RefuseRequest(w http.ResponseWriter) error {
+	rw, ok := w.(*responseWriter)
+	if !ok {
+		return errors.New("http3: RefuseRequest called with a non-HTTP/3 http.ResponseWriter")
+	}
+	if rw.headerWritten {
+		return errors.New("http3: RefuseRequest called after the response headers were written")
+	}
+	rw.headerWritten = true
+	rw.rejected = true
+	return nil
+}
+
 // copied from http2/http2.go
 // bodyAllowedForStatus reports whether a given response status code
 // permits a body. See RFC 2616, section 4.4.
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
@@ -12,6 +14,29 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// nopFlusher is a responseStream that doesn't buffer any data, and ignores Close
+type nopFlusher struct{ io.Writer }
+
+func (nopFlusher) Flush() error { return nil }
+func (nopFlusher) Close() error { return nil }
+
+// coalescingStream is a responseStream that buffers all data until Flush or Close is called
+type coalescingStream struct {
+	buffered bytes.Buffer
+	flushed  bytes.Buffer
+	closed   bool
+}
+
+func (s *coalescingStream) Write(p []byte) (int, error) { return s.buffered.Write(p) }
+func (s *coalescingStream) Flush() error {
+	_, err := s.buffered.WriteTo(&s.flushed)
+	return err
+}
+func (s *coalescingStream) Close() error {
+	s.closed = true
+	return s.Flush()
+}
+
 var _ = Describe("Response Writer", func() {
 	var (
 		rw     *responseWriter
@@ -20,7 +45,7 @@ var _ = Describe("Response Writer", func() {
 
 	BeforeEach(func() {
 		strBuf = &bytes.Buffer{}
-		rw = newResponseWriter(strBuf, utils.DefaultLogger)
+		rw = newResponseWriter(nopFlusher{strBuf}, utils.DefaultLogger)
 	})
 
 	decodeHeader := func(str io.Reader) map[string][]string {
@@ -103,6 +128,122 @@ var _ = Describe("Response Writer", func() {
 		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
 	})
 
+	It("sends the headers when flushed", func() {
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.Flush()
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(fields).To(HaveKeyWithValue("content-type", []string{"text/event-stream"}))
+		Expect(fields).ToNot(HaveKey("content-length"))
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("writes a DATA frame for every Write, without buffering", func() {
+		n, err := rw.Write([]byte("foo"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+		rw.Flush()
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(fields).ToNot(HaveKey("content-length"))
+		Expect(getData(strBuf)).To(Equal([]byte("foo")))
+		Expect(strBuf.Len()).To(BeZero())
+		_, err = rw.Write([]byte("bar"))
+		Expect(err).ToNot(HaveOccurred())
+		rw.Flush()
+		Expect(getData(strBuf)).To(Equal([]byte("bar")))
+		_, err = rw.Write([]byte("baz"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(getData(strBuf)).To(Equal([]byte("baz")))
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("sends the Content-Length set by the handler", func() {
+		rw.Header().Set("Content-Length", "6")
+		n, err := rw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(6))
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue("content-length", []string{"6"}))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		Expect(rw.wroteShortBody()).To(BeFalse())
+	})
+
+	It("closes the stream as soon as the Content-Length was written", func() {
+		str := &coalescingStream{}
+		rw = newResponseWriter(str, utils.DefaultLogger)
+		rw.Header().Set("Content-Length", "6")
+		_, err := rw.Write([]byte("foo"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.closed).To(BeFalse())
+		_, err = rw.Write([]byte("bar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.closed).To(BeTrue())
+		Expect(rw.finished).To(BeTrue())
+		Expect(str.buffered.Len()).To(BeZero())
+		fields := decodeHeader(&str.flushed)
+		Expect(fields).To(HaveKeyWithValue("content-length", []string{"6"}))
+		Expect(getData(&str.flushed)).To(Equal([]byte("foo")))
+		Expect(getData(&str.flushed)).To(Equal([]byte("bar")))
+		rw.Flush()
+		Expect(str.flushed.Len()).To(BeZero())
+	})
+
+	It("closes the stream when writing the headers, if the Content-Length is 0", func() {
+		str := &coalescingStream{}
+		rw = newResponseWriter(str, utils.DefaultLogger)
+		rw.Header().Set("Content-Length", "0")
+		rw.WriteHeader(http.StatusOK)
+		Expect(str.closed).To(BeTrue())
+		n, err := rw.Write([]byte{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(BeZero())
+	})
+
+	It("doesn't close the stream if no Content-Length was set", func() {
+		str := &coalescingStream{}
+		rw = newResponseWriter(str, utils.DefaultLogger)
+		_, err := rw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.closed).To(BeFalse())
+	})
+
+	It("doesn't close the stream for a response to a HEAD request", func() {
+		str := &coalescingStream{}
+		rw = newResponseWriter(str, utils.DefaultLogger)
+		rw.isHead = true
+		rw.Header().Set("Content-Length", "6")
+		_, err := rw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.closed).To(BeFalse())
+	})
+
+	It("errors when writing more than the Content-Length", func() {
+		rw.Header().Set("Content-Length", "6")
+		_, err := rw.Write([]byte("foo"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rw.wroteShortBody()).To(BeTrue())
+		_, err = rw.Write([]byte("barbaz"))
+		Expect(err).To(MatchError(http.ErrContentLength))
+		_, err = rw.Write([]byte("bar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rw.wroteShortBody()).To(BeFalse())
+		_, err = rw.Write([]byte("!"))
+		Expect(err).To(MatchError(http.ErrContentLength))
+		decodeHeader(strBuf)
+		Expect(getData(strBuf)).To(Equal([]byte("foo")))
+		Expect(getData(strBuf)).To(Equal([]byte("bar")))
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("ignores an invalid Content-Length", func() {
+		rw.Header().Set("Content-Length", "foobar")
+		_, err := rw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decodeHeader(strBuf)).ToNot(HaveKey("content-length"))
+		Expect(rw.wroteShortBody()).To(BeFalse())
+	})
+
 	It("does not WriteHeader() twice", func() {
 		rw.WriteHeader(200)
 		rw.WriteHeader(500)
@@ -117,4 +258,74 @@ var _ = Describe("Response Writer", func() {
 		Expect(n).To(BeZero())
 		Expect(err).To(MatchError(http.ErrBodyNotAllowed))
 	})
+
+	It("discards the body of a response to a HEAD request", func() {
+		rw.isHead = true
+		rw.Header().Set("Content-Length", "6")
+		n, err := rw.Write([]byte("foo"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+		Expect(rw.wroteShortBody()).To(BeFalse())
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(fields).To(HaveKeyWithValue("content-length", []string{"6"}))
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("doesn't consider a response with a status code that doesn't allow a body as too short", func() {
+		rw.Header().Set("Content-Length", "6")
+		rw.WriteHeader(304)
+		Expect(rw.wroteShortBody()).To(BeFalse())
+	})
+
+	It("flushes data buffered for write coalescing", func() {
+		str := &coalescingStream{}
+		rw = newResponseWriter(str, utils.DefaultLogger)
+		rw.Header().Set("Content-Type", "text/event-stream")
+		_, err := rw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.flushed.Len()).To(BeZero())
+		rw.Flush()
+		Expect(str.buffered.Len()).To(BeZero())
+		fields := decodeHeader(&str.flushed)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(getData(&str.flushed)).To(Equal([]byte("foobar")))
+	})
+
+	It("rejects a request with a Retry-After header", func() {
+		RejectRequest(rw, 1500*time.Millisecond)
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"503"}))
+		Expect(fields).To(HaveKeyWithValue("retry-after", []string{"2"}))
+	})
+
+	It("refuses a request", func() {
+		Expect(RefuseRequest(rw)).To(Succeed())
+		Expect(rw.rejected).To(BeTrue())
+		rw.WriteHeader(200)
+		Expect(strBuf.Len()).To(BeZero())
+	})
+
+	It("doesn't write anything after refusing a request", func() {
+		str := &coalescingStream{}
+		rw = newResponseWriter(str, utils.DefaultLogger)
+		Expect(RefuseRequest(rw)).To(Succeed())
+		rw.WriteHeader(200)
+		n, err := rw.Write([]byte("foobar"))
+		Expect(err).To(MatchError(errRequestRefused))
+		Expect(n).To(BeZero())
+		rw.Flush()
+		Expect(str.buffered.Len()).To(BeZero())
+		Expect(str.flushed.Len()).To(BeZero())
+	})
+
+	It("doesn't refuse a request after the headers were written", func() {
+		rw.WriteHeader(200)
+		Expect(RefuseRequest(rw)).To(MatchError("http3: RefuseRequest called after the response headers were written"))
+		Expect(rw.rejected).To(BeFalse())
+	})
+
+	It("doesn't refuse a request for a non-HTTP/3 http.ResponseWriter", func() {
+		Expect(RefuseRequest(httptest.NewRecorder())).To(MatchError("http3: RefuseRequest called with a non-HTTP/3 http.ResponseWriter"))
+	})
 })
@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	quic "github.com/lucas-clemente/quic-go"
 
@@ -15,11 +18,19 @@ import (
 )
 
 type roundTripCloser interface {
-	http.RoundTripper
+	RoundTripOpt(*http.Request, RoundTripOpt) (*http.Response, error)
 	io.Closer
 }
 
-// RoundTripper implements the http.RoundTripper interface
+// a roundTripDrainer can be shut down gracefully
+type roundTripDrainer interface {
+	roundTripCloser
+	drain()
+}
+
+// RoundTripper implements the http.RoundTripper interface.
+// Concurrent requests to a host that is not connected yet wait for a single dial,
+// and then share the resulting QUIC session (unless DisableKeepAlives is set).
 type RoundTripper struct {
 	mutex sync.Mutex
 
@@ -33,6 +44,18 @@ type RoundTripper struct {
 	// uncompressed.
 	DisableCompression bool
 
+	// DisableKeepAlives, if true, prevents the RoundTripper from reusing
+	// QUIC sessions for multiple requests. A new session is dialed for
+	// every request, and closed once the response body has been read
+	// completely or closed.
+	DisableKeepAlives bool
+
+	// MaxEmptyFrames is the maximum number of frames without any body data
+	// (e.g. empty DATA frames, or unknown frames) that the server may send on a request stream.
+	// If it is exceeded, the session is closed with HTTP_EXCESSIVE_LOAD.
+	// If zero, a default of 100 is used. If negative, the number of frames is not limited.
+	MaxEmptyFrames int
+
 	// TLSClientConfig specifies the TLS configuration to use with
 	// tls.Client. If nil, the default configuration is used.
 	TLSClientConfig *tls.Config
@@ -46,9 +69,58 @@ type RoundTripper struct {
 	// If Dial is nil, quic.DialAddr will be used.
 	Dial func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error)
 
-	clients map[string]roundTripCloser
+	// MaxRetries is the maximum number of times a request is retried when the server
+	// responds with a 503 (Service Unavailable) status code and a Retry-After header.
+	// Before retrying, the RoundTripper waits for the duration requested by the server.
+	// If the server requests a delay longer than 10 seconds, the 503 response is returned instead.
+	// Only requests that can safely be replayed are retried, i.e. requests without a body
+	// that use the GET, HEAD, OPTIONS or TRACE method.
+	// If zero, requests are never retried.
+	// Independent of MaxRetries, replayable requests are retried up to 3 times
+	// when the server resets the request stream with HTTP_REQUEST_REJECTED.
+	// These retries are sent on a new connection, and the connection that rejected the request is drained.
+	MaxRetries int
+
+	// CircuitBreakerThreshold is the number of consecutive failed dials to a host
+	// after which the RoundTripper stops dialing that host for CircuitBreakerCooldown.
+	// During that time, requests to the host fail with ErrCircuitOpen,
+	// or are sent using the CircuitBreakerFallback, if set.
+	// After the cooldown, a single dial is attempted. If it fails, the cooldown starts again.
+	// If zero, the circuit breaker is disabled.
+	CircuitBreakerThreshold int
+
+	// CircuitBreakerCooldown is the duration that a host is not dialed after
+	// CircuitBreakerThreshold consecutive dials failed.
+	// If zero, it defaults to 30 seconds.
+	CircuitBreakerCooldown time.Duration
+
+	// CircuitBreakerFallback is used to send requests to hosts that are not dialed
+	// due to the circuit breaker, e.g. an http.Transport using HTTP/1.1 or HTTP/2.
+	CircuitBreakerFallback http.RoundTripper
+
+	// MaxRedirects is the maximum number of redirects (301, 302, 303, 307 and 308) that the RoundTripper follows.
+	// Redirects to another origin are sent on a connection to that origin.
+	// If MaxRedirects is set, the RoundTripper also remembers the HTTP/3 alternative services that origins advertise
+	// in the Alt-Svc header (RFC 7838). When dialing an origin that advertised an alternative service,
+	// the alternative service is dialed instead.
+	// Only redirects to https URLs are followed. When the limit is reached, the redirect response is returned.
+	// If zero, redirect responses are returned, e.g. to be handled by the http.Client,
+	// and Alt-Svc headers are ignored.
+	MaxRedirects int
+
+	clients map[string]roundTripDrainer
+	breaker *circuitBreaker
+	altSvc  *altSvcCache
 }
 
+// maxRejectedRetries is the maximum number of times a request is retried
+// when the server resets the request stream with HTTP_REQUEST_REJECTED.
+const maxRejectedRetries = 3
+
+// maxRetryAfter is the longest delay requested by a Retry-After header that the RoundTripper waits for.
+// If the server requests a longer delay, the 503 response is returned without retrying.
+const maxRetryAfter = 10 * time.Second
+
 // RoundTripOpt are options for the Transport.RoundTripOpt method.
 type RoundTripOpt struct {
 	// OnlyCachedConn controls whether the RoundTripper may
@@ -56,6 +128,10 @@ type RoundTripOpt struct {
 	// no cached connection is available, RoundTrip
 	// will return ErrNoCachedConn.
 	OnlyCachedConn bool
+	// OmitContentLength controls whether the content-length header is omitted.
+	// By default, it is sent if the length of the request body is known,
+	// and if the request method permits a body.
+	OmitContentLength bool
 }
 
 var _ roundTripCloser = &RoundTripper{}
@@ -65,6 +141,23 @@ var ErrNoCachedConn = errors.New("http3: no cached connection was available")
 
 // RoundTripOpt is like RoundTrip, but takes options.
 func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
+	for redirects := 0; ; redirects++ {
+		rsp, err := r.roundTripOpt(req, opt)
+		if err != nil || redirects >= r.MaxRedirects {
+			return rsp, err
+		}
+		next, ok := redirectRequest(req, rsp)
+		if !ok {
+			return rsp, nil
+		}
+		if rsp.Body != nil {
+			rsp.Body.Close()
+		}
+		req = next
+	}
+}
+
+func (r *RoundTripper) roundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
 	if req.URL == nil {
 		closeRequestBody(req)
 		return nil, errors.New("http3: nil Request.URL")
@@ -101,10 +194,54 @@ func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.
 
 	hostname := authorityAddr("https", hostnameFromRequest(req))
 	cl, err := r.getClient(hostname, opt.OnlyCachedConn)
+	if err == ErrCircuitOpen && r.CircuitBreakerFallback != nil {
+		return r.CircuitBreakerFallback.RoundTrip(req)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return cl.RoundTrip(req)
+	var retries, rejected int
+	for {
+		rsp, err := cl.RoundTripOpt(req, opt)
+		if rsp != nil && r.MaxRedirects > 0 {
+			r.getAltSvcCache().Update(hostname, rsp.Header.Get("Alt-Svc"))
+		}
+		if rerr, ok := err.(*StreamResetError); ok && rerr.Retryable() && rejected < maxRejectedRetries && isReplayable(req) {
+			// The server didn't process the request.
+			// Send it again, on a new connection.
+			rejected++
+			r.removeClient(hostname, cl)
+			if cl, err = r.getClient(hostname, opt.OnlyCachedConn); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil || retries >= r.MaxRetries || !isReplayable(req) {
+			return rsp, err
+		}
+		delay, ok := retryAfter(rsp)
+		if !ok || delay > maxRetryAfter {
+			return rsp, nil
+		}
+		if rsp.Body != nil {
+			rsp.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		retries++
+		if r.DisableKeepAlives {
+			// The session was closed when the body of the 503 was closed.
+			r.removeClient(hostname, cl)
+			if cl, err = r.getClient(hostname, opt.OnlyCachedConn); err != nil {
+				return nil, err
+			}
+		}
+	}
 }
 
 // RoundTrip does a round trip.
@@ -112,12 +249,12 @@ func (r *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	return r.RoundTripOpt(req, RoundTripOpt{})
 }
 
-func (r *RoundTripper) getClient(hostname string, onlyCached bool) (http.RoundTripper, error) {
+func (r *RoundTripper) getClient(hostname string, onlyCached bool) (roundTripCloser, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if r.clients == nil {
-		r.clients = make(map[string]roundTripCloser)
+		r.clients = make(map[string]roundTripDrainer)
 	}
 
 	client, ok := r.clients[hostname]
@@ -125,18 +262,118 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (http.RoundTr
 		if onlyCached {
 			return nil, ErrNoCachedConn
 		}
+		dial := r.Dial
+		if r.CircuitBreakerThreshold > 0 {
+			if r.breaker == nil {
+				r.breaker = newCircuitBreaker(r.CircuitBreakerThreshold, r.CircuitBreakerCooldown)
+			}
+			if err := r.breaker.Allow(hostname); err != nil {
+				return nil, err
+			}
+			dial = r.dialWithCircuitBreaker(hostname, &client)
+		}
+		tlsConf := r.TLSClientConfig
+		if r.altSvc != nil && r.MaxRedirects > 0 {
+			if alt, ok := r.altSvc.Get(hostname); ok && alt != hostname {
+				tlsConf = tlsConfigForOrigin(tlsConf, hostname)
+				dial = dialAlternative(dial, alt)
+			}
+		}
 		client = newClient(
 			hostname,
-			r.TLSClientConfig,
-			&roundTripperOpts{DisableCompression: r.DisableCompression},
+			tlsConf,
+			&roundTripperOpts{
+				DisableCompression: r.DisableCompression,
+				DisableKeepAlives:  r.DisableKeepAlives,
+				MaxEmptyFrames:     r.MaxEmptyFrames,
+			},
 			r.QuicConfig,
-			r.Dial,
+			dial,
 		)
-		r.clients[hostname] = client
+		if !r.DisableKeepAlives {
+			r.clients[hostname] = client
+		}
 	}
 	return client, nil
 }
 
+// removeClient removes the client from the clients used for the host, such that the next request dials a new connection.
+// If the host already uses a different client, e.g. because the RoundTripper was drained, nothing is changed.
+// The client is drained, and closed after its outstanding requests completed.
+func (r *RoundTripper) removeClient(hostname string, cl roundTripCloser) {
+	r.mutex.Lock()
+	current, ok := r.clients[hostname]
+	if !ok || current != cl {
+		r.mutex.Unlock()
+		return
+	}
+	delete(r.clients, hostname)
+	r.mutex.Unlock()
+
+	current.drain()
+}
+
+// dialWithCircuitBreaker returns a dial function that reports the outcome to the circuit breaker.
+// When dialing fails, the client cl is removed, such that the host is dialed again for the next request.
+// cl is set after the dial function was created, and is only read while holding the mutex.
+// If the host already uses a different client, that client is kept.
+func (r *RoundTripper) dialWithCircuitBreaker(hostname string, cl *roundTripDrainer) func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error) {
+	return func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error) {
+		var sess quic.Session
+		var err error
+		if r.Dial != nil {
+			sess, err = r.Dial(network, addr, tlsCfg, cfg)
+		} else {
+			sess, err = dialAddr(addr, tlsCfg, cfg)
+		}
+		r.mutex.Lock()
+		if current, ok := r.clients[hostname]; err != nil && ok && current == *cl {
+			delete(r.clients, hostname)
+		}
+		r.breaker.Record(hostname, err)
+		r.mutex.Unlock()
+		return sess, err
+	}
+}
+
+func (r *RoundTripper) getAltSvcCache() *altSvcCache {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.altSvc == nil {
+		r.altSvc = newAltSvcCache()
+	}
+	return r.altSvc
+}
+
+// dialAlternative returns a dial function that dials the alternative service at addr, instead of the origin.
+func dialAlternative(
+	dial func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error),
+	addr string,
+) func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error) {
+	return func(network, _ string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error) {
+		if dial != nil {
+			return dial(network, addr, tlsCfg, cfg)
+		}
+		return dialAddr(addr, tlsCfg, cfg)
+	}
+}
+
+// tlsConfigForOrigin returns a tls.Config that uses the host of the origin for SNI and certificate verification,
+// since the alternative service is authoritative for the origin.
+func tlsConfigForOrigin(tlsConf *tls.Config, origin string) *tls.Config {
+	if tlsConf == nil {
+		tlsConf = &tls.Config{}
+	} else {
+		tlsConf = tlsConf.Clone()
+	}
+	if tlsConf.ServerName == "" {
+		if host, _, err := net.SplitHostPort(origin); err == nil {
+			tlsConf.ServerName = host
+		}
+	}
+	return tlsConf
+}
+
 // Close closes the QUIC connections that this RoundTripper has used
 func (r *RoundTripper) Close() error {
 	r.mutex.Lock()
@@ -150,6 +387,119 @@ func (r *RoundTripper) Close() error {
 	return nil
 }
 
+// Drain gracefully shuts down the QUIC connections that this RoundTripper currently uses.
+// A GOAWAY frame is sent on every connection, and the connection is closed as soon as all outstanding requests have completed.
+// Subsequent requests are sent on new connections.
+// This can be used to periodically rotate connections.
+func (r *RoundTripper) Drain() {
+	r.mutex.Lock()
+	clients := r.clients
+	r.clients = nil
+	r.mutex.Unlock()
+
+	for _, client := range clients {
+		client.drain()
+	}
+}
+
+// redirectRequest returns the request for following the redirect in rsp.
+// It returns false if rsp is not a redirect, or if the redirect can't be followed.
+func redirectRequest(req *http.Request, rsp *http.Response) (*http.Request, bool) {
+	method := req.Method
+	body := req.Body
+	getBody := req.GetBody
+	contentLength := req.ContentLength
+	switch rsp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther:
+		// same as net/http: the redirect is followed using a GET request without a body
+		if method != "GET" && method != "HEAD" {
+			method = "GET"
+		}
+		body = nil
+		getBody = nil
+		contentLength = 0
+	case http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		// the redirect is followed using the same method and body
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return nil, false
+			}
+			var err error
+			if body, err = req.GetBody(); err != nil {
+				return nil, false
+			}
+		}
+	default:
+		return nil, false
+	}
+	loc := rsp.Header.Get("Location")
+	if loc == "" {
+		return nil, false
+	}
+	u, err := req.URL.Parse(loc)
+	if err != nil || u.Scheme != "https" {
+		return nil, false
+	}
+
+	next := new(http.Request)
+	*next = *req
+	next.Method = method
+	next.URL = u
+	next.Host = ""
+	next.Body = body
+	next.GetBody = getBody
+	next.ContentLength = contentLength
+	next.Header = make(http.Header, len(req.Header))
+	for k, vv := range req.Header {
+		next.Header[k] = append([]string(nil), vv...)
+	}
+	if body == nil {
+		next.Header.Del("Content-Type")
+	}
+	// same as net/http: don't send credentials to another host
+	if u.Host != req.URL.Host {
+		for _, k := range []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"} {
+			next.Header.Del(k)
+		}
+	}
+	return next, true
+}
+
+// isReplayable reports whether a request can be sent again without side effects.
+func isReplayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	switch req.Method {
+	case "", "GET", "HEAD", "OPTIONS", "TRACE":
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay requested by a 503 response carrying a Retry-After header.
+// The header value can either be a number of seconds or an HTTP-date.
+func retryAfter(rsp *http.Response) (time.Duration, bool) {
+	if rsp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	val := rsp.Header.Get("Retry-After")
+	if val == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseUint(val, 10, 32); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(val)
+	if err != nil {
+		return 0, false
+	}
+	if delay := time.Until(t); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
 func closeRequestBody(req *http.Request) {
 	if req.Body != nil {
 		req.Body.Close()
@@ -2,32 +2,75 @@ package http3
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	quic "github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qpack"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
 type mockClient struct {
-	closed bool
+	closed  bool
+	drained bool
+	opt     RoundTripOpt // the options of the last request
 }
 
 func (m *mockClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	return m.RoundTripOpt(req, RoundTripOpt{})
+}
+func (m *mockClient) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
+	m.opt = opt
 	return &http.Response{Request: req}, nil
 }
 func (m *mockClient) Close() error {
 	m.closed = true
 	return nil
 }
+func (m *mockClient) drain() { m.drained = true }
+
+var _ roundTripDrainer = &mockClient{}
 
-var _ roundTripCloser = &mockClient{}
+// mockResponder replies to requests with a list of predefined responses
+type mockResponder struct {
+	errs      []error // returned before the responses
+	responses []*http.Response
+	requests  []time.Time
+	onRequest func()
+}
+
+func (m *mockResponder) RoundTripOpt(req *http.Request, _ RoundTripOpt) (*http.Response, error) {
+	m.requests = append(m.requests, time.Now())
+	if m.onRequest != nil {
+		m.onRequest()
+	}
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		return nil, err
+	}
+	rsp := m.responses[0]
+	m.responses = m.responses[1:]
+	rsp.Request = req
+	return rsp, nil
+}
+func (m *mockResponder) Close() error { return nil }
+func (m *mockResponder) drain()       {}
+
+var _ roundTripDrainer = &mockResponder{}
 
 type mockBody struct {
 	reader   bytes.Reader
@@ -144,12 +187,283 @@ var _ = Describe("RoundTripper", func() {
 			Eventually(closed).Should(BeClosed())
 		})
 
+		It("dials a host only once for concurrent requests", func() {
+			const num = 100
+			var numDials int32
+			unblockDial := make(chan struct{})
+			dialAddr = func(string, *tls.Config, *quic.Config) (quic.Session, error) {
+				atomic.AddInt32(&numDials, 1)
+				<-unblockDial
+				return session, nil
+			}
+			testErr := errors.New("test err")
+			session.EXPECT().OpenUniStreamSync().AnyTimes().Return(nil, testErr)
+			session.EXPECT().OpenStreamSync().Return(nil, testErr).Times(num)
+			session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).AnyTimes()
+
+			var started, done sync.WaitGroup
+			started.Add(num)
+			done.Add(num)
+			for i := 0; i < num; i++ {
+				go func() {
+					defer GinkgoRecover()
+					defer done.Done()
+					req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
+					Expect(err).ToNot(HaveOccurred())
+					started.Done()
+					_, err = rt.RoundTrip(req)
+					Expect(err).To(MatchError(testErr))
+				}()
+			}
+			started.Wait()
+			// give the requests some time to wait for the dial
+			time.Sleep(10 * time.Millisecond)
+			close(unblockDial)
+			done.Wait()
+			Expect(atomic.LoadInt32(&numDials)).To(BeEquivalentTo(1))
+			Expect(rt.clients).To(HaveLen(1))
+		})
+
+		Context("retrying rejected requests", func() {
+			var (
+				numDials int
+				str      *mockquic.MockStream
+			)
+			rejected := &streamCanceledError{code: quic.ErrorCode(errorRequestRejected)}
+
+			BeforeEach(func() {
+				numDials = 0
+				dialAddr = func(string, *tls.Config, *quic.Config) (quic.Session, error) {
+					numDials++
+					return session, nil
+				}
+				str = mockquic.NewMockStream(mockCtrl)
+				session.EXPECT().OpenUniStreamSync().AnyTimes().Return(nil, errors.New("test err"))
+				session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).AnyTimes()
+				// the session that rejected the request is drained, and closed since no requests are outstanding
+				session.EXPECT().Close().AnyTimes()
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close().AnyTimes()
+			})
+
+			It("retries requests that the server rejected on a new connection", func() {
+				rspBuf := &bytes.Buffer{}
+				newResponseWriter(nopFlusher{rspBuf}, utils.DefaultLogger).WriteHeader(200)
+				session.EXPECT().OpenStreamSync().Return(str, nil).Times(2)
+				gomock.InOrder(
+					str.EXPECT().Read(gomock.Any()).Return(0, rejected),
+					str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes(),
+				)
+				rsp, err := rt.RoundTrip(req1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Expect(numDials).To(Equal(2))
+				Expect(rt.clients).To(HaveLen(1))
+			})
+
+			It("gives up after a few attempts", func() {
+				session.EXPECT().OpenStreamSync().Return(str, nil).Times(maxRejectedRetries + 1)
+				str.EXPECT().Read(gomock.Any()).Return(0, rejected).Times(maxRejectedRetries + 1)
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(MatchError(&StreamResetError{ErrorCode: quic.ErrorCode(errorRequestRejected)}))
+				Expect(numDials).To(Equal(maxRejectedRetries + 1))
+			})
+		})
+
+		It("doesn't reuse clients if keep-alives are disabled", func() {
+			rt.DisableKeepAlives = true
+			var numDials int
+			testErr := errors.New("test err")
+			dialAddr = func(string, *tls.Config, *quic.Config) (quic.Session, error) {
+				numDials++
+				return session, nil
+			}
+			session.EXPECT().OpenUniStreamSync().AnyTimes().Return(nil, testErr)
+			session.EXPECT().OpenStreamSync().Return(nil, testErr).Times(2)
+			session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).AnyTimes()
+			session.EXPECT().Close().Times(2)
+			for i := 0; i < 2; i++ {
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(MatchError(testErr))
+			}
+			Expect(numDials).To(Equal(2))
+			Expect(rt.clients).To(BeEmpty())
+		})
+
+		It("retries on a new connection after a Retry-After if keep-alives are disabled", func() {
+			rt.DisableKeepAlives = true
+			rt.MaxRetries = 1
+			var numDials int
+			dialAddr = func(string, *tls.Config, *quic.Config) (quic.Session, error) {
+				numDials++
+				return session, nil
+			}
+			serviceUnavail := &bytes.Buffer{}
+			rw := newResponseWriter(nopFlusher{serviceUnavail}, utils.DefaultLogger)
+			rw.Header().Set("Retry-After", "0")
+			rw.WriteHeader(503)
+			ok := &bytes.Buffer{}
+			newResponseWriter(nopFlusher{ok}, utils.DefaultLogger).WriteHeader(200)
+			str1 := mockquic.NewMockStream(mockCtrl)
+			str2 := mockquic.NewMockStream(mockCtrl)
+			for _, str := range []*mockquic.MockStream{str1, str2} {
+				str.EXPECT().Write(gomock.Any()).AnyTimes()
+				str.EXPECT().Close().AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+			}
+			str1.EXPECT().Read(gomock.Any()).DoAndReturn(serviceUnavail.Read).AnyTimes()
+			str2.EXPECT().Read(gomock.Any()).DoAndReturn(ok.Read).AnyTimes()
+			session.EXPECT().OpenUniStreamSync().AnyTimes().Return(nil, errors.New("test err"))
+			session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).AnyTimes()
+			gomock.InOrder(
+				session.EXPECT().OpenStreamSync().Return(str1, nil),
+				// closing the body of the 503 closes the first session
+				session.EXPECT().Close(),
+				session.EXPECT().OpenStreamSync().Return(str2, nil),
+			)
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(200))
+			Expect(numDials).To(Equal(2))
+			session.EXPECT().Close()
+			Expect(rsp.Body.Close()).To(Succeed())
+		})
+
+		It("doesn't request gzip if compression is disabled", func() {
+			rt.DisableCompression = true
+			str := mockquic.NewMockStream(mockCtrl)
+			buf := &bytes.Buffer{}
+			session.EXPECT().OpenUniStreamSync().AnyTimes().Return(nil, errors.New("test err"))
+			session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).AnyTimes()
+			session.EXPECT().OpenStreamSync().Return(str, nil)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(buf.Write)
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).Return(0, errors.New("test done"))
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError("test done"))
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&headersFrame{}))
+			data := make([]byte, frame.(*headersFrame).Length)
+			_, err = io.ReadFull(buf, data)
+			Expect(err).ToNot(HaveOccurred())
+			hfs, err := qpack.NewDecoder(nil).DecodeFull(data)
+			Expect(err).ToNot(HaveOccurred())
+			for _, hf := range hfs {
+				Expect(hf.Name).ToNot(Equal("accept-encoding"))
+			}
+		})
+
 		It("doesn't create new clients if RoundTripOpt.OnlyCachedConn is set", func() {
 			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
 			Expect(err).ToNot(HaveOccurred())
 			_, err = rt.RoundTripOpt(req, RoundTripOpt{OnlyCachedConn: true})
 			Expect(err).To(MatchError(ErrNoCachedConn))
 		})
+
+		It("passes the RoundTripOpt to the client", func() {
+			cl := &mockClient{}
+			rt.clients = map[string]roundTripDrainer{"quic.clemente.io:443": cl}
+			req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", strings.NewReader("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTripOpt(req, RoundTripOpt{OmitContentLength: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cl.opt.OmitContentLength).To(BeTrue())
+			_, err = rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cl.opt.OmitContentLength).To(BeFalse())
+		})
+
+		Context("circuit breaker", func() {
+			var numDials int
+
+			BeforeEach(func() {
+				numDials = 0
+				dialAddr = func(string, *tls.Config, *quic.Config) (quic.Session, error) {
+					numDials++
+					return nil, errors.New("handshake error")
+				}
+				rt.CircuitBreakerThreshold = 2
+				rt.CircuitBreakerCooldown = 50 * time.Millisecond
+			})
+
+			It("redials a host after a failed dial", func() {
+				for i := 0; i < 2; i++ {
+					_, err := rt.RoundTrip(req1)
+					Expect(err).To(MatchError("handshake error"))
+				}
+				Expect(numDials).To(Equal(2))
+				Expect(rt.clients).To(BeEmpty())
+			})
+
+			It("doesn't remove a different client when a dial fails", func() {
+				other := &mockClient{}
+				dialAddr = func(string, *tls.Config, *quic.Config) (quic.Session, error) {
+					numDials++
+					// the host switched to a different client while this client was dialing
+					rt.clients["www.example.org:443"] = other
+					return nil, errors.New("handshake error")
+				}
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(MatchError("handshake error"))
+				Expect(numDials).To(Equal(1))
+				Expect(rt.clients).To(HaveKeyWithValue("www.example.org:443", other))
+			})
+
+			It("opens after the threshold is reached, and half-opens after the cooldown", func() {
+				for i := 0; i < 2; i++ {
+					_, err := rt.RoundTrip(req1)
+					Expect(err).To(MatchError("handshake error"))
+				}
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(MatchError(ErrCircuitOpen))
+				Expect(numDials).To(Equal(2))
+				// after the cooldown, the host is dialed again
+				Eventually(func() error {
+					_, err := rt.RoundTrip(req1)
+					return err
+				}).Should(MatchError("handshake error"))
+				Expect(numDials).To(Equal(3))
+				_, err = rt.RoundTrip(req1)
+				Expect(err).To(MatchError(ErrCircuitOpen))
+			})
+
+			It("closes when a dial succeeds", func() {
+				for i := 0; i < 2; i++ {
+					_, err := rt.RoundTrip(req1)
+					Expect(err).To(MatchError("handshake error"))
+				}
+				testErr := errors.New("test err")
+				session.EXPECT().OpenUniStreamSync().AnyTimes().Return(nil, testErr)
+				session.EXPECT().OpenStreamSync().Return(nil, testErr).Times(2)
+				session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).AnyTimes()
+				dialAddr = func(string, *tls.Config, *quic.Config) (quic.Session, error) {
+					numDials++
+					return session, nil
+				}
+				Eventually(func() error {
+					_, err := rt.RoundTrip(req1)
+					return err
+				}).Should(MatchError(testErr))
+				Expect(numDials).To(Equal(3))
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(MatchError(testErr))
+				Expect(numDials).To(Equal(3))
+			})
+
+			It("uses the fallback while the circuit is open", func() {
+				fallback := &mockClient{}
+				rt.CircuitBreakerFallback = fallback
+				for i := 0; i < 2; i++ {
+					_, err := rt.RoundTrip(req1)
+					Expect(err).To(MatchError("handshake error"))
+				}
+				rsp, err := rt.RoundTrip(req1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.Request).To(Equal(req1))
+				Expect(numDials).To(Equal(2))
+			})
+		})
 	})
 
 	Context("validating request", func() {
@@ -214,9 +528,362 @@ var _ = Describe("RoundTripper", func() {
 		})
 	})
 
+	Context("retrying requests", func() {
+		var (
+			cl             *mockResponder
+			serviceUnavail *http.Response
+		)
+
+		BeforeEach(func() {
+			cl = &mockResponder{}
+			rt.clients = map[string]roundTripDrainer{"www.example.org:443": cl}
+			serviceUnavail = &http.Response{
+				StatusCode: 503,
+				Header:     http.Header{"Retry-After": {"1"}},
+				Body:       &mockBody{},
+			}
+		})
+
+		It("retries after the duration given in the Retry-After header", func() {
+			rt.MaxRetries = 1
+			cl.responses = []*http.Response{serviceUnavail, {StatusCode: 200}}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(200))
+			Expect(cl.requests).To(HaveLen(2))
+			Expect(cl.requests[1].Sub(cl.requests[0])).To(BeNumerically(">=", time.Second))
+			Expect(serviceUnavail.Body.(*mockBody).closed).To(BeTrue())
+		})
+
+		It("parses Retry-After headers containing an HTTP-date", func() {
+			serviceUnavail.Header.Set("Retry-After", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+			delay, ok := retryAfter(serviceUnavail)
+			Expect(ok).To(BeTrue())
+			Expect(delay).To(BeZero())
+			serviceUnavail.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+			delay, ok = retryAfter(serviceUnavail)
+			Expect(ok).To(BeTrue())
+			Expect(delay).To(BeNumerically("~", time.Hour, time.Second))
+		})
+
+		It("surfaces the 503 when the retries are exhausted", func() {
+			rt.MaxRetries = 1
+			serviceUnavail.Header.Set("Retry-After", "0")
+			cl.responses = []*http.Response{serviceUnavail, serviceUnavail}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(503))
+			Expect(rsp.Header.Get("Retry-After")).To(Equal("0"))
+			Expect(cl.requests).To(HaveLen(2))
+		})
+
+		It("doesn't retry if MaxRetries is not set", func() {
+			cl.responses = []*http.Response{serviceUnavail}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(503))
+			Expect(cl.requests).To(HaveLen(1))
+		})
+
+		It("doesn't retry a 503 without a Retry-After header", func() {
+			rt.MaxRetries = 1
+			serviceUnavail.Header.Del("Retry-After")
+			cl.responses = []*http.Response{serviceUnavail}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(503))
+			Expect(cl.requests).To(HaveLen(1))
+		})
+
+		It("doesn't retry requests that are not replayable", func() {
+			rt.MaxRetries = 1
+			req, err := http.NewRequest("POST", "https://www.example.org/", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			cl.responses = []*http.Response{serviceUnavail}
+			rsp, err := rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(503))
+			Expect(cl.requests).To(HaveLen(1))
+		})
+
+		It("doesn't retry if the Retry-After exceeds the maximum delay", func() {
+			rt.MaxRetries = 1
+			serviceUnavail.Header.Set("Retry-After", strconv.Itoa(int((maxRetryAfter + time.Second).Seconds())))
+			cl.responses = []*http.Response{serviceUnavail}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(503))
+			Expect(cl.requests).To(HaveLen(1))
+			Expect(serviceUnavail.Body.(*mockBody).closed).To(BeFalse())
+		})
+
+		It("stops waiting when the request context is canceled", func() {
+			rt.MaxRetries = 1
+			serviceUnavail.Header.Set("Retry-After", "5")
+			cl.responses = []*http.Response{serviceUnavail}
+			ctx, cancel := context.WithCancel(context.Background())
+			errChan := make(chan error)
+			go func() {
+				_, err := rt.RoundTrip(req1.WithContext(ctx))
+				errChan <- err
+			}()
+			Consistently(errChan).ShouldNot(Receive())
+			cancel()
+			Eventually(errChan).Should(Receive(MatchError(context.Canceled)))
+		})
+	})
+
+	Context("retrying rejected requests", func() {
+		var cl *mockResponder
+		rejected := &StreamResetError{ErrorCode: quic.ErrorCode(errorRequestRejected)}
+
+		BeforeEach(func() {
+			cl = &mockResponder{}
+			rt.clients = map[string]roundTripDrainer{"www.example.org:443": cl}
+		})
+
+		It("retries using the client that is currently used for the host", func() {
+			second := &mockResponder{responses: []*http.Response{{StatusCode: 200}}}
+			cl.errs = []error{rejected}
+			// replace the client after the request was rejected, e.g. because the RoundTripper was drained
+			cl.onRequest = func() { rt.clients["www.example.org:443"] = second }
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(200))
+			Expect(cl.requests).To(HaveLen(1))
+			Expect(second.requests).To(HaveLen(1))
+		})
+
+		It("surfaces other stream reset errors", func() {
+			resetErr := &StreamResetError{ErrorCode: quic.ErrorCode(errorInternalError)}
+			cl.errs = []error{resetErr}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError(resetErr))
+			Expect(err.(*StreamResetError).Retryable()).To(BeFalse())
+			Expect(cl.requests).To(HaveLen(1))
+		})
+
+		It("doesn't retry requests that are not replayable", func() {
+			req, err := http.NewRequest("POST", "https://www.example.org/", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			cl.errs = []error{rejected}
+			_, err = rt.RoundTrip(req)
+			Expect(err).To(MatchError(rejected))
+			Expect(cl.requests).To(HaveLen(1))
+		})
+	})
+
+	Context("following redirects", func() {
+		var first, second *mockResponder
+
+		redirect := func(status int, location string) *http.Response {
+			return &http.Response{
+				StatusCode: status,
+				Header:     http.Header{"Location": {location}},
+				Body:       &mockBody{},
+			}
+		}
+
+		BeforeEach(func() {
+			first = &mockResponder{}
+			second = &mockResponder{}
+			rt.clients = map[string]roundTripDrainer{
+				"www.example.org:443":   first,
+				"other.example.org:443": second,
+			}
+		})
+
+		It("doesn't follow redirects if MaxRedirects is not set", func() {
+			first.responses = []*http.Response{redirect(301, "https://other.example.org/file2.html")}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(301))
+			Expect(second.requests).To(BeEmpty())
+		})
+
+		It("follows a redirect to another origin", func() {
+			rt.MaxRedirects = 5
+			rsp301 := redirect(301, "https://other.example.org/file2.html")
+			first.responses = []*http.Response{rsp301}
+			second.responses = []*http.Response{{StatusCode: 200}}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(200))
+			Expect(rsp.Request.URL.String()).To(Equal("https://other.example.org/file2.html"))
+			Expect(first.requests).To(HaveLen(1))
+			Expect(second.requests).To(HaveLen(1))
+			Expect(rsp301.Body.(*mockBody).closed).To(BeTrue())
+		})
+
+		It("follows redirects up to MaxRedirects", func() {
+			rt.MaxRedirects = 2
+			first.responses = []*http.Response{
+				redirect(301, "https://other.example.org/hop1"),
+				redirect(301, "https://other.example.org/hop3"),
+			}
+			second.responses = []*http.Response{
+				redirect(301, "https://www.example.org/hop2"),
+				{StatusCode: 200},
+			}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			// the third redirect is not followed
+			Expect(rsp.StatusCode).To(Equal(301))
+			Expect(rsp.Request.URL.Path).To(Equal("/hop2"))
+			Expect(first.requests).To(HaveLen(2))
+			Expect(second.requests).To(HaveLen(1))
+		})
+
+		It("resolves relative locations", func() {
+			rt.MaxRedirects = 1
+			first.responses = []*http.Response{redirect(302, "/file2.html"), {StatusCode: 200}}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(200))
+			Expect(rsp.Request.URL.String()).To(Equal("https://www.example.org/file2.html"))
+		})
+
+		It("doesn't follow redirects to plain HTTP", func() {
+			rt.MaxRedirects = 1
+			first.responses = []*http.Response{redirect(301, "http://other.example.org/file2.html")}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(301))
+		})
+
+		It("uses GET when following a 303", func() {
+			rt.MaxRedirects = 1
+			req, err := http.NewRequest("POST", "https://www.example.org/form", strings.NewReader("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", "text/plain")
+			first.responses = []*http.Response{redirect(303, "https://other.example.org/result")}
+			second.responses = []*http.Response{{StatusCode: 200}}
+			rsp, err := rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Request.Method).To(Equal("GET"))
+			Expect(rsp.Request.Body).To(BeNil())
+			Expect(rsp.Request.ContentLength).To(BeZero())
+			Expect(rsp.Request.Header).ToNot(HaveKey("Content-Type"))
+		})
+
+		It("resends the body when following a 307", func() {
+			rt.MaxRedirects = 1
+			req, err := http.NewRequest("POST", "https://www.example.org/form", strings.NewReader("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			first.responses = []*http.Response{redirect(307, "https://other.example.org/form")}
+			second.responses = []*http.Response{{StatusCode: 200}}
+			rsp, err := rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Request.Method).To(Equal("POST"))
+			body, err := ioutil.ReadAll(rsp.Request.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(body).To(Equal([]byte("foobar")))
+		})
+
+		It("doesn't follow a 307 if the body can't be resent", func() {
+			rt.MaxRedirects = 1
+			req, err := http.NewRequest("POST", "https://www.example.org/form", &mockBody{})
+			Expect(err).ToNot(HaveOccurred())
+			first.responses = []*http.Response{redirect(307, "https://other.example.org/form")}
+			rsp, err := rt.RoundTrip(req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(307))
+		})
+
+		It("doesn't send credentials to another host", func() {
+			rt.MaxRedirects = 1
+			req1.Header.Set("Authorization", "secret")
+			req1.Header.Set("Accept", "text/html")
+			first.responses = []*http.Response{redirect(301, "https://other.example.org/file2.html")}
+			second.responses = []*http.Response{{StatusCode: 200}}
+			rsp, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Request.Header).ToNot(HaveKey("Authorization"))
+			Expect(rsp.Request.Header.Get("Accept")).To(Equal("text/html"))
+			Expect(req1.Header.Get("Authorization")).To(Equal("secret"))
+		})
+
+		It("dials the alternative service that the new origin advertised", func() {
+			testErr := errors.New("dial error")
+			var dialedAddr, serverName string
+			rt.Dial = func(_, addr string, tlsConf *tls.Config, _ *quic.Config) (quic.Session, error) {
+				dialedAddr = addr
+				serverName = tlsConf.ServerName
+				return nil, testErr
+			}
+			rt.MaxRedirects = 1
+			delete(rt.clients, "other.example.org:443")
+			rt.getAltSvcCache().Update("other.example.org:443", `h3-19="alt.example.org:8443"; ma=60`)
+			first.responses = []*http.Response{redirect(301, "https://other.example.org/file2.html")}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError(testErr))
+			Expect(dialedAddr).To(Equal("alt.example.org:8443"))
+			Expect(serverName).To(Equal("other.example.org"))
+		})
+
+		It("remembers the alternative services advertised in responses", func() {
+			rt.MaxRedirects = 1
+			first.responses = []*http.Response{{
+				StatusCode: 200,
+				Header:     http.Header{"Alt-Svc": {`h3-19="alt.example.org:8443"`}},
+			}}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			alt, ok := rt.getAltSvcCache().Get("www.example.org:443")
+			Expect(ok).To(BeTrue())
+			Expect(alt).To(Equal("alt.example.org:8443"))
+		})
+
+		It("ignores alternative services if redirects are not followed", func() {
+			first.responses = []*http.Response{{
+				StatusCode: 200,
+				Header:     http.Header{"Alt-Svc": {`h3-19="alt.example.org:8443"`}},
+			}}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := rt.getAltSvcCache().Get("www.example.org:443")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("doesn't dial alternative services if redirects are not followed", func() {
+			var dialedAddr string
+			testErr := errors.New("dial failed")
+			rt.Dial = func(_, addr string, _ *tls.Config, _ *quic.Config) (quic.Session, error) {
+				dialedAddr = addr
+				return nil, testErr
+			}
+			delete(rt.clients, "other.example.org:443")
+			rt.getAltSvcCache().Update("other.example.org:443", `h3-19="alt.example.org:8443"; ma=60`)
+			req, err := http.NewRequest("GET", "https://other.example.org/file2.html", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rt.RoundTrip(req)
+			Expect(err).To(MatchError(testErr))
+			Expect(dialedAddr).To(Equal("other.example.org:443"))
+		})
+	})
+
+	Context("draining", func() {
+		It("drains the clients, and uses new clients for subsequent requests", func() {
+			cl := &mockClient{}
+			rt.clients = map[string]roundTripDrainer{"www.example.org:443": cl}
+			rt.Drain()
+			Expect(cl.drained).To(BeTrue())
+			Expect(cl.closed).To(BeFalse())
+			Expect(rt.clients).To(BeEmpty())
+			client, err := rt.getClient("www.example.org:443", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client).ToNot(Equal(cl))
+		})
+
+		It("drains a RoundTripper that has never been used", func() {
+			rt.Drain()
+			Expect(rt.clients).To(BeEmpty())
+		})
+	})
+
 	Context("closing", func() {
 		It("closes", func() {
-			rt.clients = make(map[string]roundTripCloser)
+			rt.clients = make(map[string]roundTripDrainer)
 			cl := &mockClient{}
 			rt.clients["foo.bar"] = cl
 			err := rt.Close()
@@ -1,6 +1,8 @@
 package http3
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -8,6 +10,7 @@ import (
 	"net"
 	"net/http"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -25,7 +28,34 @@ var (
 	quicListenAddr = quic.ListenAddr
 )
 
+// nextProtoH3 is the ALPN token used for HTTP/3
+const nextProtoH3 = "h3-19"
+
+// goAwayCloseDelay is the time that the server waits after the last request on a session
+// that was sent a GOAWAY frame completed, before closing the session.
+// This gives the response data time to be delivered to the client.
+var goAwayCloseDelay = 3 * time.Second
+
+// contextKey is a value for use with context.WithValue.
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string { return "quic-go/http3 context value " + k.name }
+
+// SessionContextKey is a context key. It can be used in HTTP handlers with
+// Context.Value to access the QUIC session that the request was received on.
+// The associated value will be of type quic.Session.
+// Details of the TLS handshake, like the SNI and the negotiated ALPN, are available in the request's TLS field.
+var SessionContextKey = &contextKey{"quic-session"}
+
 // Server is a HTTP2 server listening for QUIC connections.
+//
+// The ReadHeaderTimeout, ReadTimeout and WriteTimeout of the http.Server apply to every request stream:
+// ReadHeaderTimeout limits the time to read the request headers. If zero, ReadTimeout is used.
+// ReadTimeout limits the time to read the entire request, including the body.
+// WriteTimeout limits the time to write the response, starting when the request headers were read.
+// If a deadline is exceeded, the stream is reset with the HTTP_REQUEST_CANCELLED error code.
 type Server struct {
 	*http.Server
 
@@ -33,6 +63,39 @@ type Server struct {
 	// If nil, it uses reasonable default values.
 	QuicConfig *quic.Config
 
+	// StreamFilter, if set, is called for every new request stream, before the request is parsed.
+	// It can be used for logging, or to cheaply reject requests.
+	// If it returns false, the stream is reset with the HTTP_REQUEST_REJECTED error code,
+	// and the handler is not called.
+	// It is called synchronously when accepting streams, so it should not block.
+	StreamFilter func(sess quic.Session, id quic.StreamID) bool
+
+	// PathFilter, if set, is called with the :path of every request, before the request URL is parsed.
+	// It can be used to normalize the path, or to reject requests, e.g. to defend against path traversal.
+	// If it returns an error, both directions of the stream are reset with the HTTP_GENERAL_PROTOCOL_ERROR
+	// error code, and the handler is not called. Otherwise, the request is constructed from the returned path.
+	PathFilter func(path string) (string, error)
+
+	// SessionHandlers maps ALPN tokens to handlers for sessions that don't use HTTP/3.
+	// This allows serving HTTP/3 and other protocols on the same listener.
+	// Sessions that negotiated one of these protocols are passed to the corresponding handler,
+	// which is responsible for closing the session. All other sessions are served as HTTP/3.
+	// If the tls.Config doesn't set NextProtos, the server offers HTTP/3 and the protocols of these handlers.
+	SessionHandlers map[string]func(quic.Session)
+
+	// MaxEmptyFrames is the maximum number of frames without any body data
+	// (e.g. empty DATA frames, or unknown frames) that the client may send on a request stream.
+	// If it is exceeded, the session is closed with HTTP_EXCESSIVE_LOAD.
+	// If zero, a default of 100 is used. If negative, the number of frames is not limited.
+	MaxEmptyFrames int
+
+	// MaxRequestsPerConn is the maximum number of requests served on a single connection.
+	// When it is reached, the server sends a GOAWAY frame, and rejects all further requests on the connection
+	// with the HTTP_REQUEST_REJECTED error code. The connection is closed shortly after the outstanding requests have completed.
+	// This can be used to rotate connections, e.g. to rebalance load.
+	// If zero, the number of requests is not limited.
+	MaxRequestsPerConn int
+
 	port uint32 // used atomically
 
 	listenerMutex sync.Mutex
@@ -41,7 +104,8 @@ type Server struct {
 
 	supportedVersionsAsString string
 
-	logger utils.Logger
+	loggerOnce sync.Once
+	logger     utils.Logger
 }
 
 // ListenAndServe listens on the UDP address s.Addr and calls s.Handler to handle HTTP/3 requests on incoming connections.
@@ -77,7 +141,7 @@ func (s *Server) serveImpl(tlsConfig *tls.Config, conn net.PacketConn) error {
 	if s.Server == nil {
 		return errors.New("use of http3.Server without http.Server")
 	}
-	s.logger = utils.DefaultLogger.WithPrefix("server")
+	s.initLogger()
 	s.listenerMutex.Lock()
 	if s.closed {
 		s.listenerMutex.Unlock()
@@ -90,6 +154,7 @@ func (s *Server) serveImpl(tlsConfig *tls.Config, conn net.PacketConn) error {
 
 	var ln quic.Listener
 	var err error
+	tlsConfig = s.addNextProtos(tlsConfig)
 	if conn == nil {
 		ln, err = quicListenAddr(s.Addr, tlsConfig, s.QuicConfig)
 	} else {
@@ -107,37 +172,174 @@ func (s *Server) serveImpl(tlsConfig *tls.Config, conn net.PacketConn) error {
 		if err != nil {
 			return err
 		}
-		go s.handleConn(sess)
+		go s.handleSession(sess)
+	}
+}
+
+// ServeQUICConn serves HTTP/3 requests on a QUIC session that was already established,
+// e.g. one accepted from a custom quic.Listener.
+// The session is served as HTTP/3 regardless of the negotiated ALPN, the SessionHandlers are not used.
+// It blocks until accepting a new stream on the session fails, and returns that error.
+func (s *Server) ServeQUICConn(sess quic.Session) error {
+	if s.Server == nil {
+		return errors.New("use of http3.Server without http.Server")
+	}
+	s.initLogger()
+	return s.handleConn(sess)
+}
+
+func (s *Server) initLogger() {
+	s.loggerOnce.Do(func() {
+		if s.logger == nil {
+			s.logger = utils.DefaultLogger.WithPrefix("server")
+		}
+	})
+}
+
+// addNextProtos returns a copy of the tls.Config that offers HTTP/3 and the protocols of the SessionHandlers via ALPN.
+func (s *Server) addNextProtos(tlsConfig *tls.Config) *tls.Config {
+	if len(s.SessionHandlers) == 0 || tlsConfig == nil || len(tlsConfig.NextProtos) > 0 {
+		return tlsConfig
+	}
+	protos := make([]string, 0, len(s.SessionHandlers))
+	for proto := range s.SessionHandlers {
+		protos = append(protos, proto)
+	}
+	sort.Strings(protos)
+	conf := tlsConfig.Clone()
+	conf.NextProtos = append([]string{nextProtoH3}, protos...)
+	return conf
+}
+
+// handleSession passes the session to the handler for the negotiated ALPN protocol.
+func (s *Server) handleSession(sess quic.Session) {
+	if proto := sess.ConnectionState().NegotiatedProtocol; proto != "" {
+		if handler, ok := s.SessionHandlers[proto]; ok {
+			s.logger.Debugf("Passing session to the handler for %s", proto)
+			handler(sess)
+			return
+		}
 	}
+	s.handleConn(sess)
 }
 
-func (s *Server) handleConn(sess quic.Session) {
+func (s *Server) handleConn(sess quic.Session) error {
 	// TODO: accept control streams
 	decoder := qpack.NewDecoder(nil)
 
+	var numRequests int
+	var sentGoAway bool
+	var requests sync.WaitGroup
 	for {
-		str, err := sess.AcceptStream()
+		str, err := sess.AcceptStream(context.Background())
 		if err != nil {
 			s.logger.Debugf("Accepting stream failed: %s", err)
-			return
+			return err
+		}
+		if sentGoAway {
+			s.logger.Debugf("Rejecting stream %d, since the GOAWAY frame was already sent", str.StreamID())
+			str.CancelWrite(quic.ErrorCode(errorRequestRejected))
+			str.CancelRead(quic.ErrorCode(errorRequestRejected))
+			continue
+		}
+		if s.StreamFilter != nil && !s.StreamFilter(sess, str.StreamID()) {
+			s.logger.Debugf("Rejecting stream %d", str.StreamID())
+			str.CancelWrite(quic.ErrorCode(errorRequestRejected))
+			str.CancelRead(quic.ErrorCode(errorRequestRejected))
+			continue
+		}
+		numRequests++
+		requests.Add(1)
+		if s.MaxRequestsPerConn > 0 && numRequests >= s.MaxRequestsPerConn {
+			// requests on this stream and all streams with lower stream IDs are processed
+			s.logger.Debugf("Accepted %d requests on this session. Sending a GOAWAY frame.", numRequests)
+			s.sendGoAway(sess, str.StreamID()+4)
+			sentGoAway = true
+			go func() {
+				requests.Wait()
+				time.Sleep(goAwayCloseDelay)
+				sess.Close()
+			}()
 		}
 		// TODO: handle error
 		go func() {
-			if err := s.handleRequest(str, decoder); err != nil {
+			defer requests.Done()
+			err := s.handleRequest(sess, str, decoder)
+			if err == errStreamReset {
+				return
+			}
+			if err != nil {
 				s.logger.Debugf("Handling request failed: %s", err)
 				str.CancelWrite(quic.ErrorCode(errorGeneralProtocolError))
-				return
 			}
-			str.Close()
 		}()
 	}
 }
 
+// sendGoAway opens a control stream and sends a GOAWAY frame on it.
+// Requests on the stream with the given ID and all higher stream IDs won't be processed.
+func (s *Server) sendGoAway(sess quic.Session, id quic.StreamID) {
+	str, err := sess.OpenUniStream()
+	if err != nil {
+		s.logger.Debugf("Opening the control stream failed: %s", err)
+		return
+	}
+	buf := &bytes.Buffer{}
+	// write the type byte
+	buf.WriteByte(0x0)
+	(&settingsFrame{}).Write(buf)
+	(&goAwayFrame{ID: uint64(id)}).Write(buf)
+	if _, err := str.Write(buf.Bytes()); err != nil {
+		s.logger.Debugf("Error sending GOAWAY: %s", err)
+	}
+}
+
+// filterPath calls the PathFilter with the :path of the request, and replaces it with the path returned.
+func (s *Server) filterPath(hfs []qpack.HeaderField) error {
+	for i, hf := range hfs {
+		if hf.Name != ":path" {
+			continue
+		}
+		path, err := s.PathFilter(hf.Value)
+		if err != nil {
+			return fmt.Errorf("rejected :path %q: %s", hf.Value, err)
+		}
+		hfs[i].Value = path
+	}
+	return nil
+}
+
+// errStreamReset is returned by handleRequest when the request stream has already been reset,
+// e.g. because the handler called RefuseRequest.
+var errStreamReset = errors.New("request stream reset")
+
+// handleRequest handles a request, and closes the stream after the response was written.
 // TODO: improve error handling.
 // Most (but not all) of the errors occurring here are connection-level erros.
-func (s *Server) handleRequest(str quic.Stream, decoder *qpack.Decoder) error {
-	frame, err := parseNextFrame(str)
+func (s *Server) handleRequest(sess quic.Session, qstr quic.Stream, decoder *qpack.Decoder) error {
+	str := &deadlineStream{Stream: qstr}
+	start := time.Now()
+	readHeaderTimeout := s.ReadHeaderTimeout
+	if readHeaderTimeout == 0 {
+		readHeaderTimeout = s.ReadTimeout
+	}
+	if readHeaderTimeout > 0 {
+		str.SetReadDeadline(start.Add(readHeaderTimeout))
+	}
+
+	emptyFrames := newFrameCounter(s.MaxEmptyFrames)
+	closeWithExcessiveLoad := func() {
+		s.logger.Debugf("Client sent too many frames without body data on stream %d. Closing the session.", str.StreamID())
+		sess.CloseWithError(quic.ErrorCode(errorExcessiveLoad), errTooManyEmptyFrames)
+	}
+	frame, err := parseNextFrameWithCounter(str, emptyFrames)
 	if err != nil {
+		if err == errTooManyEmptyFrames {
+			closeWithExcessiveLoad()
+		}
+		if str.timedOut() {
+			return s.resetTimedOutStream(str)
+		}
 		str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
 		return err
 	}
@@ -149,6 +351,9 @@ func (s *Server) handleRequest(str quic.Stream, decoder *qpack.Decoder) error {
 	// TODO: check length
 	headerBlock := make([]byte, hf.Length)
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
+		if str.timedOut() {
+			return s.resetTimedOutStream(str)
+		}
 		str.CancelWrite(quic.ErrorCode(errorIncompleteRequest))
 		return err
 	}
@@ -158,11 +363,32 @@ func (s *Server) handleRequest(str quic.Stream, decoder *qpack.Decoder) error {
 		str.CancelWrite(quic.ErrorCode(errorGeneralProtocolError))
 		return err
 	}
+	if s.PathFilter != nil {
+		if err := s.filterPath(hfs); err != nil {
+			s.logger.Debugf("Rejecting request: %s", err)
+			str.CancelWrite(quic.ErrorCode(errorGeneralProtocolError))
+			str.CancelRead(quic.ErrorCode(errorGeneralProtocolError))
+			return errStreamReset
+		}
+	}
 	req, err := requestFromHeaders(hfs)
 	if err != nil {
 		return err
 	}
-	req.Body = newRequestBody(str)
+	req.Body = newRequestBody(str, emptyFrames, closeWithExcessiveLoad)
+	connState := sess.ConnectionState()
+	req.TLS = &connState
+
+	if s.ReadHeaderTimeout > 0 {
+		var deadline time.Time
+		if s.ReadTimeout > 0 {
+			deadline = start.Add(s.ReadTimeout)
+		}
+		str.SetReadDeadline(deadline)
+	}
+	if s.WriteTimeout > 0 {
+		str.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+	}
 
 	if s.logger.Debug() {
 		s.logger.Infof("%s %s%s, on stream %d", req.Method, req.Host, req.RequestURI, str.StreamID())
@@ -170,8 +396,9 @@ func (s *Server) handleRequest(str quic.Stream, decoder *qpack.Decoder) error {
 		s.logger.Infof("%s %s%s", req.Method, req.Host, req.RequestURI)
 	}
 
-	req = req.WithContext(str.Context())
+	req = req.WithContext(context.WithValue(str.Context(), SessionContextKey, sess))
 	responseWriter := newResponseWriter(str, s.logger)
+	responseWriter.isHead = req.Method == http.MethodHead
 	handler := s.Handler
 	if handler == nil {
 		handler = http.DefaultServeMux
@@ -196,18 +423,80 @@ func (s *Server) handleRequest(str quic.Stream, decoder *qpack.Decoder) error {
 		}
 	}()
 
+	if responseWriter.rejected {
+		str.CancelWrite(quic.ErrorCode(errorRequestRejected))
+		str.CancelRead(quic.ErrorCode(errorRequestRejected))
+		return errStreamReset
+	}
+
+	if str.timedOut() {
+		return s.resetTimedOutStream(str)
+	}
+
 	if panicked {
 		responseWriter.WriteHeader(500)
 	} else {
 		responseWriter.WriteHeader(200)
 	}
+	if !panicked && responseWriter.wroteShortBody() {
+		// Don't let the client mistake a truncated body for a complete response.
+		s.logger.Errorf("http3: handler wrote %d bytes, but declared a Content-Length of %d", responseWriter.numWritten, responseWriter.contentLength)
+		str.CancelWrite(quic.ErrorCode(errorInternalError))
+		str.CancelRead(quic.ErrorCode(errorInternalError))
+		return errStreamReset
+	}
 
 	if !readEOF {
 		str.CancelRead(quic.ErrorCode(errorEarlyResponse))
 	}
+	// If the complete body was written, the response writer already closed the stream.
+	if !responseWriter.finished {
+		str.Close()
+	}
 	return nil
 }
 
+// resetTimedOutStream resets a request stream on which a read or write deadline was exceeded.
+func (s *Server) resetTimedOutStream(str quic.Stream) error {
+	s.logger.Debugf("Timeout on stream %d. Resetting the stream.", str.StreamID())
+	str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+	str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+	return errStreamReset
+}
+
+// A deadlineStream records if a Read or Write on the stream failed because a deadline was exceeded.
+type deadlineStream struct {
+	quic.Stream
+
+	readTimedOut  utils.AtomicBool
+	writeTimedOut utils.AtomicBool
+}
+
+func (s *deadlineStream) Read(b []byte) (int, error) {
+	n, err := s.Stream.Read(b)
+	if isTimeout(err) {
+		s.readTimedOut.Set(true)
+	}
+	return n, err
+}
+
+func (s *deadlineStream) Write(b []byte) (int, error) {
+	n, err := s.Stream.Write(b)
+	if isTimeout(err) {
+		s.writeTimedOut.Set(true)
+	}
+	return n, err
+}
+
+func (s *deadlineStream) timedOut() bool {
+	return s.readTimedOut.Get() || s.writeTimedOut.Get()
+}
+
+func isTimeout(err error) bool {
+	nerr, ok := err.(net.Error)
+	return ok && nerr.Timeout()
+}
+
 // Close the server immediately, aborting requests and sending CONNECTION_CLOSE frames to connected clients.
 // Close in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) Close() error {
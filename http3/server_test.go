@@ -7,7 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"path"
 	"strings"
 	"time"
 
@@ -23,6 +25,12 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "deadline exceeded" }
+func (timeoutError) Temporary() bool { return true }
+func (timeoutError) Timeout() bool   { return true }
+
 var _ = Describe("Server", func() {
 	var (
 		s *Server
@@ -44,10 +52,239 @@ var _ = Describe("Server", func() {
 		quicListenAddr = origQuicListenAddr
 	})
 
+	Context("filtering streams", func() {
+		var (
+			sess *mockquic.MockSession
+			str  *mockquic.MockStream
+		)
+
+		BeforeEach(func() {
+			sess = mockquic.NewMockSession(mockCtrl)
+			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+			gomock.InOrder(
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil),
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done")),
+			)
+		})
+
+		It("resets streams rejected by the filter, without calling the handler", func() {
+			s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				Fail("handler should not be called")
+			})
+			var filteredSess quic.Session
+			var filteredID quic.StreamID
+			s.StreamFilter = func(sess quic.Session, id quic.StreamID) bool {
+				filteredSess = sess
+				filteredID = id
+				return false
+			}
+			// don't EXPECT any calls to str.Read()
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestRejected))
+			str.EXPECT().CancelRead(quic.ErrorCode(errorRequestRejected))
+			s.handleConn(sess)
+			Expect(filteredSess).To(Equal(sess))
+			Expect(filteredID).To(Equal(quic.StreamID(4)))
+		})
+
+		It("handles streams accepted by the filter", func() {
+			s.StreamFilter = func(quic.Session, quic.StreamID) bool { return true }
+			read := make(chan struct{})
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+				close(read)
+				return 0, errors.New("read error")
+			})
+			str.EXPECT().CancelWrite(gomock.Any()).AnyTimes()
+			s.handleConn(sess)
+			Eventually(read).Should(BeClosed())
+		})
+	})
+
+	Context("limiting the number of requests per connection", func() {
+		var (
+			sess             *mockquic.MockSession
+			controlStr       *mockquic.MockStream
+			controlStrData   *bytes.Buffer
+			origCloseDelay   time.Duration
+			streams          []*mockquic.MockStream
+			acceptedRequests chan quic.StreamID
+		)
+
+		newStream := func(id quic.StreamID) *mockquic.MockStream {
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().Return(id).AnyTimes()
+			return str
+		}
+
+		// expectRequest makes the stream fail when the request is read, and reports that it was read
+		expectRequest := func(str *mockquic.MockStream) {
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+				acceptedRequests <- str.StreamID()
+				return 0, errors.New("read error")
+			})
+			str.EXPECT().CancelWrite(gomock.Any()).AnyTimes()
+		}
+
+		BeforeEach(func() {
+			origCloseDelay = goAwayCloseDelay
+			goAwayCloseDelay = 10 * time.Millisecond
+			sess = mockquic.NewMockSession(mockCtrl)
+			controlStrData = &bytes.Buffer{}
+			controlStr = mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(controlStrData.Write).AnyTimes()
+			streams = []*mockquic.MockStream{newStream(0), newStream(4), newStream(8), newStream(12)}
+			var calls []*gomock.Call
+			for _, str := range streams {
+				calls = append(calls, sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil))
+			}
+			calls = append(calls, sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done")))
+			gomock.InOrder(calls...)
+			acceptedRequests = make(chan quic.StreamID, len(streams))
+		})
+
+		AfterEach(func() {
+			goAwayCloseDelay = origCloseDelay
+		})
+
+		It("sends a GOAWAY frame, rejects further requests and closes the session", func() {
+			s.MaxRequestsPerConn = 2
+			expectRequest(streams[0])
+			expectRequest(streams[1])
+			for _, str := range streams[2:] {
+				// don't EXPECT any calls to str.Read()
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestRejected))
+				str.EXPECT().CancelRead(quic.ErrorCode(errorRequestRejected))
+			}
+			sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+			closed := make(chan struct{})
+			sess.EXPECT().Close().Do(func() { close(closed) })
+			s.handleConn(sess)
+			Eventually(closed).Should(BeClosed())
+			Expect(acceptedRequests).To(HaveLen(2))
+			close(acceptedRequests)
+			var ids []quic.StreamID
+			for id := range acceptedRequests {
+				ids = append(ids, id)
+			}
+			Expect(ids).To(ConsistOf(quic.StreamID(0), quic.StreamID(4)))
+
+			// check the control stream
+			Expect(controlStrData.Bytes()[0]).To(BeZero()) // the type byte of the control stream
+			controlStrData.ReadByte()
+			frame, err := parseNextFrame(controlStrData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&settingsFrame{}))
+			frame, err = parseNextFrame(controlStrData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{ID: 8}))
+		})
+
+		It("doesn't count streams rejected by the StreamFilter", func() {
+			s.MaxRequestsPerConn = 2
+			s.StreamFilter = func(_ quic.Session, id quic.StreamID) bool { return id != 4 }
+			expectRequest(streams[0])
+			streams[1].EXPECT().CancelWrite(quic.ErrorCode(errorRequestRejected))
+			streams[1].EXPECT().CancelRead(quic.ErrorCode(errorRequestRejected))
+			expectRequest(streams[2])
+			streams[3].EXPECT().CancelWrite(quic.ErrorCode(errorRequestRejected))
+			streams[3].EXPECT().CancelRead(quic.ErrorCode(errorRequestRejected))
+			sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+			closed := make(chan struct{})
+			sess.EXPECT().Close().Do(func() { close(closed) })
+			s.handleConn(sess)
+			Eventually(closed).Should(BeClosed())
+			Expect(acceptedRequests).To(HaveLen(2))
+			controlStrData.ReadByte()
+			_, err := parseNextFrame(controlStrData) // the SETTINGS frame
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parseNextFrame(controlStrData)).To(Equal(&goAwayFrame{ID: 12}))
+		})
+
+		It("doesn't limit the number of requests by default", func() {
+			for _, str := range streams {
+				expectRequest(str)
+			}
+			// don't EXPECT any calls to sess.OpenUniStream() or sess.Close()
+			s.handleConn(sess)
+			Eventually(acceptedRequests).Should(HaveLen(len(streams)))
+		})
+	})
+
+	Context("dispatching sessions by ALPN", func() {
+		var sess *mockquic.MockSession
+
+		BeforeEach(func() {
+			sess = mockquic.NewMockSession(mockCtrl)
+		})
+
+		It("passes sessions to the handler for the negotiated protocol", func() {
+			s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				Fail("HTTP handler should not be called")
+			})
+			var handledSess quic.Session
+			s.SessionHandlers = map[string]func(quic.Session){
+				"custom": func(sess quic.Session) { handledSess = sess },
+				"other":  func(quic.Session) { Fail("wrong handler called") },
+			}
+			// don't EXPECT any calls to AcceptStream
+			sess.EXPECT().ConnectionState().Return(tls.ConnectionState{NegotiatedProtocol: "custom"})
+			s.handleSession(sess)
+			Expect(handledSess).To(Equal(sess))
+		})
+
+		It("serves HTTP/3 for sessions that negotiated h3", func() {
+			s.SessionHandlers = map[string]func(quic.Session){
+				"custom": func(quic.Session) { Fail("custom handler should not be called") },
+			}
+			sess.EXPECT().ConnectionState().Return(tls.ConnectionState{NegotiatedProtocol: nextProtoH3})
+			sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+			s.handleSession(sess)
+		})
+
+		It("serves HTTP/3 for sessions that didn't negotiate a protocol", func() {
+			s.SessionHandlers = map[string]func(quic.Session){
+				"custom": func(quic.Session) { Fail("custom handler should not be called") },
+			}
+			sess.EXPECT().ConnectionState().Return(tls.ConnectionState{})
+			sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+			s.handleSession(sess)
+		})
+
+		It("offers HTTP/3 and the protocols of the session handlers", func() {
+			s.SessionHandlers = map[string]func(quic.Session){
+				"foo": func(quic.Session) {},
+				"bar": func(quic.Session) {},
+			}
+			var receivedConf *tls.Config
+			quicListenAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.Listener, error) {
+				receivedConf = tlsConf
+				return nil, errors.New("listen err")
+			}
+			Expect(s.ListenAndServe()).To(HaveOccurred())
+			Expect(receivedConf.NextProtos).To(Equal([]string{nextProtoH3, "bar", "foo"}))
+			// the tls.Config of the server is not modified
+			Expect(s.TLSConfig.NextProtos).To(BeEmpty())
+		})
+
+		It("doesn't change the NextProtos set in the tls.Config", func() {
+			s.SessionHandlers = map[string]func(quic.Session){"foo": func(quic.Session) {}}
+			s.TLSConfig.NextProtos = []string{"foo", "h3-19"}
+			var receivedConf *tls.Config
+			quicListenAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.Listener, error) {
+				receivedConf = tlsConf
+				return nil, errors.New("listen err")
+			}
+			Expect(s.ListenAndServe()).To(HaveOccurred())
+			Expect(receivedConf.NextProtos).To(Equal([]string{"foo", "h3-19"}))
+		})
+	})
+
 	Context("handling requests", func() {
 		var (
 			qpackDecoder       *qpack.Decoder
+			sess               *mockquic.MockSession
 			str                *mockquic.MockStream
+			connState          tls.ConnectionState
 			exampleGetRequest  *http.Request
 			examplePostRequest *http.Request
 		)
@@ -81,7 +318,7 @@ var _ = Describe("Server", func() {
 			closed := make(chan struct{})
 			str.EXPECT().Close().Do(func() { close(closed) })
 			rw := newRequestWriter(utils.DefaultLogger)
-			Expect(rw.WriteRequest(str, req)).To(Succeed())
+			Expect(rw.WriteRequest(str, req, false, false)).To(Succeed())
 			if req.Body != nil {
 				b := make([]byte, 1000)
 				n, err := io.ReadFull(req.Body, b)
@@ -111,6 +348,9 @@ var _ = Describe("Server", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			qpackDecoder = qpack.NewDecoder(nil)
+			sess = mockquic.NewMockSession(mockCtrl)
+			connState = tls.ConnectionState{}
+			sess.EXPECT().ConnectionState().DoAndReturn(func() tls.ConnectionState { return connState }).AnyTimes()
 			str = mockquic.NewMockStream(mockCtrl)
 		})
 
@@ -126,10 +366,219 @@ var _ = Describe("Server", func() {
 				return len(p), nil
 			}).AnyTimes()
 
-			Expect(s.handleRequest(str, qpackDecoder)).To(Succeed())
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
+			var req *http.Request
+			Eventually(requestChan).Should(Receive(&req))
+			Expect(req.Host).To(Equal("www.example.com"))
+		})
+
+		It("serves a session that was already established", func() {
+			s.logger = nil
+			requestChan := make(chan *http.Request, 1)
+			s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				requestChan <- r
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			gomock.InOrder(
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil),
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done")),
+			)
+			str.EXPECT().StreamID().AnyTimes()
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			closed := make(chan struct{})
+			str.EXPECT().Close().Do(func() { close(closed) })
+
+			Expect(s.ServeQUICConn(sess)).To(MatchError("done"))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("errors when serving a session without http.Server", func() {
+			s.Server = nil
+			Expect(s.ServeQUICConn(sess)).To(MatchError("use of http3.Server without http.Server"))
+		})
+
+		Context("filtering paths", func() {
+			It("passes the rewritten path to the handler", func() {
+				requestChan := make(chan *http.Request, 1)
+				s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					requestChan <- r
+				})
+				var filteredPath string
+				s.PathFilter = func(p string) (string, error) {
+					filteredPath = p
+					return path.Clean(p), nil
+				}
+
+				req, err := http.NewRequest("GET", "https://www.example.com/foo/../bar?baz", nil)
+				Expect(err).ToNot(HaveOccurred())
+				setRequest(encodeRequest(req))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+
+				str.EXPECT().Close()
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
+				Expect(filteredPath).To(Equal("/foo/../bar?baz"))
+				var r *http.Request
+				Eventually(requestChan).Should(Receive(&r))
+				Expect(r.URL.Path).To(Equal("/bar"))
+				Expect(r.URL.RawQuery).To(Equal("baz"))
+				Expect(r.RequestURI).To(Equal("/bar?baz"))
+			})
+
+			It("resets the stream if the filter rejects the path, without calling the handler", func() {
+				s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					Fail("handler should not be called")
+				})
+				s.PathFilter = func(p string) (string, error) {
+					if strings.Contains(p, "..") {
+						return "", errors.New("path traversal")
+					}
+					return p, nil
+				}
+
+				req, err := http.NewRequest("GET", "https://www.example.com/foo/../../etc/passwd", nil)
+				Expect(err).ToNot(HaveOccurred())
+				setRequest(encodeRequest(req))
+				gomock.InOrder(
+					sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil),
+					sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done")),
+				)
+				reset := make(chan struct{})
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorGeneralProtocolError))
+				str.EXPECT().CancelRead(quic.ErrorCode(errorGeneralProtocolError)).Do(func(quic.ErrorCode) { close(reset) })
+				s.handleConn(sess)
+				Eventually(reset).Should(BeClosed())
+			})
+
+			It("resets both directions of the stream if the filter rejects the path", func() {
+				s.PathFilter = func(string) (string, error) { return "", errors.New("path traversal") }
+
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorGeneralProtocolError))
+				str.EXPECT().CancelRead(quic.ErrorCode(errorGeneralProtocolError))
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(MatchError(errStreamReset))
+			})
+
+			It("rejects paths that contain invalid characters", func() {
+				s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					Fail("handler should not be called")
+				})
+				s.PathFilter = func(string) (string, error) { return "/foo bar", nil }
+
+				setRequest(encodeRequest(exampleGetRequest))
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(MatchError(`invalid :path "/foo bar"`))
+			})
+		})
+
+		It("streams the response, and closes the stream when the handler returns", func() {
+			proceed := make(chan struct{})
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte("foo"))
+				w.(http.Flusher).Flush()
+				<-proceed
+				w.Write([]byte("bar"))
+				w.(http.Flusher).Flush()
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			gomock.InOrder(
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil),
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done")),
+			)
+			str.EXPECT().StreamID().AnyTimes()
+			str.EXPECT().Context().Return(reqContext)
+			written := make(chan []byte, 100)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				written <- append([]byte{}, p...)
+				return len(p), nil
+			}).AnyTimes()
+			// the handler flushes after every write
+			str.EXPECT().Flush().Times(2)
+			closed := make(chan struct{})
+			str.EXPECT().Close().Do(func() { close(closed) })
+			s.handleConn(sess)
+
+			responseBuf := &bytes.Buffer{}
+			readResponse := func() []byte {
+				for {
+					select {
+					case b := <-written:
+						responseBuf.Write(b)
+					default:
+						return responseBuf.Bytes()
+					}
+				}
+			}
+			// the first DATA frame is sent before the handler returns
+			Eventually(readResponse).Should(HaveSuffix("foo"))
+			Consistently(closed).ShouldNot(BeClosed())
+			close(proceed)
+			Eventually(closed).Should(BeClosed())
+			readResponse()
+			hfs := decodeHeader(responseBuf)
+			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
+			Expect(hfs).ToNot(HaveKey("content-length"))
+			for _, data := range []string{"foo", "bar"} {
+				frame, err := parseNextFrame(responseBuf)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&dataFrame{Length: 3}))
+				Expect(responseBuf.Next(3)).To(Equal([]byte(data)))
+			}
+			Expect(responseBuf.Len()).To(BeZero())
+		})
+
+		It("closes the stream before the handler returns, once the Content-Length was written", func() {
+			handlerReturned := make(chan struct{})
+			proceed := make(chan struct{})
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer close(handlerReturned)
+				w.Header().Set("Content-Length", "6")
+				w.Write([]byte("foobar"))
+				<-proceed
+				// writes after the complete body was written are ignored
+				w.Write([]byte{})
+				w.(http.Flusher).Flush()
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			gomock.InOrder(
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil),
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done")),
+			)
+			str.EXPECT().StreamID().AnyTimes()
+			str.EXPECT().Context().Return(reqContext)
+			responseBuf := &bytes.Buffer{}
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return responseBuf.Write(p)
+			}).AnyTimes()
+			closed := make(chan struct{})
+			// the stream is only closed once
+			str.EXPECT().Close().Do(func() { close(closed) })
+			s.handleConn(sess)
+
+			Eventually(closed).Should(BeClosed())
+			Expect(handlerReturned).ToNot(BeClosed())
+			close(proceed)
+			Eventually(handlerReturned).Should(BeClosed())
+			// make sure handleRequest doesn't close the stream a second time
+			time.Sleep(20 * time.Millisecond)
+			hfs := decodeHeader(responseBuf)
+			Expect(hfs).To(HaveKeyWithValue("content-length", []string{"6"}))
+			frame, err := parseNextFrame(responseBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&dataFrame{Length: 6}))
+			Expect(responseBuf.Bytes()).To(Equal([]byte("foobar")))
 		})
 
 		It("returns 200 with an empty handler", func() {
@@ -142,7 +591,8 @@ var _ = Describe("Server", func() {
 				return responseBuf.Write(p)
 			}).AnyTimes()
 
-			Expect(s.handleRequest(str, qpackDecoder)).To(Succeed())
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
 		})
@@ -160,11 +610,112 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			Expect(s.handleRequest(str, qpackDecoder)).To(Succeed())
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
 		})
 
+		It("exposes the TLS connection state and the session to the handler", func() {
+			connState = tls.ConnectionState{
+				ServerName:         "tenant.example.com",
+				NegotiatedProtocol: nextProtoH3,
+			}
+			var tlsState *tls.ConnectionState
+			var reqSess quic.Session
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tlsState = r.TLS
+				reqSess, _ = r.Context().Value(SessionContextKey).(quic.Session)
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
+			Expect(tlsState).ToNot(BeNil())
+			Expect(tlsState.ServerName).To(Equal("tenant.example.com"))
+			Expect(tlsState.NegotiatedProtocol).To(Equal(nextProtoH3))
+			Expect(reqSess).To(Equal(sess))
+			Expect(reqSess.ConnectionState().ServerName).To(Equal("tenant.example.com"))
+		})
+
+		It("resets the stream when the handler refuses the request", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(RefuseRequest(w)).To(Succeed())
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestRejected))
+			str.EXPECT().CancelRead(quic.ErrorCode(errorRequestRejected))
+
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(MatchError(errStreamReset))
+		})
+
+		It("doesn't send DATA frames in response to a HEAD request", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "6")
+				w.Write([]byte("foobar"))
+			})
+
+			headRequest, err := http.NewRequest(http.MethodHead, "https://www.example.com", nil)
+			Expect(err).ToNot(HaveOccurred())
+			responseBuf := &bytes.Buffer{}
+			setRequest(encodeRequest(headRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return responseBuf.Write(p)
+			}).AnyTimes()
+
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
+			hfs := decodeHeader(responseBuf)
+			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
+			Expect(hfs).To(HaveKeyWithValue("content-length", []string{"6"}))
+			Expect(responseBuf.Len()).To(BeZero())
+		})
+
+		It("doesn't send DATA frames for a 204 response", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+				w.Write([]byte("foobar"))
+			})
+
+			responseBuf := &bytes.Buffer{}
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return responseBuf.Write(p)
+			}).AnyTimes()
+
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
+			hfs := decodeHeader(responseBuf)
+			Expect(hfs).To(HaveKeyWithValue(":status", []string{"204"}))
+			Expect(responseBuf.Len()).To(BeZero())
+		})
+
+		It("resets the stream when the handler writes less than the Content-Length", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "10")
+				w.Write([]byte("foobar"))
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorInternalError))
+			str.EXPECT().CancelRead(quic.ErrorCode(errorInternalError))
+
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(MatchError(errStreamReset))
+		})
+
 		It("cancels reading when client sends a body in GET request", func() {
 			handlerCalled := make(chan struct{})
 			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -183,7 +734,8 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			Expect(s.handleRequest(str, qpackDecoder)).To(Succeed())
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
 		})
@@ -203,7 +755,8 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			Expect(s.handleRequest(str, qpackDecoder)).To(Succeed())
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
 
@@ -217,7 +770,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Read(gomock.Any()).Return(0, testErr)
 			str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
 
-			Expect(s.handleRequest(str, qpackDecoder)).To(MatchError(testErr))
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(MatchError(testErr))
 			Consistently(handlerCalled).ShouldNot(BeClosed())
 		})
 
@@ -238,7 +791,8 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			Expect(s.handleRequest(str, qpackDecoder)).To(Succeed())
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
 
@@ -260,9 +814,195 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			Expect(s.handleRequest(str, qpackDecoder)).To(Succeed())
+			str.EXPECT().Close()
+			Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
+
+		Context("limiting frames without body data", func() {
+			BeforeEach(func() {
+				str.EXPECT().StreamID().Return(protocol.StreamID(4)).AnyTimes()
+			})
+
+			It("closes the session when the client floods the request body with empty DATA frames", func() {
+				s.MaxEmptyFrames = 10
+				readErr := make(chan error, 1)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, err := ioutil.ReadAll(r.Body)
+					readErr <- err
+				})
+
+				buf := bytes.NewBuffer(encodeRequest(exampleGetRequest))
+				for i := 0; i < 11; i++ {
+					(&dataFrame{}).Write(buf)
+				}
+				setRequest(buf.Bytes())
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorExcessiveLoad), errTooManyEmptyFrames)
+
+				str.EXPECT().Close()
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
+				Expect(readErr).To(Receive(MatchError(errTooManyEmptyFrames)))
+			})
+
+			It("closes the session when the client sends too many unknown frames before the HEADERS frame", func() {
+				s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					Fail("handler should not be called")
+				})
+				buf := &bytes.Buffer{}
+				for i := 0; i < defaultMaxEmptyFrames+1; i++ {
+					buf.Write([]byte{0x21, 0}) // a reserved frame type with an empty payload
+				}
+				buf.Write(encodeRequest(exampleGetRequest))
+				setRequest(buf.Bytes())
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorExcessiveLoad), errTooManyEmptyFrames)
+
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(MatchError(errTooManyEmptyFrames))
+			})
+
+			It("accepts a request preceded by a few unknown frames", func() {
+				handlerCalled := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					close(handlerCalled)
+				})
+				buf := &bytes.Buffer{}
+				for i := 0; i < 3; i++ {
+					buf.Write([]byte{0x21, 0}) // a reserved frame type with an empty payload
+				}
+				buf.Write(encodeRequest(exampleGetRequest))
+				setRequest(buf.Bytes())
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+
+				str.EXPECT().Close()
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
+				Eventually(handlerCalled).Should(BeClosed())
+			})
+		})
+
+		Context("timeouts", func() {
+			var readDeadlines chan time.Time
+
+			// setStalledRequest makes the stream return data, and block when all data has been read.
+			// The blocked Read returns a timeout error when the read deadline expires.
+			setStalledRequest := func(data []byte) {
+				buf := bytes.NewBuffer(data)
+				var deadline time.Time
+				str.EXPECT().SetReadDeadline(gomock.Any()).Do(func(t time.Time) {
+					deadline = t
+					readDeadlines <- t
+				}).AnyTimes()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					if buf.Len() > 0 || len(p) == 0 {
+						return buf.Read(p)
+					}
+					if deadline.IsZero() {
+						Fail("blocked read without a deadline")
+					}
+					time.Sleep(time.Until(deadline))
+					return 0, &timeoutError{}
+				}).AnyTimes()
+			}
+
+			BeforeEach(func() {
+				readDeadlines = make(chan time.Time, 10)
+				str.EXPECT().StreamID().Return(protocol.StreamID(4)).AnyTimes()
+			})
+
+			It("resets the stream when reading the request headers times out", func() {
+				s.ReadHeaderTimeout = 50 * time.Millisecond
+				s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					Fail("handler should not be called")
+				})
+				data := encodeRequest(exampleGetRequest)
+				setStalledRequest(data[:len(data)-2])
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+				str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled))
+
+				start := time.Now()
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(MatchError(errStreamReset))
+				Expect(time.Since(start)).To(BeNumerically(">=", s.ReadHeaderTimeout))
+				Expect(readDeadlines).To(Receive(BeTemporally("~", start.Add(s.ReadHeaderTimeout), 10*time.Millisecond)))
+			})
+
+			It("resets the stream when reading the body stalls for longer than the ReadTimeout", func() {
+				s.ReadTimeout = 100 * time.Millisecond
+				readErr := make(chan error, 1)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, err := ioutil.ReadAll(r.Body)
+					readErr <- err
+					w.WriteHeader(400)
+				})
+				buf := bytes.NewBuffer(encodeRequest(exampleGetRequest))
+				(&dataFrame{Length: 6}).Write(buf)
+				buf.Write([]byte("foo")) // the rest of the body is never sent
+				setStalledRequest(buf.Bytes())
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+				str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled))
+
+				start := time.Now()
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(MatchError(errStreamReset))
+				Expect(time.Since(start)).To(BeNumerically(">=", s.ReadTimeout))
+				var err error
+				Expect(readErr).To(Receive(&err))
+				Expect(err).To(BeAssignableToTypeOf(&timeoutError{}))
+				// the deadline is only set once, since there's no ReadHeaderTimeout
+				Expect(readDeadlines).To(Receive(BeTemporally("~", start.Add(s.ReadTimeout), 10*time.Millisecond)))
+				Expect(readDeadlines).ToNot(Receive())
+			})
+
+			It("uses the ReadHeaderTimeout for the headers, and the ReadTimeout for the whole request", func() {
+				s.ReadHeaderTimeout = time.Hour
+				s.ReadTimeout = 2 * time.Hour
+				handlerCalled := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					close(handlerCalled)
+				})
+				setStalledRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
+
+				start := time.Now()
+				str.EXPECT().Close()
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(Succeed())
+				Expect(handlerCalled).To(BeClosed())
+				Expect(readDeadlines).To(Receive(BeTemporally("~", start.Add(time.Hour), 10*time.Millisecond)))
+				Expect(readDeadlines).To(Receive(BeTemporally("~", start.Add(2*time.Hour), 10*time.Millisecond)))
+			})
+
+			It("resets the stream when writing the response exceeds the WriteTimeout", func() {
+				s.WriteTimeout = time.Minute
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, err := w.Write([]byte("foobar"))
+					Expect(err).To(BeAssignableToTypeOf(&timeoutError{}))
+				})
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+				start := time.Now()
+				str.EXPECT().SetWriteDeadline(gomock.Any()).Do(func(t time.Time) {
+					Expect(t).To(BeTemporally("~", start.Add(time.Minute), 10*time.Millisecond))
+				})
+				str.EXPECT().Write(gomock.Any()).Return(0, &timeoutError{}).AnyTimes()
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+				str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled))
+
+				Expect(s.handleRequest(sess, str, qpackDecoder)).To(MatchError(errStreamReset))
+			})
+		})
 	})
 
 	Context("setting http headers", func() {
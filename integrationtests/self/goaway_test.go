@@ -0,0 +1,123 @@
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP/3 GOAWAY tests", func() {
+	var server quic.Listener
+
+	BeforeEach(func() {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"h3-19"}
+		var err error
+		server, err = quic.ListenAddr("localhost:0", tlsConf, &quic.Config{Versions: []protocol.VersionNumber{protocol.VersionTLS}})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// writeResponse writes a HEADERS frame for an empty 200 response, and closes the stream.
+	writeResponse := func(str quic.Stream) {
+		headers := &bytes.Buffer{}
+		enc := qpack.NewEncoder(headers)
+		Expect(enc.WriteField(qpack.HeaderField{Name: ":status", Value: "200"})).To(Succeed())
+		Expect(enc.WriteField(qpack.HeaderField{Name: "content-length", Value: "0"})).To(Succeed())
+		buf := &bytes.Buffer{}
+		utils.WriteVarInt(buf, 0x1) // HEADERS frame
+		utils.WriteVarInt(buf, uint64(headers.Len()))
+		buf.Write(headers.Bytes())
+		_, err := str.Write(buf.Bytes())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+	}
+
+	It("sends a GOAWAY frame before closing an idle connection when the RoundTripper is drained", func() {
+		controlStreamAccepted := make(chan struct{})
+		controlStreamData := make(chan []byte, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			go func() {
+				defer GinkgoRecover()
+				str, err := sess.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				close(controlStreamAccepted)
+				// the control stream is never closed, so read until the session is closed
+				data, _ := ioutil.ReadAll(str)
+				controlStreamData <- data
+			}()
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			writeResponse(str)
+		}()
+
+		// The server stops reading from the control stream as soon as it receives the CONNECTION_CLOSE.
+		// Delay every packet sent by the client after draining a bit more than the previous one,
+		// such that the server gets the chance to read the GOAWAY frame first.
+		var lastPacket, drainedAt uint64 // accessed atomically
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(dir quicproxy.Direction, packetCount uint64) time.Duration {
+				if dir != quicproxy.DirectionIncoming {
+					return 0
+				}
+				atomic.StoreUint64(&lastPacket, packetCount)
+				if d := atomic.LoadUint64(&drainedAt); d > 0 && packetCount > d {
+					return time.Duration(packetCount-d) * 20 * time.Millisecond
+				}
+				return 0
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		rt := &http3.RoundTripper{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			QuicConfig: &quic.Config{
+				Versions: []protocol.VersionNumber{protocol.VersionTLS},
+				// Small writes are delayed, which must not prevent the GOAWAY frame from being sent.
+				WriteCoalescingDelay: 50 * time.Millisecond,
+			},
+		}
+		defer rt.Close()
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://localhost:%d/", proxy.LocalPort()), nil)
+		Expect(err).ToNot(HaveOccurred())
+		rsp, err := rt.RoundTrip(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rsp.StatusCode).To(Equal(200))
+		Expect(rsp.Body.Close()).To(Succeed())
+
+		// The session is closed right after the GOAWAY frame is sent.
+		// Make sure that the control stream was accepted before that.
+		Eventually(controlStreamAccepted).Should(BeClosed())
+		atomic.StoreUint64(&drainedAt, atomic.LoadUint64(&lastPacket))
+		rt.Drain()
+		var data []byte
+		Eventually(controlStreamData, 5).Should(Receive(&data))
+		// stream type 0x0, an empty SETTINGS frame, and a GOAWAY frame with Push ID 0
+		Expect(data).To(Equal([]byte{0x0, 0x4, 0x0, 0x7, 0x1, 0x0}))
+	})
+})
@@ -1,6 +1,7 @@
 package self_test
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
@@ -155,7 +156,7 @@ var _ = Describe("Handshake tests", func() {
 						errChan := make(chan error)
 						go func() {
 							defer GinkgoRecover()
-							_, err := sess.AcceptStream()
+							_, err := sess.AcceptStream(context.Background())
 							errChan <- err
 						}()
 						Eventually(errChan).Should(Receive(&err))
@@ -176,6 +177,33 @@ var _ = Describe("Handshake tests", func() {
 		}
 	})
 
+	Context("custom transport parameters", func() {
+		It("sends custom transport parameters in both directions", func() {
+			serverReceived := make(chan []byte, 1)
+			serverConfig.TransportParameters = []quic.TransportParameter{{
+				ID:        0xff42,
+				Marshal:   func() []byte { return []byte("server") },
+				Unmarshal: func(b []byte) error { serverReceived <- b; return nil },
+			}}
+			runServer()
+			var clientReceived []byte
+			_, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				&tls.Config{RootCAs: testdata.GetRootCA()},
+				&quic.Config{
+					TransportParameters: []quic.TransportParameter{{
+						ID:        0xff42,
+						Marshal:   func() []byte { return []byte("client") },
+						Unmarshal: func(b []byte) error { clientReceived = b; return nil },
+					}},
+				},
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(clientReceived).To(Equal([]byte("server")))
+			Eventually(serverReceived).Should(Receive(Equal([]byte("client"))))
+		})
+	})
+
 	Context("rate limiting", func() {
 		var server quic.Listener
 
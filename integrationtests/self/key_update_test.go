@@ -0,0 +1,80 @@
+package self_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/integrationtests/tools/testserver"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Key Update tests", func() {
+	var server quic.Listener
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// runTransfer sends testserver.PRData from the server to the client, and echoes it back.
+	// With the key update thresholds used here, the connection goes through many key updates while the data is flowing.
+	runTransfer := func(conf *quic.Config) {
+		var err error
+		server, err = quic.ListenAddr("localhost:0", testdata.GetTLSConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+
+		serverDone := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(serverDone)
+			sess, err := server.Accept()
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(testserver.PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(testserver.PRData))
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			&tls.Config{InsecureSkipVerify: true},
+			conf,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(testserver.PRData))
+		_, err = str.Write(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(serverDone, 10).Should(BeClosed())
+		Expect(sess.Close()).To(Succeed())
+	}
+
+	It("transfers data across key updates initiated after a number of packets", func() {
+		runTransfer(&quic.Config{
+			Versions:          []protocol.VersionNumber{protocol.VersionTLS},
+			KeyUpdateInterval: 10,
+		})
+	})
+
+	It("transfers data across key updates initiated after a number of bytes", func() {
+		runTransfer(&quic.Config{
+			Versions:       []protocol.VersionNumber{protocol.VersionTLS},
+			KeyUpdateBytes: 16 << 10,
+		})
+	})
+})
@@ -50,6 +50,18 @@ type Stream interface {
 	// It must not be called concurrently with Write.
 	// It must not be called after calling CancelWrite.
 	io.Closer
+	// Flush sends data that was delayed for write coalescing (see Config.WriteCoalescingDelay).
+	// It doesn't block until the data has been sent out.
+	Flush() error
+	// SetNoDelay controls whether small writes are coalesced (see Config.WriteCoalescingDelay).
+	// If noDelay is true, data is sent out immediately, and pending data is flushed.
+	SetNoDelay(noDelay bool)
+	// SetPriority sets the weight of the stream.
+	// When multiple streams have data to send, every stream gets to send weight STREAM frames in a row,
+	// before it's the next stream's turn. A stream with a higher weight therefore gets a proportionally
+	// larger share of the bandwidth. By default, every stream has a weight of 1.
+	// A weight of 0 is treated as 1.
+	SetPriority(weight uint8)
 	// CancelWrite aborts sending on this stream.
 	// Data already written, but not yet delivered to the peer is not guaranteed to be delivered reliably.
 	// Write will unblock immediately, and future calls to Write will fail.
@@ -101,6 +113,12 @@ type SendStream interface {
 	io.Writer
 	// see Stream.Close
 	io.Closer
+	// see Stream.Flush
+	Flush() error
+	// see Stream.SetNoDelay
+	SetNoDelay(noDelay bool)
+	// see Stream.SetPriority
+	SetPriority(weight uint8)
 	// see Stream.CancelWrite
 	CancelWrite(ErrorCode)
 	// see Stream.Context
@@ -119,13 +137,18 @@ type StreamError interface {
 // A Session is a QUIC connection between two peers.
 type Session interface {
 	// AcceptStream returns the next stream opened by the peer, blocking until one is available.
+	// If the context is canceled, it returns the error of the context.
+	// If the session is closed (or was already closed), it returns the error that closed the session.
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
-	AcceptStream() (Stream, error)
+	// An accepted stream is only released once it was read until io.EOF (or CancelRead was called),
+	// and Close (or CancelWrite) was called. Until then, it counts against the number of streams
+	// the peer is allowed to open. Unread data doesn't need to be drained, it can be discarded using CancelRead.
+	AcceptStream(context.Context) (Stream, error)
 	// AcceptUniStream returns the next unidirectional stream opened by the peer, blocking until one is available.
-	// If the session was closed due to a timeout, the error satisfies
-	// the net.Error interface, and Timeout() will be true.
-	AcceptUniStream() (ReceiveStream, error)
+	// It behaves like AcceptStream with respect to context cancelation and closing of the session.
+	// An accepted stream is only released once it was read until io.EOF, or CancelRead was called.
+	AcceptUniStream(context.Context) (ReceiveStream, error)
 	// OpenStream opens a new bidirectional QUIC stream.
 	// There is no signaling to the peer about new streams:
 	// The peer can only accept the stream after data has been sent on the stream.
@@ -157,12 +180,67 @@ type Session interface {
 	// Close the connection with an error.
 	// The error must not be nil.
 	CloseWithError(ErrorCode, error) error
+	// Abort resets all streams, and then closes the connection, using the same error code.
+	// Unlike CloseWithError, it signals the error on every stream: all streams are reset (RESET_STREAM),
+	// and the peer is asked to stop sending on them (STOP_SENDING), before the CONNECTION_CLOSE is sent.
+	// The error must not be nil.
+	Abort(ErrorCode, error) error
 	// The context is cancelled when the session is closed.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
 	// ConnectionState returns basic details about the QUIC connection.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() tls.ConnectionState
+	// ExportKeyingMaterial exports keying material from the TLS session, as defined in RFC 5705.
+	// It can only be used after the handshake has completed.
+	// Both endpoints derive the same keying material for the same label and context.
+	ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error)
+	// ApplicationBytesSent returns the number of bytes of stream data sent on this session.
+	// Retransmissions and packet overhead are not counted.
+	ApplicationBytesSent() uint64
+	// ApplicationBytesReceived returns the number of bytes of stream data read by the application.
+	// Data that was discarded because a stream was reset or reading was canceled is not counted.
+	ApplicationBytesReceived() uint64
+	// CongestionWindow returns the current congestion window, in bytes.
+	CongestionWindow() uint64
+	// SetMaxCongestionWindow limits the number of bytes in flight, even if the congestion window is larger.
+	// This can be used to make a transfer less aggressive. A value of 0 removes the limit.
+	SetMaxCongestionWindow(uint64)
+	// HandshakeTimes returns the times at which the phases of the handshake were reached.
+	// Phases that haven't been reached yet are reported as the zero time.
+	HandshakeTimes() HandshakeTimes
+	// Ping sends a PING frame and returns the round-trip time measured when the peer acknowledges it.
+	// It returns ctx.Err() if the context is canceled before the acknowledgement arrives.
+	// If the session is closed before, the error that closed the session is returned.
+	Ping(ctx context.Context) (time.Duration, error)
+}
+
+// HandshakeTimes records when the phases of the handshake of a session were reached.
+type HandshakeTimes struct {
+	// Start is the time when the session was created.
+	Start time.Time
+	// FirstResponse is the time when the first packet from the peer was processed.
+	FirstResponse time.Time
+	// Complete is the time when the TLS handshake completed.
+	Complete time.Time
+	// Confirmed is the time when the handshake was confirmed,
+	// i.e. when this endpoint learned that the peer completed the handshake as well.
+	// For the server, this is the same as Complete.
+	Confirmed time.Time
+}
+
+// A TransportParameter is an additional transport parameter that is sent during the handshake.
+// It is intended for experimentation.
+type TransportParameter struct {
+	// ID is the transport parameter ID.
+	// It must be in the range reserved for private use (0xff00 - 0xffff).
+	ID uint16
+	// Marshal returns the value sent to the peer.
+	// If nil, an empty value is sent.
+	Marshal func() []byte
+	// Unmarshal is called with the value sent by the peer, if the peer sent this transport parameter.
+	// If it returns an error, the connection is closed with a TRANSPORT_PARAMETER_ERROR.
+	Unmarshal func([]byte) error
 }
 
 // Config contains all configuration data needed for a QUIC server or client.
@@ -173,6 +251,7 @@ type Config struct {
 	Versions []VersionNumber
 	// The length of the connection ID in bytes.
 	// It can be 0, or any value between 4 and 18.
+	// Other lengths can't be encoded in the packet header, and are rejected by Dial and Listen.
 	// If not set, the interpretation depends on where the Config is used:
 	// If used for dialing an address, a 0 byte connection ID will be used.
 	// If used for a server, or dialing on a packet conn, a 4 byte connection ID will be used.
@@ -211,6 +290,52 @@ type Config struct {
 	StatelessResetKey []byte
 	// KeepAlive defines whether this peer will periodically send a packet to keep the connection alive.
 	KeepAlive bool
+	// MaxAckRanges is the maximum number of ACK ranges sent in a single ACK frame.
+	// If more ranges need to be acknowledged, the oldest ranges are omitted.
+	// If not set, it will default to 32.
+	MaxAckRanges int
+	// TransportParameters are additional transport parameters sent during the handshake.
+	TransportParameters []TransportParameter
+	// WriteCoalescingDelay is the maximum duration that small writes on a stream are delayed,
+	// in order to coalesce them into fewer STREAM frames.
+	// Data is sent out when the delay expires, when enough data for a full packet was written,
+	// or when Flush or Close is called on the stream.
+	// Coalescing can be disabled for individual streams using Stream.SetNoDelay.
+	// If zero, writes are never delayed.
+	WriteCoalescingDelay time.Duration
+	// EnableGSO enables UDP GSO (Generic Segmentation Offload) on Linux.
+	// Packets of the same size are then passed to the kernel in a single system call,
+	// which considerably reduces the CPU load when sending large amounts of data.
+	// GSO is only used if the kernel supports it. If sending fails, packets are sent one by one.
+	EnableGSO bool
+	// PTOProbePackets is the number of probe packets sent when the probe timeout (PTO) fires.
+	// If not set, it will default to 2.
+	PTOProbePackets int
+	// PTOProbeWithPing makes probe packets consist of a PING frame (and an ACK frame, if one is due),
+	// instead of a retransmission of the oldest outstanding packet.
+	// Such probe packets are smaller and elicit an acknowledgement just the same, but they don't repair any loss.
+	PTOProbeWithPing bool
+	// DSCP is the Differentiated Services Code Point set on outgoing packets,
+	// using the IP_TOS and IPV6_TCLASS socket options of the UDP socket.
+	// It can be used to prioritize QUIC traffic on networks that implement QoS.
+	// It must be between 0 and 63. If zero, the socket options are not changed.
+	// The socket option applies to all connections using the same UDP socket.
+	DSCP uint8
+	// KeyUpdateInterval is the number of packets sent with the same 1-RTT keys, after which a key update is initiated.
+	// A key update is only initiated after the handshake was confirmed,
+	// and after the peer acknowledged a packet sent with the current keys.
+	// If zero, key updates are never initiated based on the number of packets.
+	// Key updates initiated by the peer are always handled.
+	KeyUpdateInterval uint64
+	// KeyUpdateBytes is the number of bytes sent with the same 1-RTT keys, after which a key update is initiated.
+	// The same conditions as for KeyUpdateInterval apply.
+	// If zero, key updates are never initiated based on the number of bytes.
+	KeyUpdateBytes uint64
+	// InitialRTT is the RTT estimate used before the first RTT sample is taken.
+	// It is used to calculate the timeouts for retransmissions during the handshake,
+	// and the probe timeout (PTO) until an RTT sample is available.
+	// If not set, it will default to 100ms.
+	InitialRTT time.Duration
 }
 
 // A Listener for incoming QUIC connections
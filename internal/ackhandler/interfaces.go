@@ -28,6 +28,15 @@ type SentPacketHandler interface {
 	// Before sending any packet, SendingAllowed() must be called to learn if we can actually send it.
 	ShouldSendNumPackets() int
 
+	// GetCongestionWindow returns the congestion window of the congestion controller.
+	GetCongestionWindow() protocol.ByteCount
+	// SetMaxCongestionWindow limits the number of bytes in flight, even if the congestion window is larger.
+	// A value of 0 removes the limit.
+	SetMaxCongestionWindow(protocol.ByteCount)
+	// SetMaxAckDelay sets the max_ack_delay the peer sent in its transport parameters.
+	// It is used to calculate the PTO.
+	SetMaxAckDelay(time.Duration)
+
 	// only to be called once the handshake is complete
 	GetLowestPacketNotConfirmedAcked() protocol.PacketNumber
 	DequeuePacketForRetransmission() *Packet
@@ -12,7 +12,7 @@ import (
 
 const (
 	// maximum delay that can be applied to an ACK for a retransmittable packet
-	ackSendDelay = 25 * time.Millisecond
+	ackSendDelay = protocol.DefaultMaxAckDelay
 	// initial maximum number of retransmittable packets received before sending an ack.
 	initialRetransmittablePacketsBeforeAck = 2
 	// number of retransmittable that an ACK is sent for
@@ -42,15 +42,17 @@ type receivedPacketHandler struct {
 var _ ReceivedPacketHandler = &receivedPacketHandler{}
 
 // NewReceivedPacketHandler creates a new receivedPacketHandler
+// maxAckRanges is the maximum number of ACK ranges sent in a single ACK frame.
 func NewReceivedPacketHandler(
 	rttStats *congestion.RTTStats,
+	maxAckRanges int,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) ReceivedPacketHandler {
 	return &receivedPacketHandler{
-		initialPackets:   newReceivedPacketTracker(rttStats, logger, version),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger, version),
-		oneRTTPackets:    newReceivedPacketTracker(rttStats, logger, version),
+		initialPackets:   newReceivedPacketTracker(rttStats, maxAckRanges, logger, version),
+		handshakePackets: newReceivedPacketTracker(rttStats, maxAckRanges, logger, version),
+		oneRTTPackets:    newReceivedPacketTracker(rttStats, maxAckRanges, logger, version),
 	}
 }
 
@@ -18,6 +18,7 @@ var _ = Describe("Received Packet Handler", func() {
 	BeforeEach(func() {
 		handler = NewReceivedPacketHandler(
 			&congestion.RTTStats{},
+			protocol.DefaultMaxAckRanges,
 			utils.DefaultLogger,
 			protocol.VersionWhatever,
 		)
@@ -17,6 +17,7 @@ type receivedPacketTracker struct {
 	packetHistory *receivedPacketHistory
 
 	ackSendDelay time.Duration
+	maxAckRanges int
 	rttStats     *congestion.RTTStats
 
 	packetsReceivedSinceLastAck                int
@@ -32,12 +33,14 @@ type receivedPacketTracker struct {
 
 func newReceivedPacketTracker(
 	rttStats *congestion.RTTStats,
+	maxAckRanges int,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
 		packetHistory: newReceivedPacketHistory(),
 		ackSendDelay:  ackSendDelay,
+		maxAckRanges:  maxAckRanges,
 		rttStats:      rttStats,
 		logger:        logger,
 		version:       version,
@@ -175,8 +178,17 @@ func (h *receivedPacketTracker) GetAckFrame() *wire.AckFrame {
 		h.logger.Debugf("Sending ACK because the ACK timer expired.")
 	}
 
+	ackRanges := h.packetHistory.GetAckRanges()
+	// The ACK ranges are sorted in descending order.
+	// Drop the oldest ranges. The peer will retransmit the data if necessary.
+	if h.maxAckRanges > 0 && len(ackRanges) > h.maxAckRanges {
+		if h.logger.Debug() {
+			h.logger.Debugf("\tLimiting ACK frame to %d of %d ACK ranges.", h.maxAckRanges, len(ackRanges))
+		}
+		ackRanges = ackRanges[:h.maxAckRanges]
+	}
 	ack := &wire.AckFrame{
-		AckRanges: h.packetHistory.GetAckRanges(),
+		AckRanges: ackRanges,
 		DelayTime: now.Sub(h.largestObservedReceivedTime),
 	}
 
@@ -20,7 +20,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &congestion.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger, protocol.VersionWhatever)
+		tracker = newReceivedPacketTracker(rttStats, protocol.DefaultMaxAckRanges, utils.DefaultLogger, protocol.VersionWhatever)
 	})
 
 	Context("accepting packets", func() {
@@ -301,6 +301,33 @@ var _ = Describe("Received Packet Tracker", func() {
 				}))
 			})
 
+			It("limits the number of ACK ranges", func() {
+				for i := 0; i < 3*protocol.DefaultMaxAckRanges; i++ {
+					Expect(tracker.ReceivedPacket(protocol.PacketNumber(2*i), time.Time{}, true)).To(Succeed())
+				}
+				ack := tracker.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.AckRanges).To(HaveLen(protocol.DefaultMaxAckRanges))
+				// the oldest ranges are dropped
+				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(2 * (3*protocol.DefaultMaxAckRanges - 1))))
+				Expect(ack.LowestAcked()).To(Equal(protocol.PacketNumber(2 * 2 * protocol.DefaultMaxAckRanges)))
+			})
+
+			It("uses a custom limit for the number of ACK ranges", func() {
+				tracker = newReceivedPacketTracker(rttStats, 3, utils.DefaultLogger, protocol.VersionWhatever)
+				tracker.ackQueued = true
+				for _, pn := range []protocol.PacketNumber{1, 3, 5, 7, 9} {
+					Expect(tracker.ReceivedPacket(pn, time.Time{}, true)).To(Succeed())
+				}
+				ack := tracker.GetAckFrame()
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.AckRanges).To(Equal([]wire.AckRange{
+					{Smallest: 9, Largest: 9},
+					{Smallest: 7, Largest: 7},
+					{Smallest: 5, Largest: 5},
+				}))
+			})
+
 			It("accepts packets below the lower limit", func() {
 				tracker.IgnoreBelow(6)
 				err := tracker.ReceivedPacket(2, time.Time{}, true)
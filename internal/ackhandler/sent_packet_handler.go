@@ -19,6 +19,8 @@ const (
 	timeReorderingFraction = 1.0 / 8
 	// Timer granularity. The timer will not be set to a value smaller than granularity.
 	granularity = time.Millisecond
+	// The number of PTO periods for which all packets need to be lost for persistent congestion to be declared.
+	persistentCongestionThreshold = 3
 )
 
 type packetNumberSpace struct {
@@ -39,6 +41,11 @@ func newPacketNumberSpace(initialPN protocol.PacketNumber) *packetNumberSpace {
 type sentPacketHandler struct {
 	lastSentRetransmittablePacketTime time.Time // only applies to the application-data packet number space
 	lastSentCryptoPacketTime          time.Time
+	// the send time of the last packet that was acknowledged
+	// Only applies to the application-data packet number space.
+	lastAckedPacketSendTime time.Time
+	// the time when the first RTT sample was obtained
+	firstRTTSampleTime time.Time
 
 	nextSendTime time.Time
 
@@ -58,6 +65,11 @@ type sentPacketHandler struct {
 
 	congestion congestion.SendAlgorithm
 	rttStats   *congestion.RTTStats
+	// maxCongestionWindow is a limit for the bytes in flight set by the application.
+	// 0 means that the bytes in flight are only limited by the congestion controller.
+	maxCongestionWindow protocol.ByteCount
+	// The peer's max_ack_delay. It is 0 until the transport parameters are received.
+	maxAckDelay time.Duration
 
 	handshakeComplete bool
 
@@ -68,6 +80,8 @@ type sentPacketHandler struct {
 	// The number of PTO probe packets that should be sent.
 	// Only applies to the application-data packet number space.
 	numProbesToSend int
+	// The number of probe packets sent every time the PTO fires.
+	numProbesPerPTO int
 
 	// The time at which the next packet will be considered lost based on early transmit or exceeding the reordering window in time.
 	lossTime time.Time
@@ -82,6 +96,7 @@ type sentPacketHandler struct {
 func NewSentPacketHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *congestion.RTTStats,
+	numProbesPerPTO int,
 	logger utils.Logger,
 ) SentPacketHandler {
 	congestion := congestion.NewCubicSender(
@@ -98,6 +113,7 @@ func NewSentPacketHandler(
 		oneRTTPackets:    newPacketNumberSpace(0),
 		rttStats:         rttStats,
 		congestion:       congestion,
+		numProbesPerPTO:  numProbesPerPTO,
 		logger:           logger,
 	}
 }
@@ -210,6 +226,9 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 	// maybe update the RTT
 	if p := pnSpace.history.GetPacket(ackFrame.LargestAcked()); p != nil {
 		h.rttStats.UpdateRTT(rcvTime.Sub(p.SendTime), ackFrame.DelayTime, rcvTime)
+		if h.firstRTTSampleTime.IsZero() {
+			h.firstRTTSampleTime = rcvTime
+		}
 		if h.logger.Debug() {
 			h.logger.Debugf("\tupdated RTT: %s (σ: %s)", h.rttStats.SmoothedRTT(), h.rttStats.MeanDeviation())
 		}
@@ -240,9 +259,21 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 		}
 	}
 
-	if err := h.detectLostPackets(rcvTime, encLevel, priorInFlight); err != nil {
+	lostPackets, err := h.detectLostPackets(rcvTime, encLevel, priorInFlight)
+	if err != nil {
 		return err
 	}
+	if encLevel == protocol.Encryption1RTT {
+		if h.detectPersistentCongestion(lostPackets, ackedPackets) {
+			h.logger.Debugf("Persistent congestion detected. Collapsing the congestion window.")
+			// Persistent congestion replaced the retransmission timeout.
+			// The congestion controller reacts the same way: it collapses its window to the minimum.
+			h.congestion.OnRetransmissionTimeout(true)
+		}
+		for _, p := range ackedPackets {
+			h.lastAckedPacketSendTime = utils.MaxTime(h.lastAckedPacketSendTime, p.SendTime)
+		}
+	}
 
 	h.ptoCount = 0
 	h.cryptoCount = 0
@@ -333,7 +364,7 @@ func (h *sentPacketHandler) detectLostPackets(
 	now time.Time,
 	encLevel protocol.EncryptionLevel,
 	priorInFlight protocol.ByteCount,
-) error {
+) ([]*Packet, error) {
 	if encLevel == protocol.Encryption1RTT {
 		h.lossTime = time.Time{}
 	}
@@ -378,12 +409,49 @@ func (h *sentPacketHandler) detectLostPackets(
 		if p.canBeRetransmitted {
 			// queue the packet for retransmission, and report the loss to the congestion controller
 			if err := h.queuePacketForRetransmission(p, pnSpace); err != nil {
-				return err
+				return nil, err
 			}
 		}
 		pnSpace.history.Remove(p.PacketNumber)
 	}
-	return nil
+	return lostPackets, nil
+}
+
+// detectPersistentCongestion checks if the lost packets establish persistent congestion.
+// This is the case if all packets sent over a period longer than the persistent congestion duration were lost.
+// Both lostPackets and ackedPackets are sorted by packet number.
+// Only the packets declared lost when processing a single ACK frame are considered.
+// A PTO doesn't declare any packets lost. The packets sent before the PTO are declared lost
+// when the probe packets are acknowledged, so they are taken into account then.
+// Packets declared lost when the loss timer fires are not taken into account.
+// This is a limitation: these packets were sent less than 9/8 RTT before the ACK frame that armed the loss timer,
+// but they might extend a period of packets that was declared lost by the previous ACK frame.
+func (h *sentPacketHandler) detectPersistentCongestion(lostPackets, ackedPackets []*Packet) bool {
+	if h.firstRTTSampleTime.IsZero() {
+		return false
+	}
+	duration := h.computePersistentCongestionDuration()
+	var first *Packet // the first packet of the current period of lost packets
+	var i int
+	for _, p := range lostPackets {
+		// Packets sent before the first RTT sample,
+		// or before a packet that was acknowledged by a previous ACK, are not taken into account.
+		if !p.SendTime.After(h.firstRTTSampleTime) || !p.SendTime.After(h.lastAckedPacketSendTime) {
+			continue
+		}
+		// If a packet in between was acknowledged by this ACK, a new period starts.
+		for ; i < len(ackedPackets) && ackedPackets[i].PacketNumber < p.PacketNumber; i++ {
+			first = nil
+		}
+		if first == nil {
+			first = p
+			continue
+		}
+		if p.SendTime.Sub(first.SendTime) > duration {
+			return true
+		}
+	}
+	return false
 }
 
 func (h *sentPacketHandler) OnAlarm() error {
@@ -413,13 +481,13 @@ func (h *sentPacketHandler) onVerifiedAlarm() error {
 			h.logger.Debugf("Loss detection alarm fired in loss timer mode. Loss time: %s", h.lossTime)
 		}
 		// Early retransmit or time loss detection
-		err = h.detectLostPackets(time.Now(), protocol.Encryption1RTT, h.bytesInFlight)
+		_, err = h.detectLostPackets(time.Now(), protocol.Encryption1RTT, h.bytesInFlight)
 	} else { // PTO
 		if h.logger.Debug() {
 			h.logger.Debugf("Loss detection alarm fired in PTO mode. PTO count: %d", h.ptoCount)
 		}
 		h.ptoCount++
-		h.numProbesToSend += 2
+		h.numProbesToSend += h.numProbesPerPTO
 	}
 	return err
 }
@@ -543,7 +611,7 @@ func (h *sentPacketHandler) SendMode() SendMode {
 		return SendPTO
 	}
 	// Only send ACKs if we're congestion limited.
-	if cwnd := h.congestion.GetCongestionWindow(); h.bytesInFlight > cwnd {
+	if cwnd := h.sendWindow(); h.bytesInFlight > cwnd {
 		if h.logger.Debug() {
 			h.logger.Debugf("Congestion limited: bytes in flight %d, window %d", h.bytesInFlight, cwnd)
 		}
@@ -562,6 +630,27 @@ func (h *sentPacketHandler) SendMode() SendMode {
 	return SendAny
 }
 
+// sendWindow is the congestion window, limited by the maximum set by the application
+func (h *sentPacketHandler) sendWindow() protocol.ByteCount {
+	cwnd := h.congestion.GetCongestionWindow()
+	if h.maxCongestionWindow > 0 && h.maxCongestionWindow < cwnd {
+		return h.maxCongestionWindow
+	}
+	return cwnd
+}
+
+func (h *sentPacketHandler) GetCongestionWindow() protocol.ByteCount {
+	return h.congestion.GetCongestionWindow()
+}
+
+func (h *sentPacketHandler) SetMaxCongestionWindow(cwnd protocol.ByteCount) {
+	h.maxCongestionWindow = cwnd
+}
+
+func (h *sentPacketHandler) SetMaxAckDelay(d time.Duration) {
+	h.maxAckDelay = d
+}
+
 func (h *sentPacketHandler) TimeUntilSend() time.Time {
 	return h.nextSendTime
 }
@@ -621,10 +710,18 @@ func (h *sentPacketHandler) computeCryptoTimeout() time.Duration {
 	return duration << h.cryptoCount
 }
 
+// computePTO computes the probe timeout, without exponential backoff.
+func (h *sentPacketHandler) computePTO() time.Duration {
+	return utils.MaxDuration(h.rttStats.SmoothedOrInitialRTT()+4*h.rttStats.MeanDeviation(), granularity) + h.maxAckDelay
+}
+
 func (h *sentPacketHandler) computePTOTimeout() time.Duration {
-	// TODO(#1236): include the max_ack_delay
-	duration := utils.MaxDuration(h.rttStats.SmoothedOrInitialRTT()+4*h.rttStats.MeanDeviation(), granularity)
-	return duration << h.ptoCount
+	// exponential backoff
+	return h.computePTO() << h.ptoCount
+}
+
+func (h *sentPacketHandler) computePersistentCongestionDuration() time.Duration {
+	return persistentCongestionThreshold * h.computePTO()
 }
 
 func (h *sentPacketHandler) ResetForRetry() error {
@@ -49,7 +49,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 	BeforeEach(func() {
 		rttStats := &congestion.RTTStats{}
-		handler = NewSentPacketHandler(42, rttStats, utils.DefaultLogger).(*sentPacketHandler)
+		handler = NewSentPacketHandler(42, rttStats, 2, utils.DefaultLogger).(*sentPacketHandler)
 		handler.SetHandshakeComplete()
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
@@ -397,6 +397,22 @@ var _ = Describe("SentPacketHandler", func() {
 		Expect(handler.DequeuePacketForRetransmission()).To(BeNil())
 	})
 
+	Context("limiting the congestion window", func() {
+		It("limits the bytes in flight below the congestion window", func() {
+			cwnd := handler.GetCongestionWindow()
+			handler.SetMaxCongestionWindow(cwnd / 2)
+			var pn protocol.PacketNumber
+			for handler.SendMode() == SendAny {
+				pn++
+				handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: pn, Length: 1000}))
+			}
+			Expect(handler.SendMode()).To(Equal(SendAck))
+			Expect(handler.bytesInFlight).To(BeNumerically("<=", cwnd/2+1000))
+			Expect(handler.bytesInFlight).To(BeNumerically("<", cwnd))
+			Expect(handler.GetCongestionWindow()).To(Equal(cwnd))
+		})
+	})
+
 	Context("congestion", func() {
 		var cong *mocks.MockSendAlgorithm
 
@@ -494,6 +510,37 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).To(Equal(SendAck))
 		})
 
+		It("returns the congestion window", func() {
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(1337))
+			Expect(handler.GetCongestionWindow()).To(Equal(protocol.ByteCount(1337)))
+		})
+
+		It("limits the bytes in flight to the maximum congestion window set by the application", func() {
+			handler.SetMaxCongestionWindow(80)
+			handler.bytesInFlight = 100
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(200))
+			Expect(handler.SendMode()).To(Equal(SendAck))
+			handler.bytesInFlight = 80
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(200))
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			// the congestion window is smaller than the maximum
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(75))
+			Expect(handler.SendMode()).To(Equal(SendAck))
+			// the congestion controller is not affected by the limit
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(200))
+			Expect(handler.GetCongestionWindow()).To(Equal(protocol.ByteCount(200)))
+		})
+
+		It("removes the maximum congestion window", func() {
+			handler.SetMaxCongestionWindow(80)
+			handler.bytesInFlight = 100
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(200))
+			Expect(handler.SendMode()).To(Equal(SendAck))
+			handler.SetMaxCongestionWindow(0)
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(200))
+			Expect(handler.SendMode()).To(Equal(SendAny))
+		})
+
 		It("only allows sending of ACKs when we're keeping track of MaxOutstandingSentPackets packets", func() {
 			cong.EXPECT().GetCongestionWindow().Return(protocol.MaxByteCount).AnyTimes()
 			cong.EXPECT().TimeUntilSend(gomock.Any()).AnyTimes()
@@ -582,6 +629,105 @@ var _ = Describe("SentPacketHandler", func() {
 		Expect(handler.SendMode()).To(Equal(SendAny))
 	})
 
+	Context("persistent congestion", func() {
+		const minCongestionWindow = 2 * protocol.DefaultTCPMSS
+
+		var start time.Time
+
+		BeforeEach(func() {
+			start = time.Now().Add(-10 * time.Second)
+		})
+
+		// getRTTSample sends packet 1 and receives an ACK for it, one RTT (100ms) later
+		getRTTSample := func() {
+			handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1, SendTime: start}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			ExpectWithOffset(1, handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, start.Add(100*time.Millisecond))).To(Succeed())
+		}
+
+		// sendPackets sends packets 2 to 11, spread evenly over the given period, starting 1 second after start
+		sendPackets := func(period time.Duration) {
+			for i := 0; i < 10; i++ {
+				handler.SentPacket(retransmittablePacket(&Packet{
+					PacketNumber: protocol.PacketNumber(2 + i),
+					SendTime:     start.Add(time.Second + time.Duration(i)*period/9),
+				}))
+			}
+			handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 12, SendTime: time.Now().Add(-100 * time.Millisecond)}))
+		}
+
+		It("uses the same PTO as the probe timeout, but without exponential backoff", func() {
+			getRTTSample()
+			handler.maxAckDelay = 25 * time.Millisecond
+			Expect(handler.computePersistentCongestionDuration()).To(Equal(3 * handler.computePTOTimeout()))
+			handler.ptoCount = 2
+			Expect(handler.computePersistentCongestionDuration()).To(Equal(3 * (100 + 4*50 + 25) * time.Millisecond))
+		})
+
+		It("collapses the congestion window when all packets sent over the persistent congestion duration are lost", func() {
+			getRTTSample()
+			// The persistent congestion duration is 3 * (100ms + 4 * 50ms) = 900ms.
+			sendPackets(time.Second)
+			Expect(handler.congestion.GetCongestionWindow()).To(BeNumerically(">", minCongestionWindow))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 12, Largest: 12}}}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.retransmissionQueue).To(HaveLen(10))
+			Expect(handler.congestion.GetCongestionWindow()).To(Equal(minCongestionWindow))
+		})
+
+		It("doesn't collapse the congestion window when the lost packets were sent over a shorter period", func() {
+			getRTTSample()
+			sendPackets(500 * time.Millisecond)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 12, Largest: 12}}}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.retransmissionQueue).To(HaveLen(10))
+			Expect(handler.congestion.GetCongestionWindow()).To(BeNumerically(">", minCongestionWindow))
+		})
+
+		It("doesn't collapse the congestion window when a packet in between was acknowledged", func() {
+			getRTTSample()
+			sendPackets(time.Second)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 12, Largest: 12}, {Smallest: 6, Largest: 6}}}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.retransmissionQueue).To(HaveLen(9))
+			Expect(handler.congestion.GetCongestionWindow()).To(BeNumerically(">", minCongestionWindow))
+		})
+
+		It("doesn't collapse the congestion window when a packet in between was acknowledged by a previous ACK", func() {
+			getRTTSample()
+			sendPackets(time.Second)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, start.Add(2*time.Second))).To(Succeed())
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 12, Largest: 12}, {Smallest: 6, Largest: 6}}}
+			Expect(handler.ReceivedAck(ack, 3, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.retransmissionQueue).To(HaveLen(9))
+			Expect(handler.congestion.GetCongestionWindow()).To(BeNumerically(">", minCongestionWindow))
+		})
+
+		It("takes packets sent before a PTO into account when the probe packet is acknowledged", func() {
+			getRTTSample()
+			sendPackets(time.Second)
+			// the PTO doesn't declare any packets lost
+			Expect(handler.OnAlarm()).To(Succeed())
+			Expect(handler.ptoCount).To(BeEquivalentTo(1))
+			Expect(handler.retransmissionQueue).To(BeEmpty())
+			Expect(handler.congestion.GetCongestionWindow()).To(BeNumerically(">", minCongestionWindow))
+			handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 13, SendTime: time.Now()}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 13, Largest: 13}}}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(len(handler.retransmissionQueue)).To(BeNumerically(">=", 10))
+			Expect(handler.congestion.GetCongestionWindow()).To(Equal(minCongestionWindow))
+		})
+
+		It("doesn't take packets sent before the first RTT sample into account", func() {
+			sendPackets(time.Second)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 12, Largest: 12}}}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.retransmissionQueue).To(HaveLen(10))
+			Expect(handler.congestion.GetCongestionWindow()).To(BeNumerically(">", minCongestionWindow))
+		})
+	})
+
 	Context("probe packets", func() {
 		It("uses the RTT from RTT stats", func() {
 			rtt := 2 * time.Second
@@ -591,12 +737,34 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.computePTOTimeout()).To(Equal(time.Duration(2+4) * time.Second))
 		})
 
+		It("uses the initial RTT until an RTT sample is taken", func() {
+			handler.rttStats.SetInitialRTT(300 * time.Millisecond)
+			Expect(handler.computePTOTimeout()).To(Equal(300 * time.Millisecond))
+			Expect(handler.computeCryptoTimeout()).To(Equal(600 * time.Millisecond))
+			updateRTT(2 * time.Second)
+			Expect(handler.computePTOTimeout()).To(Equal(time.Duration(2+4) * time.Second))
+			Expect(handler.computeCryptoTimeout()).To(Equal(4 * time.Second))
+		})
+
 		It("uses the granularity for short RTTs", func() {
 			rtt := time.Microsecond
 			updateRTT(rtt)
 			Expect(handler.computePTOTimeout()).To(Equal(granularity))
 		})
 
+		It("adds the peer's max_ack_delay", func() {
+			updateRTT(2 * time.Second)
+			handler.SetMaxAckDelay(25 * time.Millisecond)
+			Expect(handler.computePTOTimeout()).To(Equal(time.Duration(2+4)*time.Second + 25*time.Millisecond))
+		})
+
+		It("doesn't add the max_ack_delay to the crypto timeout", func() {
+			updateRTT(2 * time.Second)
+			timeout := handler.computeCryptoTimeout()
+			handler.SetMaxAckDelay(25 * time.Millisecond)
+			Expect(handler.computeCryptoTimeout()).To(Equal(timeout))
+		})
+
 		It("implements exponential backoff", func() {
 			handler.ptoCount = 0
 			timeout := handler.computePTOTimeout()
@@ -618,6 +786,19 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).ToNot(Equal(SendPTO))
 		})
 
+		It("sends the configured number of probe packets", func() {
+			handler.numProbesPerPTO = 3
+			handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
+			handler.OnAlarm()
+			Expect(handler.SendMode()).To(Equal(SendPTO))
+			Expect(handler.ShouldSendNumPackets()).To(Equal(3))
+			for p := protocol.PacketNumber(2); p < 5; p++ {
+				Expect(handler.SendMode()).To(Equal(SendPTO))
+				handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: p}))
+			}
+			Expect(handler.SendMode()).ToNot(Equal(SendPTO))
+		})
+
 		It("only counts retransmittable packets as probe packets", func() {
 			handler.SentPacket(retransmittablePacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
 			handler.OnAlarm()
@@ -21,6 +21,8 @@ type RTTStats struct {
 	latestRTT     time.Duration
 	smoothedRTT   time.Duration
 	meanDeviation time.Duration
+
+	initialRTT time.Duration // used before an RTT sample is taken, defaultInitialRTT if zero
 }
 
 // NewRTTStats makes a properly initialized RTTStats object
@@ -46,9 +48,18 @@ func (r *RTTStats) SmoothedOrInitialRTT() time.Duration {
 	if r.smoothedRTT != 0 {
 		return r.smoothedRTT
 	}
+	if r.initialRTT != 0 {
+		return r.initialRTT
+	}
 	return defaultInitialRTT
 }
 
+// SetInitialRTT sets the RTT that is used before an RTT sample is taken.
+// If zero, the default initial RTT is used.
+func (r *RTTStats) SetInitialRTT(t time.Duration) {
+	r.initialRTT = t
+}
+
 // MeanDeviation gets the mean deviation
 func (r *RTTStats) MeanDeviation() time.Duration { return r.meanDeviation }
 
@@ -43,6 +43,14 @@ var _ = Describe("RTT stats", func() {
 		Expect(rttStats.SmoothedOrInitialRTT()).To(Equal((300 * time.Millisecond)))
 	})
 
+	It("uses the configured initial RTT", func() {
+		rttStats.SetInitialRTT(42 * time.Millisecond)
+		Expect(rttStats.SmoothedOrInitialRTT()).To(Equal(42 * time.Millisecond))
+		Expect(rttStats.SmoothedRTT()).To(BeZero())
+		rttStats.UpdateRTT((300 * time.Millisecond), (100 * time.Millisecond), time.Time{})
+		Expect(rttStats.SmoothedOrInitialRTT()).To(Equal((300 * time.Millisecond)))
+	})
+
 	It("MinRTT", func() {
 		rttStats.UpdateRTT((200 * time.Millisecond), 0, time.Time{})
 		Expect(rttStats.MinRTT()).To(Equal((200 * time.Millisecond)))
@@ -2,6 +2,7 @@ package flowcontrol
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -12,6 +13,10 @@ import (
 type connectionFlowController struct {
 	baseFlowController
 
+	// accessed atomically
+	applicationBytesSent uint64
+	applicationBytesRead uint64
+
 	queueWindowUpdate func()
 }
 
@@ -54,11 +59,30 @@ func (c *connectionFlowController) IncrementHighestReceived(increment protocol.B
 	return nil
 }
 
+func (c *connectionFlowController) AddBytesSent(n protocol.ByteCount) {
+	c.baseFlowController.AddBytesSent(n)
+	atomic.AddUint64(&c.applicationBytesSent, uint64(n))
+}
+
 func (c *connectionFlowController) AddBytesRead(n protocol.ByteCount) {
 	c.baseFlowController.AddBytesRead(n)
+	atomic.AddUint64(&c.applicationBytesRead, uint64(n))
 	c.maybeQueueWindowUpdate()
 }
 
+func (c *connectionFlowController) AddBytesAbandoned(n protocol.ByteCount) {
+	c.baseFlowController.AddBytesRead(n)
+	c.maybeQueueWindowUpdate()
+}
+
+func (c *connectionFlowController) ApplicationBytesSent() protocol.ByteCount {
+	return protocol.ByteCount(atomic.LoadUint64(&c.applicationBytesSent))
+}
+
+func (c *connectionFlowController) ApplicationBytesRead() protocol.ByteCount {
+	return protocol.ByteCount(atomic.LoadUint64(&c.applicationBytesRead))
+}
+
 func (c *connectionFlowController) maybeQueueWindowUpdate() {
 	c.mutex.Lock()
 	hasWindowUpdate := c.hasWindowUpdate()
@@ -95,6 +95,30 @@ var _ = Describe("Connection Flow controller", func() {
 		})
 	})
 
+	Context("application data accounting", func() {
+		It("counts the bytes sent", func() {
+			controller.AddBytesSent(100)
+			controller.AddBytesSent(23)
+			Expect(controller.ApplicationBytesSent()).To(Equal(protocol.ByteCount(123)))
+		})
+
+		It("counts the bytes read", func() {
+			controller.AddBytesRead(100)
+			controller.AddBytesRead(23)
+			Expect(controller.ApplicationBytesRead()).To(Equal(protocol.ByteCount(123)))
+		})
+
+		It("doesn't count abandoned bytes as read, but uses them for flow control", func() {
+			controller.receiveWindow = 100
+			controller.receiveWindowSize = 100
+			controller.AddBytesRead(10)
+			controller.AddBytesAbandoned(80)
+			Expect(controller.ApplicationBytesRead()).To(Equal(protocol.ByteCount(10)))
+			Expect(controller.bytesRead).To(Equal(protocol.ByteCount(90)))
+			Expect(queuedWindowUpdate).To(BeTrue())
+		})
+	})
+
 	Context("setting the minimum window size", func() {
 		var (
 			oldWindowSize     protocol.ByteCount
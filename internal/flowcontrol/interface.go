@@ -29,6 +29,14 @@ type StreamFlowController interface {
 // The ConnectionFlowController is the flow controller for the connection.
 type ConnectionFlowController interface {
 	flowController
+	// ApplicationBytesSent returns the number of bytes of stream data sent.
+	// Retransmissions are not counted.
+	// It is safe to call from any goroutine.
+	ApplicationBytesSent() protocol.ByteCount
+	// ApplicationBytesRead returns the number of bytes of stream data read by the application.
+	// Data discarded when a stream is reset or reading is canceled is not counted.
+	// It is safe to call from any goroutine.
+	ApplicationBytesRead() protocol.ByteCount
 }
 
 type connectionFlowControllerI interface {
@@ -38,4 +46,7 @@ type connectionFlowControllerI interface {
 	EnsureMinimumWindowSize(protocol.ByteCount)
 	// for receiving
 	IncrementHighestReceived(protocol.ByteCount) error
+	// AddBytesAbandoned should be called for data that counts towards flow control,
+	// but won't be read by the application.
+	AddBytesAbandoned(protocol.ByteCount)
 }
@@ -97,7 +97,7 @@ func (c *streamFlowController) AddBytesRead(n protocol.ByteCount) {
 
 func (c *streamFlowController) Abandon() {
 	if unread := c.highestReceived - c.bytesRead; unread > 0 {
-		c.connection.AddBytesRead(unread)
+		c.connection.AddBytesAbandoned(unread)
 	}
 }
 
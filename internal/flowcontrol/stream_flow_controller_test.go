@@ -139,6 +139,13 @@ var _ = Describe("Stream Flow controller", func() {
 				controller.Abandon()
 				Expect(controller.connection.(*connectionFlowController).bytesRead).To(Equal(protocol.ByteCount(100)))
 			})
+
+			It("doesn't count abandoned data as read by the application", func() {
+				controller.AddBytesRead(5)
+				Expect(controller.UpdateHighestReceived(100, true)).To(Succeed())
+				controller.Abandon()
+				Expect(controller.connection.ApplicationBytesRead()).To(Equal(protocol.ByteCount(5)))
+			})
 		})
 
 		It("saves when data is read", func() {
@@ -176,6 +183,40 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(queuedWindowUpdate).To(BeFalse())
 			})
 
+			It("queues a window update before half of the window is consumed", func() {
+				var queued bool
+				cc := NewConnectionFlowController(1000, 1000, func() {}, &congestion.RTTStats{}, utils.DefaultLogger)
+				fc := NewStreamFlowController(5, cc, 1000, 1000, 0, func(protocol.StreamID) { queued = true }, &congestion.RTTStats{}, utils.DefaultLogger)
+				Expect(fc.UpdateHighestReceived(1000, false)).To(Succeed())
+				fc.AddBytesRead(protocol.ByteCount(1000*protocol.WindowUpdateThreshold) - 1)
+				Expect(queued).To(BeFalse())
+				Expect(fc.GetWindowUpdate()).To(BeZero())
+				fc.AddBytesRead(1)
+				Expect(queued).To(BeTrue())
+				// the peer can send a full window beyond the data we already read
+				Expect(fc.GetWindowUpdate()).To(Equal(protocol.ByteCount(1000*protocol.WindowUpdateThreshold) + 1000))
+			})
+
+			It("keeps sending window updates to a reader that consumes data as soon as it arrives", func() {
+				var numQueued int
+				cc := NewConnectionFlowController(1<<20, 1<<20, func() {}, &congestion.RTTStats{}, utils.DefaultLogger)
+				fc := NewStreamFlowController(5, cc, 1000, 1000, 0, func(protocol.StreamID) { numQueued++ }, &congestion.RTTStats{}, utils.DefaultLogger)
+				receiveWindow := protocol.ByteCount(1000)
+				var offset protocol.ByteCount
+				for i := 0; i < 20; i++ {
+					// the peer sends half a window, which is immediately read
+					offset += 500
+					Expect(fc.UpdateHighestReceived(offset, false)).To(Succeed())
+					fc.AddBytesRead(500)
+					Expect(numQueued).To(Equal(i + 1))
+					update := fc.GetWindowUpdate()
+					Expect(update).To(BeNumerically(">", receiveWindow))
+					receiveWindow = update
+					// the sender never gets blocked
+					Expect(receiveWindow - offset).To(BeNumerically(">=", 1000))
+				}
+			})
+
 			It("tells the connection flow controller when the window was autotuned", func() {
 				oldOffset := controller.bytesRead
 				setRtt(scaleDuration(20 * time.Millisecond))
@@ -1,7 +1,6 @@
 package handshake
 
 import (
-	"crypto/aes"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -104,9 +103,10 @@ type cryptoSetup struct {
 	handshakeOpener Opener
 	handshakeSealer Sealer
 
-	oneRTTStream io.Writer
-	opener       Opener
-	sealer       Sealer
+	oneRTTStream  io.Writer
+	aead          *updatableAEAD
+	has1RTTSealer bool
+	has1RTTOpener bool
 }
 
 var _ qtls.RecordLayer = &cryptoSetup{}
@@ -122,6 +122,8 @@ func NewCryptoSetupClient(
 	tp *TransportParameters,
 	handleParams func([]byte),
 	tlsConf *tls.Config,
+	keyUpdateInterval uint64,
+	keyUpdateBytes uint64,
 	logger utils.Logger,
 ) (CryptoSetup, <-chan struct{} /* ClientHello written */, error) {
 	cs, clientHelloWritten, err := newCryptoSetup(
@@ -132,6 +134,8 @@ func NewCryptoSetupClient(
 		tp,
 		handleParams,
 		tlsConf,
+		keyUpdateInterval,
+		keyUpdateBytes,
 		logger,
 		protocol.PerspectiveClient,
 	)
@@ -152,6 +156,8 @@ func NewCryptoSetupServer(
 	tp *TransportParameters,
 	handleParams func([]byte),
 	tlsConf *tls.Config,
+	keyUpdateInterval uint64,
+	keyUpdateBytes uint64,
 	logger utils.Logger,
 ) (CryptoSetup, error) {
 	cs, _, err := newCryptoSetup(
@@ -162,6 +168,8 @@ func NewCryptoSetupServer(
 		tp,
 		handleParams,
 		tlsConf,
+		keyUpdateInterval,
+		keyUpdateBytes,
 		logger,
 		protocol.PerspectiveServer,
 	)
@@ -180,6 +188,8 @@ func newCryptoSetup(
 	tp *TransportParameters,
 	handleParams func([]byte),
 	tlsConf *tls.Config,
+	keyUpdateInterval uint64,
+	keyUpdateBytes uint64,
 	logger utils.Logger,
 	perspective protocol.Perspective,
 ) (*cryptoSetup, <-chan struct{} /* ClientHello written */, error) {
@@ -194,6 +204,7 @@ func newCryptoSetup(
 		initialOpener:          initialOpener,
 		handshakeStream:        handshakeStream,
 		oneRTTStream:           oneRTTStream,
+		aead:                   newUpdatableAEAD(keyUpdateInterval, keyUpdateBytes, logger),
 		readEncLevel:           protocol.EncryptionInitial,
 		writeEncLevel:          protocol.EncryptionInitial,
 		handleParamsCallback:   handleParams,
@@ -426,23 +437,20 @@ func (h *cryptoSetup) ReadHandshakeMessage() ([]byte, error) {
 }
 
 func (h *cryptoSetup) SetReadKey(suite *qtls.CipherSuite, trafficSecret []byte) {
-	key := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic key", suite.KeyLen())
-	iv := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic iv", suite.IVLen())
-	hpKey := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic hp", suite.KeyLen())
-	hpDecrypter, err := aes.NewCipher(hpKey)
-	if err != nil {
-		panic(fmt.Sprintf("error creating new AES cipher: %s", err))
-	}
-
 	h.mutex.Lock()
 	switch h.readEncLevel {
 	case protocol.EncryptionInitial:
 		h.readEncLevel = protocol.EncryptionHandshake
-		h.handshakeOpener = newOpener(suite.AEAD(key, iv), hpDecrypter, false)
+		h.handshakeOpener = newOpener(
+			createAEAD(suite, trafficSecret),
+			createHeaderProtector(suite, trafficSecret),
+			false,
+		)
 		h.logger.Debugf("Installed Handshake Read keys")
 	case protocol.EncryptionHandshake:
 		h.readEncLevel = protocol.Encryption1RTT
-		h.opener = newOpener(suite.AEAD(key, iv), hpDecrypter, true)
+		h.aead.SetReadKey(suite, trafficSecret)
+		h.has1RTTOpener = true
 		h.logger.Debugf("Installed 1-RTT Read keys")
 	default:
 		panic("unexpected read encryption level")
@@ -452,23 +460,20 @@ func (h *cryptoSetup) SetReadKey(suite *qtls.CipherSuite, trafficSecret []byte)
 }
 
 func (h *cryptoSetup) SetWriteKey(suite *qtls.CipherSuite, trafficSecret []byte) {
-	key := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic key", suite.KeyLen())
-	iv := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic iv", suite.IVLen())
-	hpKey := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic hp", suite.KeyLen())
-	hpEncrypter, err := aes.NewCipher(hpKey)
-	if err != nil {
-		panic(fmt.Sprintf("error creating new AES cipher: %s", err))
-	}
-
 	h.mutex.Lock()
 	switch h.writeEncLevel {
 	case protocol.EncryptionInitial:
 		h.writeEncLevel = protocol.EncryptionHandshake
-		h.handshakeSealer = newSealer(suite.AEAD(key, iv), hpEncrypter, false)
+		h.handshakeSealer = newSealer(
+			createAEAD(suite, trafficSecret),
+			createHeaderProtector(suite, trafficSecret),
+			false,
+		)
 		h.logger.Debugf("Installed Handshake Write keys")
 	case protocol.EncryptionHandshake:
 		h.writeEncLevel = protocol.Encryption1RTT
-		h.sealer = newSealer(suite.AEAD(key, iv), hpEncrypter, true)
+		h.aead.SetWriteKey(suite, trafficSecret)
+		h.has1RTTSealer = true
 		h.logger.Debugf("Installed 1-RTT Write keys")
 	default:
 		panic("unexpected write encryption level")
@@ -515,8 +520,8 @@ func (h *cryptoSetup) GetSealer() (protocol.EncryptionLevel, Sealer) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
-	if h.sealer != nil {
-		return protocol.Encryption1RTT, h.sealer
+	if h.has1RTTSealer {
+		return protocol.Encryption1RTT, h.aead
 	}
 	if h.handshakeSealer != nil {
 		return protocol.EncryptionHandshake, h.handshakeSealer
@@ -539,10 +544,10 @@ func (h *cryptoSetup) GetSealerWithEncryptionLevel(level protocol.EncryptionLeve
 		}
 		return h.handshakeSealer, nil
 	case protocol.Encryption1RTT:
-		if h.sealer == nil {
+		if !h.has1RTTSealer {
 			return nil, errNoSealer
 		}
-		return h.sealer, nil
+		return h.aead, nil
 	default:
 		return nil, errNoSealer
 	}
@@ -560,16 +565,32 @@ func (h *cryptoSetup) GetOpener(level protocol.EncryptionLevel) (Opener, error)
 			return nil, ErrOpenerNotYetAvailable
 		}
 		return h.handshakeOpener, nil
-	case protocol.Encryption1RTT:
-		if h.opener == nil {
-			return nil, ErrOpenerNotYetAvailable
-		}
-		return h.opener, nil
 	default:
 		return nil, fmt.Errorf("CryptoSetup: no opener with encryption level %s", level)
 	}
 }
 
+// Get1RTTOpener returns the opener for short header packets.
+func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.has1RTTOpener {
+		return nil, ErrOpenerNotYetAvailable
+	}
+	return h.aead, nil
+}
+
+// SetHandshakeConfirmed is called when the handshake is confirmed.
+func (h *cryptoSetup) SetHandshakeConfirmed() {
+	h.aead.SetHandshakeConfirmed()
+}
+
+// SetLargest1RTTAcked is called when an ACK for a 1-RTT packet is received.
+func (h *cryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) {
+	h.aead.SetLargestAcked(pn)
+}
+
 func (h *cryptoSetup) ConnectionState() tls.ConnectionState {
 	cs := h.conn.ConnectionState()
 	// h.conn is a qtls.Conn, which returns a qtls.ConnectionState.
@@ -579,3 +600,13 @@ func (h *cryptoSetup) ConnectionState() tls.ConnectionState {
 	// In unsafe.go we check that the two objects are actually identical.
 	return *(*tls.ConnectionState)(unsafe.Pointer(&cs))
 }
+
+// ExportKeyingMaterial exports keying material from the TLS session, as defined in RFC 5705.
+// It returns an error if the handshake hasn't completed yet.
+func (h *cryptoSetup) ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error) {
+	cs := h.conn.ConnectionState()
+	if !cs.HandshakeComplete {
+		return nil, errors.New("cannot export keying material before the handshake has completed")
+	}
+	return cs.ExportKeyingMaterial(label, context, length)
+}
@@ -88,6 +88,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			func([]byte) {},
 			tlsConf,
+			0,
+			0,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
 		Expect(err).ToNot(HaveOccurred())
@@ -116,6 +118,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			func([]byte) {},
 			testdata.GetTLSConfig(),
+			0,
+			0,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
 		Expect(err).ToNot(HaveOccurred())
@@ -150,6 +154,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			func([]byte) {},
 			testdata.GetTLSConfig(),
+			0,
+			0,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
 		Expect(err).ToNot(HaveOccurred())
@@ -178,6 +184,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			func([]byte) {},
 			testdata.GetTLSConfig(),
+			0,
+			0,
 			utils.DefaultLogger.WithPrefix("server"),
 		)
 		Expect(err).ToNot(HaveOccurred())
@@ -257,6 +265,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&TransportParameters{},
 				func([]byte) {},
 				clientConf,
+				0,
+				0,
 				utils.DefaultLogger.WithPrefix("client"),
 			)
 			Expect(err).ToNot(HaveOccurred())
@@ -272,6 +282,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&TransportParameters{StatelessResetToken: &token},
 				func([]byte) {},
 				serverConf,
+				0,
+				0,
 				utils.DefaultLogger.WithPrefix("server"),
 			)
 			Expect(err).ToNot(HaveOccurred())
@@ -303,6 +315,57 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Expect(serverErr).ToNot(HaveOccurred())
 		})
 
+		It("exports the same keying material on both sides", func() {
+			cChunkChan, cInitialStream, cHandshakeStream := initStreams()
+			client, _, err := NewCryptoSetupClient(
+				cInitialStream,
+				cHandshakeStream,
+				ioutil.Discard,
+				protocol.ConnectionID{},
+				nil,
+				&TransportParameters{},
+				func([]byte) {},
+				clientConf,
+				0,
+				0,
+				utils.DefaultLogger.WithPrefix("client"),
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			sChunkChan, sInitialStream, sHandshakeStream := initStreams()
+			var token [16]byte
+			server, err := NewCryptoSetupServer(
+				sInitialStream,
+				sHandshakeStream,
+				ioutil.Discard,
+				protocol.ConnectionID{},
+				nil,
+				&TransportParameters{StatelessResetToken: &token},
+				func([]byte) {},
+				testdata.GetTLSConfig(),
+				0,
+				0,
+				utils.DefaultLogger.WithPrefix("server"),
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = client.ExportKeyingMaterial("label", []byte("context"), 32)
+			Expect(err).To(MatchError("cannot export keying material before the handshake has completed"))
+
+			clientErr, serverErr := handshake(client, cChunkChan, server, sChunkChan)
+			Expect(clientErr).ToNot(HaveOccurred())
+			Expect(serverErr).ToNot(HaveOccurred())
+			clientKM, err := client.ExportKeyingMaterial("label", []byte("context"), 32)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(clientKM).To(HaveLen(32))
+			serverKM, err := server.ExportKeyingMaterial("label", []byte("context"), 32)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serverKM).To(Equal(clientKM))
+			otherKM, err := server.ExportKeyingMaterial("other label", []byte("context"), 32)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(otherKM).ToNot(Equal(clientKM))
+		})
+
 		It("signals when it has written the ClientHello", func() {
 			cChunkChan, cInitialStream, cHandshakeStream := initStreams()
 			client, chChan, err := NewCryptoSetupClient(
@@ -314,6 +377,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&TransportParameters{},
 				func([]byte) {},
 				&tls.Config{InsecureSkipVerify: true},
+				0,
+				0,
 				utils.DefaultLogger.WithPrefix("client"),
 			)
 			Expect(err).ToNot(HaveOccurred())
@@ -351,6 +416,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 				cTransportParameters,
 				func(p []byte) { sTransportParametersRcvd = p },
 				clientConf,
+				0,
+				0,
 				utils.DefaultLogger.WithPrefix("client"),
 			)
 			Expect(err).ToNot(HaveOccurred())
@@ -370,6 +437,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sTransportParameters,
 				func(p []byte) { cTransportParametersRcvd = p },
 				testdata.GetTLSConfig(),
+				0,
+				0,
 				utils.DefaultLogger.WithPrefix("server"),
 			)
 			Expect(err).ToNot(HaveOccurred())
@@ -22,6 +22,21 @@ type Sealer interface {
 	Overhead() int
 }
 
+// ShortHeaderOpener opens a short header packet.
+// The key phase is needed to select the keys, since 1-RTT keys can be updated.
+type ShortHeaderOpener interface {
+	Open(dst, src []byte, packetNumber protocol.PacketNumber, keyPhase int, associatedData []byte) ([]byte, error)
+	DecryptHeader(sample []byte, firstByte *byte, pnBytes []byte)
+}
+
+// ShortHeaderSealer seals a short header packet
+type ShortHeaderSealer interface {
+	Sealer
+	// KeyPhase returns the key phase that has to be set in the header of the next packet.
+	// It initiates a key update, if necessary.
+	KeyPhase() int
+}
+
 // A tlsExtensionHandler sends and received the QUIC TLS extension.
 type tlsExtensionHandler interface {
 	GetExtensions(msgType uint8) []qtls.Extension
@@ -37,10 +52,16 @@ type CryptoSetup interface {
 
 	HandleMessage([]byte, protocol.EncryptionLevel) bool
 	ConnectionState() tls.ConnectionState
+	ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error)
 
 	GetSealer() (protocol.EncryptionLevel, Sealer)
 	GetSealerWithEncryptionLevel(protocol.EncryptionLevel) (Sealer, error)
 	GetOpener(protocol.EncryptionLevel) (Opener, error)
+	Get1RTTOpener() (ShortHeaderOpener, error)
+
+	// SetHandshakeConfirmed and SetLargest1RTTAcked are used to decide when a key update can be initiated.
+	SetHandshakeConfirmed()
+	SetLargest1RTTAcked(protocol.PacketNumber)
 }
 
 // ConnectionState records basic details about the QUIC connection.
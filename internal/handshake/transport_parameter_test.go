@@ -31,9 +31,10 @@ var _ = Describe("Transport Parameters", func() {
 			IdleTimeout:                    42 * time.Second,
 			OriginalConnectionID:           protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
 			AckDelayExponent:               14,
+			MaxAckDelay:                    37 * time.Millisecond,
 			StatelessResetToken:            &[16]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00},
 		}
-		Expect(p.String()).To(Equal("&handshake.TransportParameters{OriginalConnectionID: 0xdeadbeef, InitialMaxStreamDataBidiLocal: 0x1234, InitialMaxStreamDataBidiRemote: 0x2345, InitialMaxStreamDataUni: 0x3456, InitialMaxData: 0x4567, MaxBidiStreams: 1337, MaxUniStreams: 7331, IdleTimeout: 42s, AckDelayExponent: 14, StatelessResetToken: 0x112233445566778899aabbccddeeff00}"))
+		Expect(p.String()).To(Equal("&handshake.TransportParameters{OriginalConnectionID: 0xdeadbeef, InitialMaxStreamDataBidiLocal: 0x1234, InitialMaxStreamDataBidiRemote: 0x2345, InitialMaxStreamDataUni: 0x3456, InitialMaxData: 0x4567, MaxBidiStreams: 1337, MaxUniStreams: 7331, IdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, StatelessResetToken: 0x112233445566778899aabbccddeeff00}"))
 	})
 
 	It("has a string representation, if there's no stateless reset token", func() {
@@ -47,8 +48,9 @@ var _ = Describe("Transport Parameters", func() {
 			IdleTimeout:                    42 * time.Second,
 			OriginalConnectionID:           protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
 			AckDelayExponent:               14,
+			MaxAckDelay:                    37 * time.Millisecond,
 		}
-		Expect(p.String()).To(Equal("&handshake.TransportParameters{OriginalConnectionID: 0xdeadbeef, InitialMaxStreamDataBidiLocal: 0x1234, InitialMaxStreamDataBidiRemote: 0x2345, InitialMaxStreamDataUni: 0x3456, InitialMaxData: 0x4567, MaxBidiStreams: 1337, MaxUniStreams: 7331, IdleTimeout: 42s, AckDelayExponent: 14}"))
+		Expect(p.String()).To(Equal("&handshake.TransportParameters{OriginalConnectionID: 0xdeadbeef, InitialMaxStreamDataBidiLocal: 0x1234, InitialMaxStreamDataBidiRemote: 0x2345, InitialMaxStreamDataUni: 0x3456, InitialMaxData: 0x4567, MaxBidiStreams: 1337, MaxUniStreams: 7331, IdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms}"))
 	})
 
 	getRandomValue := func() uint64 {
@@ -72,6 +74,7 @@ var _ = Describe("Transport Parameters", func() {
 			StatelessResetToken:            &token,
 			OriginalConnectionID:           protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
 			AckDelayExponent:               13,
+			MaxAckDelay:                    42 * time.Millisecond,
 		}
 		data := params.Marshal()
 
@@ -88,6 +91,7 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.StatelessResetToken).To(Equal(params.StatelessResetToken))
 		Expect(p.OriginalConnectionID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}))
 		Expect(p.AckDelayExponent).To(Equal(uint8(13)))
+		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
 	})
 
 	It("errors if the transport parameters are too short to contain the length", func() {
@@ -148,6 +152,26 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.AckDelayExponent).To(BeEquivalentTo(protocol.DefaultAckDelayExponent))
 	})
 
+	It("errors when the max_ack_delay is too large", func() {
+		data := (&TransportParameters{MaxAckDelay: 1 << 14 * time.Millisecond}).Marshal()
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(MatchError("invalid value for max_ack_delay: 16384ms (maximum 16383ms)"))
+	})
+
+	It("doesn't send the max_ack_delay, if it has the default value", func() {
+		dataDefault := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay}).Marshal()
+		defaultLen := len(dataDefault)
+		data := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay + time.Millisecond}).Marshal()
+		Expect(len(data)).To(Equal(defaultLen + 2 /* parameter ID */ + 2 /* length field */ + 1 /* value */))
+	})
+
+	It("sets the default value for the max_ack_delay, when no value was sent", func() {
+		data := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay}).Marshal()
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.MaxAckDelay).To(Equal(protocol.DefaultMaxAckDelay))
+	})
+
 	It("errors when the varint value has the wrong length", func() {
 		b := &bytes.Buffer{}
 		utils.BigEndian.WriteUint16(b, uint16(initialMaxStreamDataBidiLocalParameterID))
@@ -181,6 +205,44 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.InitialMaxStreamDataBidiRemote).To(Equal(protocol.ByteCount(0x42)))
 	})
 
+	It("ignores the retry_source_connection_id, since it isn't defined for this version", func() {
+		b := &bytes.Buffer{}
+		utils.BigEndian.WriteUint16(b, 0x10)
+		utils.BigEndian.WriteUint16(b, 4)
+		b.Write([]byte{0xde, 0xad, 0xbe, 0xef})
+		for _, pers := range []protocol.Perspective{protocol.PerspectiveClient, protocol.PerspectiveServer} {
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(prependLength(b.Bytes()), pers)).To(Succeed())
+			Expect(p.CustomParameters).To(BeEmpty())
+		}
+	})
+
+	It("marshals and unmarshals custom parameters", func() {
+		params := &TransportParameters{
+			CustomParameters: map[uint16][]byte{
+				0xff42: []byte("foobar"),
+				0xff00: {},
+				0xffff: []byte("raboof"),
+			},
+		}
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(params.Marshal(), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.CustomParameters).To(Equal(params.CustomParameters))
+	})
+
+	It("only saves unknown parameters in the private-use range", func() {
+		b := &bytes.Buffer{}
+		utils.BigEndian.WriteUint16(b, 0x42)
+		utils.BigEndian.WriteUint16(b, 3)
+		b.Write([]byte("foo"))
+		utils.BigEndian.WriteUint16(b, 0xff42)
+		utils.BigEndian.WriteUint16(b, 3)
+		b.Write([]byte("bar"))
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.CustomParameters).To(Equal(map[uint16][]byte{0xff42: []byte("bar")}))
+	})
+
 	It("rejects duplicate parameters", func() {
 		b := &bytes.Buffer{}
 		// write first parameter
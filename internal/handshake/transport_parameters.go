@@ -27,6 +27,7 @@ const (
 	initialMaxStreamsBidiParameterID          transportParameterID = 0x8
 	initialMaxStreamsUniParameterID           transportParameterID = 0x9
 	ackDelayExponentParameterID               transportParameterID = 0xa
+	maxAckDelayParameterID                    transportParameterID = 0xb
 	disableMigrationParameterID               transportParameterID = 0xc
 )
 
@@ -38,6 +39,7 @@ type TransportParameters struct {
 	InitialMaxData                 protocol.ByteCount
 
 	AckDelayExponent uint8
+	MaxAckDelay      time.Duration
 
 	MaxPacketSize protocol.ByteCount
 
@@ -49,6 +51,9 @@ type TransportParameters struct {
 
 	StatelessResetToken  *[16]byte
 	OriginalConnectionID protocol.ConnectionID
+
+	// CustomParameters are transport parameters in the private-use range
+	CustomParameters map[uint16][]byte
 }
 
 // Unmarshal the transport parameters
@@ -65,6 +70,7 @@ func (p *TransportParameters) Unmarshal(data []byte, sentBy protocol.Perspective
 	var parameterIDs []transportParameterID
 
 	var readAckDelayExponent bool
+	var readMaxAckDelay bool
 
 	r := bytes.NewReader(data[2:])
 	for r.Len() >= 4 {
@@ -73,6 +79,11 @@ func (p *TransportParameters) Unmarshal(data []byte, sentBy protocol.Perspective
 		paramLen, _ := utils.BigEndian.ReadUint16(r)
 		parameterIDs = append(parameterIDs, paramID)
 		switch paramID {
+		case maxAckDelayParameterID:
+			readMaxAckDelay = true
+			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
+				return err
+			}
 		case ackDelayExponentParameterID:
 			readAckDelayExponent = true
 			fallthrough
@@ -113,7 +124,16 @@ func (p *TransportParameters) Unmarshal(data []byte, sentBy protocol.Perspective
 				}
 				p.OriginalConnectionID, _ = protocol.ReadConnectionID(r, int(paramLen))
 			default:
-				r.Seek(int64(paramLen), io.SeekCurrent)
+				if paramID < protocol.MinPrivateUseTransportParameterID {
+					r.Seek(int64(paramLen), io.SeekCurrent)
+					break
+				}
+				val := make([]byte, paramLen)
+				r.Read(val)
+				if p.CustomParameters == nil {
+					p.CustomParameters = make(map[uint16][]byte)
+				}
+				p.CustomParameters[uint16(paramID)] = val
 			}
 		}
 	}
@@ -121,6 +141,9 @@ func (p *TransportParameters) Unmarshal(data []byte, sentBy protocol.Perspective
 	if !readAckDelayExponent {
 		p.AckDelayExponent = protocol.DefaultAckDelayExponent
 	}
+	if !readMaxAckDelay {
+		p.MaxAckDelay = protocol.DefaultMaxAckDelay
+	}
 
 	// check that every transport parameter was sent at most once
 	sort.Slice(parameterIDs, func(i, j int) bool { return parameterIDs[i] < parameterIDs[j] })
@@ -174,6 +197,12 @@ func (p *TransportParameters) readNumericTransportParameter(
 			return fmt.Errorf("invalid value for ack_delay_exponent: %d (maximum %d)", val, protocol.MaxAckDelayExponent)
 		}
 		p.AckDelayExponent = uint8(val)
+	case maxAckDelayParameterID:
+		maxAckDelay := time.Duration(val) * time.Millisecond
+		if maxAckDelay > protocol.MaxMaxAckDelay {
+			return fmt.Errorf("invalid value for max_ack_delay: %dms (maximum %dms)", val, protocol.MaxMaxAckDelay/time.Millisecond)
+		}
+		p.MaxAckDelay = maxAckDelay
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -225,6 +254,14 @@ func (p *TransportParameters) Marshal() []byte {
 		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(uint64(p.AckDelayExponent))))
 		utils.WriteVarInt(b, uint64(p.AckDelayExponent))
 	}
+	// max_ack_delay
+	// Only send it if is different from the default value.
+	if p.MaxAckDelay != protocol.DefaultMaxAckDelay {
+		maxAckDelay := uint64(p.MaxAckDelay / time.Millisecond)
+		utils.BigEndian.WriteUint16(b, uint16(maxAckDelayParameterID))
+		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(maxAckDelay)))
+		utils.WriteVarInt(b, maxAckDelay)
+	}
 	// disable_migration
 	if p.DisableMigration {
 		utils.BigEndian.WriteUint16(b, uint16(disableMigrationParameterID))
@@ -241,6 +278,18 @@ func (p *TransportParameters) Marshal() []byte {
 		utils.BigEndian.WriteUint16(b, uint16(p.OriginalConnectionID.Len()))
 		b.Write(p.OriginalConnectionID.Bytes())
 	}
+	// custom transport parameters, sorted, such that the encoding is deterministic
+	customIDs := make([]uint16, 0, len(p.CustomParameters))
+	for id := range p.CustomParameters {
+		customIDs = append(customIDs, id)
+	}
+	sort.Slice(customIDs, func(i, j int) bool { return customIDs[i] < customIDs[j] })
+	for _, id := range customIDs {
+		val := p.CustomParameters[id]
+		utils.BigEndian.WriteUint16(b, id)
+		utils.BigEndian.WriteUint16(b, uint16(len(val)))
+		b.Write(val)
+	}
 
 	data := b.Bytes()
 	binary.BigEndian.PutUint16(data[:2], uint16(b.Len()-2))
@@ -249,8 +298,8 @@ func (p *TransportParameters) Marshal() []byte {
 
 // String returns a string representation, intended for logging.
 func (p *TransportParameters) String() string {
-	logString := "&handshake.TransportParameters{OriginalConnectionID: %s, InitialMaxStreamDataBidiLocal: %#x, InitialMaxStreamDataBidiRemote: %#x, InitialMaxStreamDataUni: %#x, InitialMaxData: %#x, MaxBidiStreams: %d, MaxUniStreams: %d, IdleTimeout: %s, AckDelayExponent: %d"
-	logParams := []interface{}{p.OriginalConnectionID, p.InitialMaxStreamDataBidiLocal, p.InitialMaxStreamDataBidiRemote, p.InitialMaxStreamDataUni, p.InitialMaxData, p.MaxBidiStreams, p.MaxUniStreams, p.IdleTimeout, p.AckDelayExponent}
+	logString := "&handshake.TransportParameters{OriginalConnectionID: %s, InitialMaxStreamDataBidiLocal: %#x, InitialMaxStreamDataBidiRemote: %#x, InitialMaxStreamDataUni: %#x, InitialMaxData: %#x, MaxBidiStreams: %d, MaxUniStreams: %d, IdleTimeout: %s, AckDelayExponent: %d, MaxAckDelay: %s"
+	logParams := []interface{}{p.OriginalConnectionID, p.InitialMaxStreamDataBidiLocal, p.InitialMaxStreamDataBidiRemote, p.InitialMaxStreamDataUni, p.InitialMaxData, p.MaxBidiStreams, p.MaxUniStreams, p.IdleTimeout, p.AckDelayExponent, p.MaxAckDelay}
 	if p.StatelessResetToken != nil { // the client never sends a stateless reset token
 		logString += ", StatelessResetToken: %#x"
 		logParams = append(logParams, *p.StatelessResetToken)
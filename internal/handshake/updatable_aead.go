@@ -0,0 +1,257 @@
+package handshake
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qtls"
+)
+
+// keyUpdateLabel is the label used to derive the next generation of 1-RTT secrets.
+// See draft-ietf-quic-tls-19, section 6.
+const keyUpdateLabel = "traffic upd"
+
+// cipherSuite is implemented by qtls.CipherSuite.
+type cipherSuite interface {
+	Hash() crypto.Hash
+	KeyLen() int
+	IVLen() int
+	AEAD(key, fixedNonce []byte) cipher.AEAD
+}
+
+func createAEAD(suite cipherSuite, trafficSecret []byte) cipher.AEAD {
+	key := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic key", suite.KeyLen())
+	iv := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic iv", suite.IVLen())
+	return suite.AEAD(key, iv)
+}
+
+func createHeaderProtector(suite cipherSuite, trafficSecret []byte) cipher.Block {
+	hpKey := qtls.HkdfExpandLabel(suite.Hash(), trafficSecret, []byte{}, "quic hp", suite.KeyLen())
+	hp, err := aes.NewCipher(hpKey)
+	if err != nil {
+		panic(fmt.Sprintf("error creating new AES cipher: %s", err))
+	}
+	return hp
+}
+
+// The updatableAEAD seals and opens 1-RTT packets.
+// It initiates a key update after keyUpdateInterval packets or keyUpdateBytes bytes were sent with the current keys,
+// and follows key updates initiated by the peer.
+// The header protection keys are not changed by a key update.
+type updatableAEAD struct {
+	suite cipherSuite
+
+	keyPhase          uint64 // the number of key updates
+	keyUpdateInterval uint64 // 0 means that we never initiate a key update based on the number of packets
+	keyUpdateBytes    uint64 // 0 means that we never initiate a key update based on the number of bytes
+
+	// A key update may only be initiated after the handshake was confirmed.
+	handshakeConfirmed bool
+
+	rcvdWithCurrentKey      bool
+	firstRcvdWithCurrentKey protocol.PacketNumber
+
+	numSentWithCurrentKey   uint64
+	bytesSentWithCurrentKey uint64
+	firstSentWithCurrentKey protocol.PacketNumber
+	// A key update may only be initiated after a packet sent with the current keys was acknowledged.
+	ackedWithCurrentKey bool
+
+	rcvAEAD  cipher.AEAD
+	sendAEAD cipher.AEAD
+	// The keys of the previous key phase are kept until the next key update,
+	// so that reordered packets can still be decrypted.
+	prevRcvAEAD cipher.AEAD
+
+	nextRcvAEAD           cipher.AEAD
+	nextSendAEAD          cipher.AEAD
+	nextRcvTrafficSecret  []byte
+	nextSendTrafficSecret []byte
+
+	hpDecrypter cipher.Block
+	hpEncrypter cipher.Block
+
+	logger utils.Logger
+
+	// use separate slices for sealing and opening to avoid allocations
+	openNonceBuf []byte
+	sealNonceBuf []byte
+	openHPMask   []byte
+	sealHPMask   []byte
+}
+
+var _ ShortHeaderOpener = &updatableAEAD{}
+var _ ShortHeaderSealer = &updatableAEAD{}
+
+func newUpdatableAEAD(keyUpdateInterval, keyUpdateBytes uint64, logger utils.Logger) *updatableAEAD {
+	return &updatableAEAD{
+		keyUpdateInterval: keyUpdateInterval,
+		keyUpdateBytes:    keyUpdateBytes,
+		logger:            logger,
+	}
+}
+
+func (a *updatableAEAD) getNextTrafficSecret(hash crypto.Hash, ts []byte) []byte {
+	return qtls.HkdfExpandLabel(hash, ts, []byte{}, keyUpdateLabel, hash.Size())
+}
+
+// SetReadKey is called when the 1-RTT read keys are derived during the handshake.
+func (a *updatableAEAD) SetReadKey(suite cipherSuite, trafficSecret []byte) {
+	a.suite = suite
+	a.rcvAEAD = createAEAD(suite, trafficSecret)
+	a.hpDecrypter = createHeaderProtector(suite, trafficSecret)
+	a.openNonceBuf = make([]byte, a.rcvAEAD.NonceSize())
+	a.openHPMask = make([]byte, a.hpDecrypter.BlockSize())
+
+	a.nextRcvTrafficSecret = a.getNextTrafficSecret(suite.Hash(), trafficSecret)
+	a.nextRcvAEAD = createAEAD(suite, a.nextRcvTrafficSecret)
+}
+
+// SetWriteKey is called when the 1-RTT write keys are derived during the handshake.
+func (a *updatableAEAD) SetWriteKey(suite cipherSuite, trafficSecret []byte) {
+	a.suite = suite
+	a.sendAEAD = createAEAD(suite, trafficSecret)
+	a.hpEncrypter = createHeaderProtector(suite, trafficSecret)
+	a.sealNonceBuf = make([]byte, a.sendAEAD.NonceSize())
+	a.sealHPMask = make([]byte, a.hpEncrypter.BlockSize())
+
+	a.nextSendTrafficSecret = a.getNextTrafficSecret(suite.Hash(), trafficSecret)
+	a.nextSendAEAD = createAEAD(suite, a.nextSendTrafficSecret)
+}
+
+func (a *updatableAEAD) rollKeys() {
+	a.keyPhase++
+	a.rcvdWithCurrentKey = false
+	a.numSentWithCurrentKey = 0
+	a.bytesSentWithCurrentKey = 0
+	a.ackedWithCurrentKey = false
+	a.prevRcvAEAD = a.rcvAEAD
+	a.rcvAEAD = a.nextRcvAEAD
+	a.sendAEAD = a.nextSendAEAD
+
+	a.nextRcvTrafficSecret = a.getNextTrafficSecret(a.suite.Hash(), a.nextRcvTrafficSecret)
+	a.nextSendTrafficSecret = a.getNextTrafficSecret(a.suite.Hash(), a.nextSendTrafficSecret)
+	a.nextRcvAEAD = createAEAD(a.suite, a.nextRcvTrafficSecret)
+	a.nextSendAEAD = createAEAD(a.suite, a.nextSendTrafficSecret)
+}
+
+func (a *updatableAEAD) keyPhaseBit() int {
+	return int(a.keyPhase % 2)
+}
+
+func (a *updatableAEAD) Open(dst, src []byte, pn protocol.PacketNumber, kp int, ad []byte) ([]byte, error) {
+	binary.BigEndian.PutUint64(a.openNonceBuf[len(a.openNonceBuf)-8:], uint64(pn))
+	if kp != a.keyPhaseBit() {
+		// A packet sent before the last key update.
+		// If we initiated the key update, the peer might not have updated its keys yet.
+		if (a.keyPhase > 0 && !a.rcvdWithCurrentKey) || (a.rcvdWithCurrentKey && pn < a.firstRcvdWithCurrentKey) {
+			if a.prevRcvAEAD == nil {
+				return nil, fmt.Errorf("no keys for key phase %d", a.keyPhase-1)
+			}
+			return a.prevRcvAEAD.Open(dst, a.openNonceBuf, src, ad)
+		}
+		// the peer initiated a key update
+		dec, err := a.nextRcvAEAD.Open(dst, a.openNonceBuf, src, ad)
+		if err != nil {
+			return nil, err
+		}
+		// The peer may only update its keys after it received a packet protected with the current keys.
+		if a.numSentWithCurrentKey == 0 {
+			return nil, qerr.Error(qerr.ProtocolViolation, fmt.Sprintf("peer updated keys too quickly (key phase %d)", a.keyPhase+1))
+		}
+		a.rollKeys()
+		a.rcvdWithCurrentKey = true
+		a.firstRcvdWithCurrentKey = pn
+		a.logger.Debugf("Peer updated keys to key phase %d", a.keyPhase)
+		return dec, nil
+	}
+	// The AEAD we're using here will be the qtls.aeadAESGCM13.
+	// It uses the nonce provided here and XOR it with the IV.
+	dec, err := a.rcvAEAD.Open(dst, a.openNonceBuf, src, ad)
+	if err != nil {
+		return nil, err
+	}
+	if !a.rcvdWithCurrentKey {
+		a.rcvdWithCurrentKey = true
+		a.firstRcvdWithCurrentKey = pn
+	}
+	return dec, nil
+}
+
+func (a *updatableAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byte) []byte {
+	if a.numSentWithCurrentKey == 0 {
+		a.firstSentWithCurrentKey = pn
+	}
+	a.numSentWithCurrentKey++
+	a.bytesSentWithCurrentKey += uint64(len(src))
+	binary.BigEndian.PutUint64(a.sealNonceBuf[len(a.sealNonceBuf)-8:], uint64(pn))
+	return a.sendAEAD.Seal(dst, a.sealNonceBuf, src, ad)
+}
+
+// SetHandshakeConfirmed is called when the handshake is confirmed.
+// Key updates are only initiated after that.
+func (a *updatableAEAD) SetHandshakeConfirmed() {
+	a.handshakeConfirmed = true
+}
+
+// SetLargestAcked is called when an ACK for a 1-RTT packet is received.
+func (a *updatableAEAD) SetLargestAcked(pn protocol.PacketNumber) {
+	if a.numSentWithCurrentKey > 0 && pn >= a.firstSentWithCurrentKey {
+		a.ackedWithCurrentKey = true
+	}
+}
+
+func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
+	if !a.handshakeConfirmed {
+		return false
+	}
+	// Only one key update may be in progress at a time:
+	// wait until the peer acknowledged a packet sent with the current keys.
+	if !a.ackedWithCurrentKey {
+		return false
+	}
+	if a.keyUpdateInterval > 0 && a.numSentWithCurrentKey >= a.keyUpdateInterval {
+		return true
+	}
+	return a.keyUpdateBytes > 0 && a.bytesSentWithCurrentKey >= a.keyUpdateBytes
+}
+
+func (a *updatableAEAD) KeyPhase() int {
+	if a.shouldInitiateKeyUpdate() {
+		a.rollKeys()
+		a.logger.Debugf("Initiating key update to key phase %d", a.keyPhase)
+	}
+	return a.keyPhaseBit()
+}
+
+func (a *updatableAEAD) Overhead() int {
+	return a.sendAEAD.Overhead()
+}
+
+func (a *updatableAEAD) EncryptHeader(sample []byte, firstByte *byte, pnBytes []byte) {
+	if len(sample) != a.hpEncrypter.BlockSize() {
+		panic("invalid sample size")
+	}
+	a.hpEncrypter.Encrypt(a.sealHPMask, sample)
+	*firstByte ^= a.sealHPMask[0] & 0x1f
+	for i := range pnBytes {
+		pnBytes[i] ^= a.sealHPMask[i+1]
+	}
+}
+
+func (a *updatableAEAD) DecryptHeader(sample []byte, firstByte *byte, pnBytes []byte) {
+	if len(sample) != a.hpDecrypter.BlockSize() {
+		panic("invalid sample size")
+	}
+	a.hpDecrypter.Encrypt(a.openHPMask, sample)
+	*firstByte ^= a.openHPMask[0] & 0x1f
+	for i := range pnBytes {
+		pnBytes[i] ^= a.openHPMask[i+1]
+	}
+}
@@ -0,0 +1,292 @@
+package handshake
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qtls"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mockCipherSuite implements cipherSuite for TLS_AES_128_GCM_SHA256
+type mockCipherSuite struct{}
+
+var _ cipherSuite = &mockCipherSuite{}
+
+func (c *mockCipherSuite) Hash() crypto.Hash { return crypto.SHA256 }
+func (c *mockCipherSuite) KeyLen() int       { return 16 }
+func (c *mockCipherSuite) IVLen() int        { return 12 }
+func (c *mockCipherSuite) AEAD(key, fixedNonce []byte) cipher.AEAD {
+	return qtls.AEADAESGCMTLS13(key, fixedNonce)
+}
+
+var _ = Describe("Updatable AEAD", func() {
+	var client, server *updatableAEAD
+
+	getKeys := func(keyUpdateInterval, keyUpdateBytes uint64) (*updatableAEAD, *updatableAEAD) {
+		clientSecret := make([]byte, 32)
+		serverSecret := make([]byte, 32)
+		rand.Read(clientSecret)
+		rand.Read(serverSecret)
+		client := newUpdatableAEAD(keyUpdateInterval, keyUpdateBytes, utils.DefaultLogger)
+		server := newUpdatableAEAD(keyUpdateInterval, keyUpdateBytes, utils.DefaultLogger)
+		client.SetReadKey(&mockCipherSuite{}, serverSecret)
+		client.SetWriteKey(&mockCipherSuite{}, clientSecret)
+		server.SetReadKey(&mockCipherSuite{}, clientSecret)
+		server.SetWriteKey(&mockCipherSuite{}, serverSecret)
+		return client, server
+	}
+
+	var nextPN map[*updatableAEAD]protocol.PacketNumber
+
+	// sendWithoutAck seals a packet and opens it on the receiving side
+	sendWithoutAck := func(sender, receiver *updatableAEAD, msg []byte) ([]byte, error) {
+		pn := nextPN[sender]
+		nextPN[sender]++
+		kp := sender.KeyPhase()
+		ad := []byte{byte(kp)}
+		sealed := sender.Seal(nil, msg, pn, ad)
+		return receiver.Open(nil, sealed, pn, kp, ad)
+	}
+
+	// send seals a packet, opens it on the receiving side, and acknowledges it right away
+	send := func(sender, receiver *updatableAEAD, msg []byte) ([]byte, error) {
+		opened, err := sendWithoutAck(sender, receiver, msg)
+		if err == nil {
+			sender.SetLargestAcked(nextPN[sender] - 1)
+		}
+		return opened, err
+	}
+
+	BeforeEach(func() {
+		nextPN = make(map[*updatableAEAD]protocol.PacketNumber)
+	})
+
+	Context("without key updates", func() {
+		BeforeEach(func() {
+			client, server = getKeys(0, 0)
+			client.SetHandshakeConfirmed()
+			server.SetHandshakeConfirmed()
+		})
+
+		It("seals and opens", func() {
+			msg := []byte("foobar")
+			opened, err := send(client, server, msg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal(msg))
+			opened, err = send(server, client, msg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal(msg))
+		})
+
+		It("fails to open a message if the associated data is not the same", func() {
+			sealed := client.Seal(nil, []byte("foobar"), 42, []byte("aad"))
+			_, err := server.Open(nil, sealed, 42, 0, []byte("other aad"))
+			Expect(err).To(MatchError("cipher: message authentication failed"))
+		})
+
+		It("fails to open a message if the packet number is not the same", func() {
+			sealed := server.Seal(nil, []byte("foobar"), 42, []byte("aad"))
+			_, err := client.Open(nil, sealed, 43, 0, []byte("aad"))
+			Expect(err).To(MatchError("cipher: message authentication failed"))
+		})
+
+		It("encrypts and decrypts the header", func() {
+			var firstByte byte = 0x5b
+			pnBytes := []byte{0xde, 0xad, 0xbe, 0xef}
+			sample := make([]byte, 16)
+			rand.Read(sample)
+			client.EncryptHeader(sample, &firstByte, pnBytes)
+			Expect(firstByte & 0xe0).To(Equal(byte(0x5b & 0xe0)))
+			Expect(pnBytes).ToNot(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+			server.DecryptHeader(sample, &firstByte, pnBytes)
+			Expect(firstByte).To(Equal(byte(0x5b)))
+			Expect(pnBytes).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+		})
+
+		It("never initiates a key update", func() {
+			for i := 0; i < 100; i++ {
+				_, err := send(client, server, []byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = send(server, client, []byte("bar"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(client.keyPhase).To(BeZero())
+			Expect(server.keyPhase).To(BeZero())
+		})
+	})
+
+	Context("key updates", func() {
+		const keyUpdateInterval = 10
+
+		BeforeEach(func() {
+			client, server = getKeys(keyUpdateInterval, 0)
+			// the server doesn't initiate key updates
+			server.keyUpdateInterval = 0
+			client.SetHandshakeConfirmed()
+			server.SetHandshakeConfirmed()
+		})
+
+		It("initiates a key update after the configured number of packets", func() {
+			for i := 0; i < keyUpdateInterval; i++ {
+				_, err := send(client, server, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = send(server, client, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(client.KeyPhase()).To(Equal(1))
+			Expect(client.keyPhase).To(BeEquivalentTo(1))
+			// the server follows the key update
+			msg := []byte("new keys")
+			opened, err := send(client, server, msg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal(msg))
+			Expect(server.keyPhase).To(BeEquivalentTo(1))
+			Expect(server.KeyPhase()).To(Equal(1))
+			opened, err = send(server, client, msg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal(msg))
+		})
+
+		It("keeps updating keys, as long as data is flowing", func() {
+			msg := []byte("foobar")
+			for i := 0; i < 10*keyUpdateInterval; i++ {
+				opened, err := send(client, server, msg)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(opened).To(Equal(msg))
+				opened, err = send(server, client, msg)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(opened).To(Equal(msg))
+			}
+			Expect(client.keyPhase).To(BeNumerically(">=", 9))
+			Expect(server.keyPhase).To(Equal(client.keyPhase))
+		})
+
+		It("doesn't initiate a key update before the handshake is confirmed", func() {
+			client, server = getKeys(keyUpdateInterval, 0)
+			server.keyUpdateInterval = 0
+			server.SetHandshakeConfirmed()
+			for i := 0; i < 3*keyUpdateInterval; i++ {
+				_, err := send(client, server, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = send(server, client, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(client.KeyPhase()).To(BeZero())
+			client.SetHandshakeConfirmed()
+			Expect(client.KeyPhase()).To(Equal(1))
+		})
+
+		It("doesn't initiate a key update before a packet sent with the current keys was acknowledged", func() {
+			for i := 0; i < 3*keyUpdateInterval; i++ {
+				_, err := sendWithoutAck(client, server, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = send(server, client, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(client.KeyPhase()).To(BeZero())
+			lastSentWithOldKeys := nextPN[client] - 1
+			client.SetLargestAcked(lastSentWithOldKeys)
+			Expect(client.KeyPhase()).To(Equal(1))
+			// the next key update is only initiated after a packet sent with the new keys was acknowledged
+			for i := 0; i < 3*keyUpdateInterval; i++ {
+				_, err := sendWithoutAck(client, server, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = send(server, client, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(client.KeyPhase()).To(Equal(1))
+			// an ACK for a packet sent with the previous keys doesn't count
+			client.SetLargestAcked(lastSentWithOldKeys)
+			Expect(client.KeyPhase()).To(Equal(1))
+			client.SetLargestAcked(nextPN[client] - 1)
+			Expect(client.KeyPhase()).To(Equal(0))
+			Expect(client.keyPhase).To(BeEquivalentTo(2))
+		})
+
+		It("initiates a key update after the configured number of bytes", func() {
+			client, server = getKeys(0, 100)
+			server.keyUpdateBytes = 0
+			client.SetHandshakeConfirmed()
+			server.SetHandshakeConfirmed()
+			for i := 0; i < 9; i++ {
+				_, err := send(client, server, make([]byte, 10))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = send(server, client, []byte("ack"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(client.KeyPhase()).To(BeZero())
+			_, err := send(client, server, make([]byte, 10))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(client.KeyPhase()).To(Equal(1))
+			// the server follows the key update
+			msg := []byte("new keys")
+			opened, err := send(client, server, msg)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal(msg))
+			Expect(server.keyPhase).To(BeEquivalentTo(1))
+		})
+
+		It("opens reordered packets sent with the previous keys", func() {
+			for i := 0; i < keyUpdateInterval; i++ {
+				_, err := send(server, client, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = send(client, server, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			// a packet sent by the server before it updated its keys
+			delayedPN := nextPN[server]
+			nextPN[server]++
+			delayed := server.Seal(nil, []byte("delayed"), delayedPN, []byte("ad"))
+			Expect(client.KeyPhase()).To(Equal(1))
+			// the server updates the keys
+			_, err := send(client, server, []byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = send(server, client, []byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			opened, err := client.Open(nil, delayed, delayedPN, 0, []byte("ad"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal([]byte("delayed")))
+		})
+
+		It("opens packets with the previous keys, if the peer didn't update its keys yet", func() {
+			for i := 0; i < keyUpdateInterval; i++ {
+				_, err := send(server, client, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = send(client, server, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(client.KeyPhase()).To(Equal(1))
+			// the server still uses the old keys
+			opened, err := send(server, client, []byte("old keys"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal([]byte("old keys")))
+			Expect(client.keyPhase).To(BeEquivalentTo(1))
+		})
+
+		It("errors when the peer updates keys before receiving a packet with the current keys", func() {
+			for i := 0; i < keyUpdateInterval; i++ {
+				_, err := send(server, client, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = send(client, server, []byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			// the client initiates a key update
+			_, err := send(client, server, []byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(server.keyPhase).To(BeEquivalentTo(1))
+			// the client initiates a second key update, before the server sent any packet with the new keys
+			client.rollKeys()
+			_, err = send(client, server, []byte("foobar"))
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ProtocolViolation))
+			Expect(err.Error()).To(ContainSubstring("peer updated keys too quickly"))
+		})
+	})
+})
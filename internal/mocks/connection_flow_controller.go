@@ -58,6 +58,34 @@ func (mr *MockConnectionFlowControllerMockRecorder) AddBytesSent(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBytesSent", reflect.TypeOf((*MockConnectionFlowController)(nil).AddBytesSent), arg0)
 }
 
+// ApplicationBytesRead mocks base method
+func (m *MockConnectionFlowController) ApplicationBytesRead() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplicationBytesRead")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// ApplicationBytesRead indicates an expected call of ApplicationBytesRead
+func (mr *MockConnectionFlowControllerMockRecorder) ApplicationBytesRead() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplicationBytesRead", reflect.TypeOf((*MockConnectionFlowController)(nil).ApplicationBytesRead))
+}
+
+// ApplicationBytesSent mocks base method
+func (m *MockConnectionFlowController) ApplicationBytesSent() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplicationBytesSent")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// ApplicationBytesSent indicates an expected call of ApplicationBytesSent
+func (mr *MockConnectionFlowControllerMockRecorder) ApplicationBytesSent() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplicationBytesSent", reflect.TypeOf((*MockConnectionFlowController)(nil).ApplicationBytesSent))
+}
+
 // GetWindowUpdate mocks base method
 func (m *MockConnectionFlowController) GetWindowUpdate() protocol.ByteCount {
 	m.ctrl.T.Helper()
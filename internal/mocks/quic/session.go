@@ -9,6 +9,7 @@ import (
 	tls "crypto/tls"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	quic_go "github.com/lucas-clemente/quic-go"
@@ -38,34 +39,76 @@ func (m *MockSession) EXPECT() *MockSessionMockRecorder {
 	return m.recorder
 }
 
+// Abort mocks base method
+func (m *MockSession) Abort(arg0 protocol.ApplicationErrorCode, arg1 error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Abort", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Abort indicates an expected call of Abort
+func (mr *MockSessionMockRecorder) Abort(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abort", reflect.TypeOf((*MockSession)(nil).Abort), arg0, arg1)
+}
+
 // AcceptStream mocks base method
-func (m *MockSession) AcceptStream() (quic_go.Stream, error) {
+func (m *MockSession) AcceptStream(arg0 context.Context) (quic_go.Stream, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AcceptStream")
+	ret := m.ctrl.Call(m, "AcceptStream", arg0)
 	ret0, _ := ret[0].(quic_go.Stream)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AcceptStream indicates an expected call of AcceptStream
-func (mr *MockSessionMockRecorder) AcceptStream() *gomock.Call {
+func (mr *MockSessionMockRecorder) AcceptStream(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptStream", reflect.TypeOf((*MockSession)(nil).AcceptStream))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptStream", reflect.TypeOf((*MockSession)(nil).AcceptStream), arg0)
 }
 
 // AcceptUniStream mocks base method
-func (m *MockSession) AcceptUniStream() (quic_go.ReceiveStream, error) {
+func (m *MockSession) AcceptUniStream(arg0 context.Context) (quic_go.ReceiveStream, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AcceptUniStream")
+	ret := m.ctrl.Call(m, "AcceptUniStream", arg0)
 	ret0, _ := ret[0].(quic_go.ReceiveStream)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AcceptUniStream indicates an expected call of AcceptUniStream
-func (mr *MockSessionMockRecorder) AcceptUniStream() *gomock.Call {
+func (mr *MockSessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockSession)(nil).AcceptUniStream), arg0)
+}
+
+// ApplicationBytesReceived mocks base method
+func (m *MockSession) ApplicationBytesReceived() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplicationBytesReceived")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// ApplicationBytesReceived indicates an expected call of ApplicationBytesReceived
+func (mr *MockSessionMockRecorder) ApplicationBytesReceived() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockSession)(nil).AcceptUniStream))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplicationBytesReceived", reflect.TypeOf((*MockSession)(nil).ApplicationBytesReceived))
+}
+
+// ApplicationBytesSent mocks base method
+func (m *MockSession) ApplicationBytesSent() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplicationBytesSent")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// ApplicationBytesSent indicates an expected call of ApplicationBytesSent
+func (mr *MockSessionMockRecorder) ApplicationBytesSent() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplicationBytesSent", reflect.TypeOf((*MockSession)(nil).ApplicationBytesSent))
 }
 
 // Close mocks base method
@@ -96,6 +139,20 @@ func (mr *MockSessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockSession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CongestionWindow mocks base method
+func (m *MockSession) CongestionWindow() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CongestionWindow")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// CongestionWindow indicates an expected call of CongestionWindow
+func (mr *MockSessionMockRecorder) CongestionWindow() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CongestionWindow", reflect.TypeOf((*MockSession)(nil).CongestionWindow))
+}
+
 // ConnectionState mocks base method
 func (m *MockSession) ConnectionState() tls.ConnectionState {
 	m.ctrl.T.Helper()
@@ -124,6 +181,35 @@ func (mr *MockSessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSession)(nil).Context))
 }
 
+// ExportKeyingMaterial mocks base method
+func (m *MockSession) ExportKeyingMaterial(arg0 string, arg1 []byte, arg2 int) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportKeyingMaterial", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportKeyingMaterial indicates an expected call of ExportKeyingMaterial
+func (mr *MockSessionMockRecorder) ExportKeyingMaterial(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportKeyingMaterial", reflect.TypeOf((*MockSession)(nil).ExportKeyingMaterial), arg0, arg1, arg2)
+}
+
+// HandshakeTimes mocks base method
+func (m *MockSession) HandshakeTimes() quic_go.HandshakeTimes {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeTimes")
+	ret0, _ := ret[0].(quic_go.HandshakeTimes)
+	return ret0
+}
+
+// HandshakeTimes indicates an expected call of HandshakeTimes
+func (mr *MockSessionMockRecorder) HandshakeTimes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeTimes", reflect.TypeOf((*MockSession)(nil).HandshakeTimes))
+}
+
 // LocalAddr mocks base method
 func (m *MockSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -198,6 +284,21 @@ func (mr *MockSessionMockRecorder) OpenUniStreamSync() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockSession)(nil).OpenUniStreamSync))
 }
 
+// Ping mocks base method
+func (m *MockSession) Ping(arg0 context.Context) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ping indicates an expected call of Ping
+func (mr *MockSessionMockRecorder) Ping(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockSession)(nil).Ping), arg0)
+}
+
 // RemoteAddr mocks base method
 func (m *MockSession) RemoteAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -211,3 +312,15 @@ func (mr *MockSessionMockRecorder) RemoteAddr() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockSession)(nil).RemoteAddr))
 }
+
+// SetMaxCongestionWindow mocks base method
+func (m *MockSession) SetMaxCongestionWindow(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxCongestionWindow", arg0)
+}
+
+// SetMaxCongestionWindow indicates an expected call of SetMaxCongestionWindow
+func (mr *MockSessionMockRecorder) SetMaxCongestionWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxCongestionWindow", reflect.TypeOf((*MockSession)(nil).SetMaxCongestionWindow), arg0)
+}
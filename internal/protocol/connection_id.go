@@ -10,8 +10,6 @@ import (
 // A ConnectionID in QUIC
 type ConnectionID []byte
 
-const maxConnectionIDLen = 18
-
 // GenerateConnectionID generates a connection ID using cryptographic random
 func GenerateConnectionID(len int) (ConnectionID, error) {
 	b := make([]byte, len)
@@ -28,7 +26,7 @@ func GenerateConnectionIDForInitial() (ConnectionID, error) {
 	if _, err := rand.Read(r); err != nil {
 		return nil, err
 	}
-	len := MinConnectionIDLenInitial + int(r[0])%(maxConnectionIDLen-MinConnectionIDLenInitial+1)
+	len := MinConnectionIDLenInitial + int(r[0])%(MaxConnectionIDLen-MinConnectionIDLenInitial+1)
 	return GenerateConnectionID(len)
 }
 
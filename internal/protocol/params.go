@@ -73,6 +73,15 @@ const MaxTrackedSentPackets = MaxOutstandingSentPackets * 5 / 4
 // MaxTrackedReceivedAckRanges is the maximum number of ACK ranges tracked
 const MaxTrackedReceivedAckRanges = defaultMaxCongestionWindowPackets
 
+// MinPrivateUseTransportParameterID is the start of the range of transport parameter IDs reserved for private use
+const MinPrivateUseTransportParameterID = 0xff00
+
+// DefaultMaxAckRanges is the default maximum number of ACK ranges sent in an ACK frame
+const DefaultMaxAckRanges = 32
+
+// DefaultNumPTOProbePackets is the default number of probe packets sent when the PTO fires
+const DefaultNumPTOProbePackets = 2
+
 // MaxNonRetransmittableAcks is the maximum number of packets containing an ACK, but no retransmittable frames, that we send in a row
 const MaxNonRetransmittableAcks = 19
 
@@ -103,6 +112,10 @@ const RetiredConnectionIDDeleteTimeout = 5 * time.Second
 // 2. it reduces the head-of-line blocking, when a packet is lost
 const MinStreamFrameSize ByteCount = 128
 
+// MaxCoalescedWriteSize is the maximum number of bytes that are buffered on a stream
+// when coalescing small writes.
+const MaxCoalescedWriteSize = MaxPacketSizeIPv6
+
 // MaxPostHandshakeCryptoFrameSize is the maximum size of CRYPTO frames
 // we send after the handshake completes.
 const MaxPostHandshakeCryptoFrameSize ByteCount = 1000
@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"fmt"
+	"time"
 )
 
 // A PacketNumber in QUIC
@@ -61,6 +62,12 @@ const MinInitialPacketSize = 1200
 // MinStatelessResetSize is the minimum size of a stateless reset packet
 const MinStatelessResetSize = 1 /* first byte */ + 22 /* random bytes */ + 16 /* token */
 
+// MinConnectionIDLen is the minimum length of a non-empty connection ID that can be encoded in a long header.
+const MinConnectionIDLen = 4
+
+// MaxConnectionIDLen is the maximum length of a connection ID that can be encoded in a long header.
+const MaxConnectionIDLen = 18
+
 // MinConnectionIDLenInitial is the minimum length of the destination connection ID on an Initial packet.
 const MinConnectionIDLenInitial = 8
 
@@ -73,3 +80,10 @@ const DefaultAckDelayExponent = 3
 
 // MaxAckDelayExponent is the maximum ack delay exponent
 const MaxAckDelayExponent = 20
+
+// DefaultMaxAckDelay is the default max_ack_delay.
+// It is also the maximum time by which we delay sending ACKs.
+const DefaultMaxAckDelay = 25 * time.Millisecond
+
+// MaxMaxAckDelay is the maximum max_ack_delay
+const MaxMaxAckDelay = (1<<14 - 1) * time.Millisecond
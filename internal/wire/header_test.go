@@ -245,6 +245,43 @@ var _ = Describe("Header Parsing", func() {
 			Expect(hdr.DestConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}))
 		})
 
+		It("parses Long Headers with connection IDs of all valid lengths", func() {
+			lengths := []int{0}
+			for l := protocol.MinConnectionIDLen; l <= protocol.MaxConnectionIDLen; l++ {
+				lengths = append(lengths, l)
+			}
+			for _, destLen := range lengths {
+				for _, srcLen := range lengths {
+					destConnID := make(protocol.ConnectionID, destLen)
+					for i := range destConnID {
+						destConnID[i] = byte(i + 1)
+					}
+					srcConnID := make(protocol.ConnectionID, srcLen)
+					for i := range srcConnID {
+						srcConnID[i] = byte(0xff - i)
+					}
+					buf := &bytes.Buffer{}
+					Expect((&ExtendedHeader{
+						Header: Header{
+							IsLongHeader:     true,
+							Type:             protocol.PacketTypeHandshake,
+							DestConnectionID: destConnID,
+							SrcConnectionID:  srcConnID,
+							Version:          versionIETFFrames,
+							Length:           2,
+						},
+						PacketNumberLen: protocol.PacketNumberLen2,
+					}).Write(buf, versionIETFFrames)).To(Succeed())
+					hdr, _, _, err := ParsePacket(buf.Bytes(), 0)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(hdr.DestConnectionID).To(HaveLen(destLen))
+					Expect(hdr.DestConnectionID.Equal(destConnID)).To(BeTrue())
+					Expect(hdr.SrcConnectionID).To(HaveLen(srcLen))
+					Expect(hdr.SrcConnectionID.Equal(srcConnID)).To(BeTrue())
+				}
+			}
+		})
+
 		It("parses a Long Header with a 2 byte packet number", func() {
 			data := []byte{0xc0 ^ 0x1}
 			data = appendVersion(data, versionIETFFrames) // version number
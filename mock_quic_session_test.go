@@ -9,6 +9,7 @@ import (
 	tls "crypto/tls"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
@@ -37,34 +38,76 @@ func (m *MockQuicSession) EXPECT() *MockQuicSessionMockRecorder {
 	return m.recorder
 }
 
+// Abort mocks base method
+func (m *MockQuicSession) Abort(arg0 protocol.ApplicationErrorCode, arg1 error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Abort", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Abort indicates an expected call of Abort
+func (mr *MockQuicSessionMockRecorder) Abort(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abort", reflect.TypeOf((*MockQuicSession)(nil).Abort), arg0, arg1)
+}
+
 // AcceptStream mocks base method
-func (m *MockQuicSession) AcceptStream() (Stream, error) {
+func (m *MockQuicSession) AcceptStream(arg0 context.Context) (Stream, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AcceptStream")
+	ret := m.ctrl.Call(m, "AcceptStream", arg0)
 	ret0, _ := ret[0].(Stream)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AcceptStream indicates an expected call of AcceptStream
-func (mr *MockQuicSessionMockRecorder) AcceptStream() *gomock.Call {
+func (mr *MockQuicSessionMockRecorder) AcceptStream(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptStream", reflect.TypeOf((*MockQuicSession)(nil).AcceptStream))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptStream", reflect.TypeOf((*MockQuicSession)(nil).AcceptStream), arg0)
 }
 
 // AcceptUniStream mocks base method
-func (m *MockQuicSession) AcceptUniStream() (ReceiveStream, error) {
+func (m *MockQuicSession) AcceptUniStream(arg0 context.Context) (ReceiveStream, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AcceptUniStream")
+	ret := m.ctrl.Call(m, "AcceptUniStream", arg0)
 	ret0, _ := ret[0].(ReceiveStream)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AcceptUniStream indicates an expected call of AcceptUniStream
-func (mr *MockQuicSessionMockRecorder) AcceptUniStream() *gomock.Call {
+func (mr *MockQuicSessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockQuicSession)(nil).AcceptUniStream), arg0)
+}
+
+// ApplicationBytesReceived mocks base method
+func (m *MockQuicSession) ApplicationBytesReceived() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplicationBytesReceived")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// ApplicationBytesReceived indicates an expected call of ApplicationBytesReceived
+func (mr *MockQuicSessionMockRecorder) ApplicationBytesReceived() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockQuicSession)(nil).AcceptUniStream))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplicationBytesReceived", reflect.TypeOf((*MockQuicSession)(nil).ApplicationBytesReceived))
+}
+
+// ApplicationBytesSent mocks base method
+func (m *MockQuicSession) ApplicationBytesSent() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplicationBytesSent")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// ApplicationBytesSent indicates an expected call of ApplicationBytesSent
+func (mr *MockQuicSessionMockRecorder) ApplicationBytesSent() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplicationBytesSent", reflect.TypeOf((*MockQuicSession)(nil).ApplicationBytesSent))
 }
 
 // Close mocks base method
@@ -95,6 +138,20 @@ func (mr *MockQuicSessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockQuicSession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CongestionWindow mocks base method
+func (m *MockQuicSession) CongestionWindow() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CongestionWindow")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// CongestionWindow indicates an expected call of CongestionWindow
+func (mr *MockQuicSessionMockRecorder) CongestionWindow() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CongestionWindow", reflect.TypeOf((*MockQuicSession)(nil).CongestionWindow))
+}
+
 // ConnectionState mocks base method
 func (m *MockQuicSession) ConnectionState() tls.ConnectionState {
 	m.ctrl.T.Helper()
@@ -123,6 +180,21 @@ func (mr *MockQuicSessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockQuicSession)(nil).Context))
 }
 
+// ExportKeyingMaterial mocks base method
+func (m *MockQuicSession) ExportKeyingMaterial(arg0 string, arg1 []byte, arg2 int) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportKeyingMaterial", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportKeyingMaterial indicates an expected call of ExportKeyingMaterial
+func (mr *MockQuicSessionMockRecorder) ExportKeyingMaterial(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportKeyingMaterial", reflect.TypeOf((*MockQuicSession)(nil).ExportKeyingMaterial), arg0, arg1, arg2)
+}
+
 // GetVersion mocks base method
 func (m *MockQuicSession) GetVersion() protocol.VersionNumber {
 	m.ctrl.T.Helper()
@@ -137,6 +209,20 @@ func (mr *MockQuicSessionMockRecorder) GetVersion() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersion", reflect.TypeOf((*MockQuicSession)(nil).GetVersion))
 }
 
+// HandshakeTimes mocks base method
+func (m *MockQuicSession) HandshakeTimes() HandshakeTimes {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeTimes")
+	ret0, _ := ret[0].(HandshakeTimes)
+	return ret0
+}
+
+// HandshakeTimes indicates an expected call of HandshakeTimes
+func (mr *MockQuicSessionMockRecorder) HandshakeTimes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeTimes", reflect.TypeOf((*MockQuicSession)(nil).HandshakeTimes))
+}
+
 // LocalAddr mocks base method
 func (m *MockQuicSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -211,6 +297,21 @@ func (mr *MockQuicSessionMockRecorder) OpenUniStreamSync() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenUniStreamSync))
 }
 
+// Ping mocks base method
+func (m *MockQuicSession) Ping(arg0 context.Context) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ping indicates an expected call of Ping
+func (mr *MockQuicSessionMockRecorder) Ping(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockQuicSession)(nil).Ping), arg0)
+}
+
 // RemoteAddr mocks base method
 func (m *MockQuicSession) RemoteAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -225,6 +326,18 @@ func (mr *MockQuicSessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQuicSession)(nil).RemoteAddr))
 }
 
+// SetMaxCongestionWindow mocks base method
+func (m *MockQuicSession) SetMaxCongestionWindow(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxCongestionWindow", arg0)
+}
+
+// SetMaxCongestionWindow indicates an expected call of SetMaxCongestionWindow
+func (mr *MockQuicSessionMockRecorder) SetMaxCongestionWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxCongestionWindow", reflect.TypeOf((*MockQuicSession)(nil).SetMaxCongestionWindow), arg0)
+}
+
 // closeForRecreating mocks base method
 func (m *MockQuicSession) closeForRecreating() protocol.PacketNumber {
 	m.ctrl.T.Helper()
@@ -29,8 +29,9 @@ type packetHandlerMap struct {
 	resetTokens map[[16]byte] /* stateless reset token */ packetHandler
 	server      unknownPacketHandler
 
-	listening chan struct{} // is closed when listen returns
-	closed    bool
+	listening   chan struct{} // is closed when listen returns
+	closed      bool
+	closeCalled bool // set when Close is called, such that the resulting read error is not logged as an error
 
 	deleteRetiredSessionsAfter time.Duration
 
@@ -128,6 +129,9 @@ func (h *packetHandlerMap) CloseServer() {
 
 // Close the underlying connection and wait until listen() has returned.
 func (h *packetHandlerMap) Close() error {
+	h.mutex.Lock()
+	h.closeCalled = true
+	h.mutex.Unlock()
 	if err := h.conn.Close(); err != nil {
 		return err
 	}
@@ -162,6 +166,7 @@ func (h *packetHandlerMap) close(e error) error {
 
 func (h *packetHandlerMap) listen() {
 	defer close(h.listening)
+	var tempDelay time.Duration // how long to sleep on a temporary read error
 	for {
 		buffer := getPacketBuffer()
 		data := buffer.Slice
@@ -169,9 +174,36 @@ func (h *packetHandlerMap) listen() {
 		// If it does, we only read a truncated packet, which will then end up undecryptable
 		n, addr, err := h.conn.ReadFrom(data)
 		if err != nil {
+			buffer.Release()
+			// Temporary errors don't affect the sessions.
+			// Back off, so we don't spin if the error persists (same as net/http for Accept errors).
+			if nerr, ok := err.(net.Error); ok && nerr.Temporary() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if max := time.Second; tempDelay > max {
+					tempDelay = max
+				}
+				h.logger.Debugf("Temporary error reading from the packet conn: %s. Retrying in %s.", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+			// All other errors are fatal. Destroy all sessions using this connection.
+			h.mutex.RLock()
+			closeCalled := h.closeCalled
+			h.mutex.RUnlock()
+			if closeCalled {
+				// This is the error returned by reading from the conn that we closed ourselves.
+				h.logger.Debugf("Stopped reading from the packet conn: %s. Closing all sessions.", err)
+			} else {
+				h.logger.Errorf("Error reading from the packet conn: %s. Closing all sessions.", err)
+			}
 			h.close(err)
 			return
 		}
+		tempDelay = 0
 		h.handlePacket(addr, buffer, data[:n])
 	}
 }
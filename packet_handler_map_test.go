@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"crypto/rand"
 	"errors"
+	"log"
 	"net"
+	"os"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -15,6 +17,12 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "temporary error" }
+func (temporaryError) Temporary() bool { return true }
+func (temporaryError) Timeout() bool   { return false }
+
 var _ = Describe("Packet Handler Map", func() {
 	var (
 		handler *packetHandlerMap
@@ -22,6 +30,7 @@ var _ = Describe("Packet Handler Map", func() {
 
 		connIDLen         int
 		statelessResetKey []byte
+		logger            utils.Logger
 	)
 
 	getPacketWithLength := func(connID protocol.ConnectionID, length protocol.ByteCount) []byte {
@@ -46,11 +55,12 @@ var _ = Describe("Packet Handler Map", func() {
 	BeforeEach(func() {
 		statelessResetKey = nil
 		connIDLen = 0
+		logger = utils.DefaultLogger
 	})
 
 	JustBeforeEach(func() {
 		conn = newMockPacketConn()
-		handler = newPacketHandlerMap(conn, connIDLen, statelessResetKey, utils.DefaultLogger).(*packetHandlerMap)
+		handler = newPacketHandlerMap(conn, connIDLen, statelessResetKey, logger).(*packetHandlerMap)
 	})
 
 	AfterEach(func() {
@@ -88,6 +98,75 @@ var _ = Describe("Packet Handler Map", func() {
 		handler.close(testErr)
 	})
 
+	Context("read errors", func() {
+		It("destroys all sessions and closes the server when reading from the conn fails", func() {
+			testErr := &net.OpError{Op: "read", Err: errors.New("socket closed externally")}
+			sess1 := NewMockPacketHandler(mockCtrl)
+			sess1.EXPECT().destroy(testErr)
+			sess2 := NewMockPacketHandler(mockCtrl)
+			sess2.EXPECT().destroy(testErr)
+			server := NewMockUnknownPacketHandler(mockCtrl)
+			server.EXPECT().closeWithError(testErr)
+			handler.Add(protocol.ConnectionID{1, 1, 1, 1}, sess1)
+			handler.Add(protocol.ConnectionID{2, 2, 2, 2}, sess2)
+			handler.SetServer(server)
+			conn.readErrs <- testErr
+			Eventually(handler.listening).Should(BeClosed())
+		})
+
+		Context("logging", func() {
+			var logBuf *bytes.Buffer
+
+			BeforeEach(func() {
+				logBuf = &bytes.Buffer{}
+				log.SetOutput(logBuf)
+				logger = utils.DefaultLogger.WithPrefix("test")
+				logger.SetLogLevel(utils.LogLevelError)
+			})
+
+			AfterEach(func() {
+				log.SetOutput(os.Stdout)
+			})
+
+			It("logs fatal read errors", func() {
+				conn.readErrs <- &net.OpError{Op: "read", Err: errors.New("socket closed externally")}
+				Eventually(handler.listening).Should(BeClosed())
+				Expect(logBuf.String()).To(ContainSubstring("Error reading from the packet conn"))
+			})
+
+			It("doesn't log the read error caused by closing", func() {
+				Expect(handler.Close()).To(Succeed())
+				Expect(handler.listening).To(BeClosed())
+				Expect(logBuf.Len()).To(BeZero())
+			})
+		})
+
+		It("doesn't destroy sessions on temporary errors", func() {
+			handledPacket := make(chan struct{})
+			connID := protocol.ConnectionID{1, 2, 3, 4}
+			sess := NewMockPacketHandler(mockCtrl)
+			sess.EXPECT().handlePacket(gomock.Any()).Do(func(*receivedPacket) { close(handledPacket) })
+			handler.Add(connID, sess)
+			for i := 0; i < 3; i++ {
+				conn.readErrs <- &net.OpError{Op: "read", Err: &temporaryError{}}
+			}
+			conn.dataToRead <- getPacket(connID)
+			Eventually(handledPacket).Should(BeClosed())
+			Expect(handler.listening).ToNot(BeClosed())
+		})
+
+		It("backs off when temporary errors persist", func() {
+			start := time.Now()
+			for i := 0; i < 4; i++ {
+				conn.readErrs <- &net.OpError{Op: "read", Err: &temporaryError{}}
+			}
+			Eventually(conn.readErrs).Should(BeEmpty())
+			// 5ms + 10ms + 20ms until the 4th error is read
+			Expect(time.Since(start)).To(BeNumerically(">", 30*time.Millisecond))
+			Expect(handler.listening).ToNot(BeClosed())
+		})
+	})
+
 	Context("handling packets", func() {
 		BeforeEach(func() {
 			connIDLen = 5
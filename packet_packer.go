@@ -17,6 +17,7 @@ import (
 type packer interface {
 	PackPacket() (*packedPacket, error)
 	MaybePackAckPacket() (*packedPacket, error)
+	PackPingPacket() (*packedPacket, error)
 	PackRetransmission(packet *ackhandler.Packet) ([]*packedPacket, error)
 	PackConnectionClose(*wire.ConnectionCloseFrame) (*packedPacket, error)
 
@@ -29,6 +30,9 @@ type packedPacket struct {
 	header *wire.ExtendedHeader
 	raw    []byte
 	frames []wire.Frame
+	// padding is the number of padding bytes added to the packet,
+	// either to reach the minimum size of an Initial packet, or to allow sampling for header protection.
+	padding protocol.ByteCount
 
 	buffer *packetBuffer
 }
@@ -169,6 +173,19 @@ func (p *packetPacker) MaybePackAckPacket() (*packedPacket, error) {
 	return p.writeAndSealPacket(header, frames, encLevel, sealer)
 }
 
+// PackPingPacket packs a packet containing a PING frame, and an ACK frame if one is due.
+// It is used for probe packets: it is ack-eliciting, but kept as small as possible.
+func (p *packetPacker) PackPingPacket() (*packedPacket, error) {
+	encLevel, sealer := p.cryptoSetup.GetSealer()
+	header := p.getHeader(encLevel)
+	var frames []wire.Frame
+	if ack := p.acks.GetAckFrame(encLevel); ack != nil {
+		frames = append(frames, ack)
+	}
+	frames = append(frames, &wire.PingFrame{})
+	return p.writeAndSealPacket(header, frames, encLevel, sealer)
+}
+
 // PackRetransmission packs a retransmission
 // For packets sent after completion of the handshake, it might happen that 2 packets have to be sent.
 // This can happen e.g. when a longer packet number is used in the header.
@@ -396,6 +413,11 @@ func (p *packetPacker) writeAndSealPacket(
 
 	addPaddingForInitial := p.perspective == protocol.PerspectiveClient && header.Type == protocol.PacketTypeInitial
 
+	if s, ok := sealer.(handshake.ShortHeaderSealer); ok && !header.IsLongHeader {
+		// The key phase is part of the associated data, so it has to be set before the header is written.
+		header.KeyPhase = s.KeyPhase()
+	}
+
 	if header.IsLongHeader {
 		if p.perspective == protocol.PerspectiveClient && header.Type == protocol.PacketTypeInitial {
 			header.Token = p.token
@@ -417,6 +439,7 @@ func (p *packetPacker) writeAndSealPacket(
 		return nil, err
 	}
 	payloadOffset := buffer.Len()
+	var padding int
 
 	// write all frames but the last one
 	for _, frame := range frames[:len(frames)-1] {
@@ -436,6 +459,7 @@ func (p *packetPacker) writeAndSealPacket(
 			// Pad the packet such that packet number length + payload length is 4 bytes.
 			// This is needed to enable the peer to get a 16 byte sample for header protection.
 			buffer.Write(bytes.Repeat([]byte{0}, paddingLen))
+			padding += paddingLen
 		}
 	}
 	if err := lastFrame.Write(buffer, p.version); err != nil {
@@ -446,6 +470,7 @@ func (p *packetPacker) writeAndSealPacket(
 		paddingLen := protocol.MinInitialPacketSize - sealer.Overhead() - buffer.Len()
 		if paddingLen > 0 {
 			buffer.Write(bytes.Repeat([]byte{0}, paddingLen))
+			padding += paddingLen
 		}
 	}
 
@@ -469,10 +494,11 @@ func (p *packetPacker) writeAndSealPacket(
 		return nil, errors.New("packetPacker BUG: Peeked and Popped packet numbers do not match")
 	}
 	return &packedPacket{
-		header: header,
-		raw:    raw,
-		frames: frames,
-		buffer: packetBuffer,
+		header:  header,
+		raw:     raw,
+		frames:  frames,
+		padding: protocol.ByteCount(padding),
+		buffer:  packetBuffer,
 	}, nil
 }
 
@@ -188,6 +188,28 @@ var _ = Describe("Packet packer", func() {
 			Expect(p.raw[0:len(hdrRaw)]).To(Equal(hdrRawEncrypted))
 			Expect(p.raw[len(p.raw)-4:]).To(Equal([]byte{0xde, 0xca, 0xfb, 0xad}))
 		})
+
+		It("sets the key phase of short header packets", func() {
+			pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen2)
+			pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337))
+			sealer := mocks.NewMockShortHeaderSealer(mockCtrl)
+			sealer.EXPECT().Overhead().Return(4).AnyTimes()
+			var hdrRaw []byte
+			gomock.InOrder(
+				sealer.EXPECT().KeyPhase().Return(1),
+				sealer.EXPECT().Seal(gomock.Any(), gomock.Any(), protocol.PacketNumber(0x1337), gomock.Any()).DoAndReturn(func(_, src []byte, _ protocol.PacketNumber, aad []byte) []byte {
+					hdrRaw = append([]byte{}, aad...)
+					return append(src, []byte{0xde, 0xca, 0xfb, 0xad}...)
+				}),
+				sealer.EXPECT().EncryptHeader(gomock.Any(), gomock.Any(), gomock.Any()),
+			)
+			sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+			ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
+			p, err := packer.PackPingPacket()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.header.KeyPhase).To(Equal(1))
+			Expect(hdrRaw[0] & 0x4).ToNot(BeZero())
+		})
 	})
 
 	Context("packing packets", func() {
@@ -340,6 +362,30 @@ var _ = Describe("Packet packer", func() {
 				})
 			})
 
+			Context("packing PING packets", func() {
+				It("packs a PING frame", func() {
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT)
+					p, err := packer.PackPingPacket()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(p.frames).To(Equal([]wire.Frame{&wire.PingFrame{}}))
+					Expect(p.IsRetransmittable()).To(BeTrue())
+				})
+
+				It("packs an ACK frame together with the PING frame", func() {
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().GetSealer().Return(protocol.Encryption1RTT, sealer)
+					ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT).Return(ack)
+					p, err := packer.PackPingPacket()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(p.frames).To(Equal([]wire.Frame{ack, &wire.PingFrame{}}))
+				})
+			})
+
 			Context("making ACK packets retransmittable", func() {
 				sendMaxNumNonRetransmittableAcks := func() {
 					for i := 0; i < protocol.MaxNonRetransmittableAcks; i++ {
@@ -768,6 +814,30 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.frames).To(Equal([]wire.Frame{ack}))
 			})
 
+			It("packs a PING packet with an ACK for the Initial encryption level", func() {
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 10, Largest: 20}}}
+				sealingManager.EXPECT().GetSealer().Return(protocol.EncryptionInitial, sealer)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial).Return(ack)
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42))
+				p, err := packer.PackPingPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
+				Expect(p.frames).To(Equal([]wire.Frame{ack, &wire.PingFrame{}}))
+			})
+
+			It("packs a PING packet with an ACK for the Handshake encryption level", func() {
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 10, Largest: 20}}}
+				sealingManager.EXPECT().GetSealer().Return(protocol.EncryptionHandshake, sealer)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionHandshake).Return(ack)
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42))
+				p, err := packer.PackPingPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.EncryptionLevel()).To(Equal(protocol.EncryptionHandshake))
+				Expect(p.frames).To(Equal([]wire.Frame{ack, &wire.PingFrame{}}))
+			})
+
 			It("pads Initial packets to the required minimum packet size", func() {
 				token := []byte("initial token")
 				packer.SetToken(token)
@@ -786,6 +856,10 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet.frames).To(HaveLen(1))
 				cf := packet.frames[0].(*wire.CryptoFrame)
 				Expect(cf.Data).To(Equal([]byte("foobar")))
+				// the padding is added after the frames, in front of the tag that the mock sealer added
+				Expect(packet.padding).To(BeNumerically(">", 1000))
+				paddingEnd := len(packet.raw) - sealer.Overhead()
+				Expect(packet.raw[paddingEnd-int(packet.padding) : paddingEnd]).To(Equal(make([]byte, packet.padding)))
 			})
 
 			It("pads if payload length + packet number length is smaller than 4", func() {
@@ -815,6 +889,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(extHdr.PacketNumberLen).To(Equal(protocol.PacketNumberLen1))
 				Expect(r.Len()).To(Equal(4 - 1 /* packet number length */))
+				Expect(packet.padding).To(BeEquivalentTo(1))
 				// the first byte of the payload should be a PADDING frame...
 				firstPayloadByte, err := r.ReadByte()
 				Expect(err).ToNot(HaveOccurred())
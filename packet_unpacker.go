@@ -36,8 +36,6 @@ func newPacketUnpacker(cs handshake.CryptoSetup, version protocol.VersionNumber)
 }
 
 func (u *packetUnpacker) Unpack(hdr *wire.Header, data []byte) (*unpackedPacket, error) {
-	r := bytes.NewReader(data)
-
 	var encLevel protocol.EncryptionLevel
 	switch hdr.Type {
 	case protocol.PacketTypeInitial:
@@ -50,20 +48,69 @@ func (u *packetUnpacker) Unpack(hdr *wire.Header, data []byte) (*unpackedPacket,
 		}
 		encLevel = protocol.Encryption1RTT
 	}
-	opener, err := u.cs.GetOpener(encLevel)
-	if err != nil {
-		return nil, err
+
+	var extHdr *wire.ExtendedHeader
+	var pn protocol.PacketNumber
+	var decrypted []byte
+	if encLevel == protocol.Encryption1RTT {
+		opener, err := u.cs.Get1RTTOpener()
+		if err != nil {
+			return nil, err
+		}
+		var extHdrLen int
+		extHdr, extHdrLen, pn, err = u.unpackHeader(opener, hdr, data)
+		if err != nil {
+			return nil, err
+		}
+		decrypted, err = opener.Open(data[extHdrLen:extHdrLen], data[extHdrLen:], pn, extHdr.KeyPhase, data[:extHdrLen])
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		opener, err := u.cs.GetOpener(encLevel)
+		if err != nil {
+			return nil, err
+		}
+		var extHdrLen int
+		extHdr, extHdrLen, pn, err = u.unpackHeader(opener, hdr, data)
+		if err != nil {
+			return nil, err
+		}
+		decrypted, err = opener.Open(data[extHdrLen:extHdrLen], data[extHdrLen:], pn, data[:extHdrLen])
+		if err != nil {
+			return nil, err
+		}
 	}
+
+	// Only do this after decrypting, so we are sure the packet is not attacker-controlled
+	u.largestRcvdPacketNumber = utils.MaxPacketNumber(u.largestRcvdPacketNumber, pn)
+
+	return &unpackedPacket{
+		hdr:             extHdr,
+		packetNumber:    pn,
+		encryptionLevel: encLevel,
+		data:            decrypted,
+	}, nil
+}
+
+type headerDecryptor interface {
+	DecryptHeader(sample []byte, firstByte *byte, pnBytes []byte)
+}
+
+// unpackHeader removes the header protection, and parses the extended header.
+// It returns the extended header, its length and the decoded packet number.
+func (u *packetUnpacker) unpackHeader(hd headerDecryptor, hdr *wire.Header, data []byte) (*wire.ExtendedHeader, int, protocol.PacketNumber, error) {
+	r := bytes.NewReader(data)
 	hdrLen := int(hdr.ParsedLen())
 	if len(data) < hdrLen+4+16 {
-		return nil, fmt.Errorf("Packet too small. Expected at least 20 bytes after the header, got %d", len(data)-hdrLen)
+		return nil, 0, 0, fmt.Errorf("Packet too small. Expected at least 20 bytes after the header, got %d", len(data)-hdrLen)
 	}
 	// The packet number can be up to 4 bytes long, but we won't know the length until we decrypt it.
 	// 1. save a copy of the 4 bytes
 	origPNBytes := make([]byte, 4)
 	copy(origPNBytes, data[hdrLen:hdrLen+4])
 	// 2. decrypt the header, assuming a 4 byte packet number
-	opener.DecryptHeader(
+	hd.DecryptHeader(
 		data[hdrLen+4:hdrLen+4+16],
 		&data[0],
 		data[hdrLen:hdrLen+4],
@@ -71,7 +118,7 @@ func (u *packetUnpacker) Unpack(hdr *wire.Header, data []byte) (*unpackedPacket,
 	// 3. parse the header (and learn the actual length of the packet number)
 	extHdr, err := hdr.ParseExtended(r, u.version)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing extended header: %s", err)
+		return nil, 0, 0, fmt.Errorf("error parsing extended header: %s", err)
 	}
 	extHdrLen := hdrLen + int(extHdr.PacketNumberLen)
 	// 4. if the packet number is shorter than 4 bytes, replace the remaining bytes with the copy we saved earlier
@@ -84,19 +131,5 @@ func (u *packetUnpacker) Unpack(hdr *wire.Header, data []byte) (*unpackedPacket,
 		u.largestRcvdPacketNumber,
 		extHdr.PacketNumber,
 	)
-
-	decrypted, err := opener.Open(data[extHdrLen:extHdrLen], data[extHdrLen:], pn, data[:extHdrLen])
-	if err != nil {
-		return nil, err
-	}
-
-	// Only do this after decrypting, so we are sure the packet is not attacker-controlled
-	u.largestRcvdPacketNumber = utils.MaxPacketNumber(u.largestRcvdPacketNumber, pn)
-
-	return &unpackedPacket{
-		hdr:             extHdr,
-		packetNumber:    pn,
-		encryptionLevel: encLevel,
-		data:            decrypted,
-	}, nil
+	return extHdr, extHdrLen, pn, nil
 }
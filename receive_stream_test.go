@@ -7,8 +7,12 @@ import (
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -622,4 +626,70 @@ var _ = Describe("Receive Stream", func() {
 			Expect(str.getWindowUpdate()).To(Equal(protocol.ByteCount(0x100)))
 		})
 	})
+
+	Context("validating the final size", func() {
+		BeforeEach(func() {
+			rttStats := &congestion.RTTStats{}
+			connFC := flowcontrol.NewConnectionFlowController(1000, 1000, func() {}, rttStats, utils.DefaultLogger)
+			fc := flowcontrol.NewStreamFlowController(streamID, connFC, 1000, 1000, 1000, func(protocol.StreamID) {}, rttStats, utils.DefaultLogger)
+			str = newReceiveStream(streamID, mockSender, fc, protocol.VersionWhatever)
+		})
+
+		It("errors when a RESET_STREAM has a final size smaller than the data received", func() {
+			Expect(str.handleStreamFrame(&wire.StreamFrame{
+				StreamID: streamID,
+				Offset:   10,
+				Data:     []byte("foobar"),
+			})).To(Succeed())
+			err := str.handleResetStreamFrame(&wire.ResetStreamFrame{
+				StreamID:   streamID,
+				ByteOffset: 15,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.FinalSizeError))
+		})
+
+		It("errors when receiving data beyond the final size of a RESET_STREAM", func() {
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+				StreamID:   streamID,
+				ByteOffset: 15,
+			})).To(Succeed())
+			err := str.handleStreamFrame(&wire.StreamFrame{
+				StreamID: streamID,
+				Offset:   10,
+				Data:     []byte("foobar"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.FinalSizeError))
+		})
+
+		It("errors when receiving data beyond the final size of a STREAM frame with FIN", func() {
+			Expect(str.handleStreamFrame(&wire.StreamFrame{
+				StreamID: streamID,
+				Data:     []byte("foo"),
+				FinBit:   true,
+			})).To(Succeed())
+			err := str.handleStreamFrame(&wire.StreamFrame{
+				StreamID: streamID,
+				Offset:   3,
+				Data:     []byte("bar"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.FinalSizeError))
+		})
+
+		It("accepts data below the final size of a RESET_STREAM", func() {
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+				StreamID:   streamID,
+				ByteOffset: 16,
+			})).To(Succeed())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{
+				StreamID: streamID,
+				Offset:   10,
+				Data:     []byte("foobar"),
+			})).To(Succeed())
+		})
+	})
 })
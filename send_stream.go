@@ -17,6 +17,7 @@ type sendStreamI interface {
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	hasData() bool
 	popStreamFrame(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool)
+	getWeight() uint8
 	closeForShutdown(error)
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
 }
@@ -42,6 +43,14 @@ type sendStream struct {
 
 	dataForWriting []byte
 
+	// Small writes are coalesced for up to coalescingDelay, unless noDelay is set.
+	coalescingDelay time.Duration
+	noDelay         bool
+	coalescedData   []byte // data that was written, but not yet handed to the framer
+	coalescingTimer *time.Timer
+
+	weight uint8 // set by SetPriority
+
 	writeChan chan struct{}
 	deadline  time.Time
 
@@ -94,7 +103,21 @@ func (s *sendStream) Write(p []byte) (int, error) {
 		return 0, nil
 	}
 
-	s.dataForWriting = p
+	if s.coalescingDelay > 0 && !s.noDelay && len(s.coalescedData)+len(p) <= protocol.MaxCoalescedWriteSize {
+		s.coalescedData = append(s.coalescedData, p...)
+		if s.coalescingTimer == nil {
+			s.coalescingTimer = time.AfterFunc(s.coalescingDelay, s.onCoalescingTimer)
+		}
+		return len(p), nil
+	}
+
+	// Data that was already written (but not sent yet) has be sent before p.
+	s.flushCoalescedData()
+	if s.dataForWriting != nil {
+		s.dataForWriting = append(s.dataForWriting, p...)
+	} else {
+		s.dataForWriting = p
+	}
 
 	var (
 		deadlineTimer  *utils.Timer
@@ -102,11 +125,17 @@ func (s *sendStream) Write(p []byte) (int, error) {
 		notifiedSender bool
 	)
 	for {
-		bytesWritten = len(p) - len(s.dataForWriting)
+		bytesWritten = len(p) - utils.Min(len(p), len(s.dataForWriting))
 		deadline := s.deadline
 		if !deadline.IsZero() {
 			if !time.Now().Before(deadline) {
-				s.dataForWriting = nil
+				// Only drop the part of p that wasn't sent yet.
+				// Coalesced data preceding p was already reported as written.
+				if l := len(s.dataForWriting) - (len(p) - bytesWritten); l > 0 {
+					s.dataForWriting = s.dataForWriting[:l]
+				} else {
+					s.dataForWriting = nil
+				}
 				return bytesWritten, errDeadline
 			}
 			if deadlineTimer == nil {
@@ -227,12 +256,100 @@ func (s *sendStream) getDataForWriting(maxBytes protocol.ByteCount) ([]byte, boo
 	return ret, s.finishedWriting && s.dataForWriting == nil && !s.finSent
 }
 
+// flushCoalescedData hands the coalesced data to the framer.
+// The caller has to call onHasStreamData if it returns true.
+// It must be called with the mutex held.
+func (s *sendStream) flushCoalescedData() bool /* has data to send */ {
+	if s.coalescingTimer != nil {
+		s.coalescingTimer.Stop()
+		s.coalescingTimer = nil
+	}
+	if len(s.coalescedData) == 0 {
+		return false
+	}
+	s.dataForWriting = append(s.dataForWriting, s.coalescedData...)
+	s.coalescedData = s.coalescedData[:0]
+	return true
+}
+
+// stopCoalescing discards the coalesced data.
+// It must be called with the mutex held.
+func (s *sendStream) stopCoalescing() {
+	if s.coalescingTimer != nil {
+		s.coalescingTimer.Stop()
+		s.coalescingTimer = nil
+	}
+	s.coalescedData = nil
+}
+
+func (s *sendStream) onCoalescingTimer() {
+	s.mutex.Lock()
+	var hasData bool
+	if !s.canceledWrite && !s.closedForShutdown {
+		hasData = s.flushCoalescedData()
+	}
+	s.mutex.Unlock()
+
+	if hasData {
+		s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	}
+}
+
+func (s *sendStream) Flush() error {
+	s.mutex.Lock()
+	if s.closeForShutdownErr != nil {
+		s.mutex.Unlock()
+		return s.closeForShutdownErr
+	}
+	if s.canceledWrite {
+		s.mutex.Unlock()
+		return s.cancelWriteErr
+	}
+	hasData := s.flushCoalescedData()
+	s.mutex.Unlock()
+
+	if hasData {
+		s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	}
+	return nil
+}
+
+func (s *sendStream) SetNoDelay(noDelay bool) {
+	s.mutex.Lock()
+	s.noDelay = noDelay
+	var hasData bool
+	if noDelay && !s.canceledWrite && !s.closedForShutdown {
+		hasData = s.flushCoalescedData()
+	}
+	s.mutex.Unlock()
+
+	if hasData {
+		s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	}
+}
+
+func (s *sendStream) SetPriority(weight uint8) {
+	s.mutex.Lock()
+	s.weight = weight
+	s.mutex.Unlock()
+}
+
+func (s *sendStream) getWeight() uint8 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.weight == 0 {
+		return 1
+	}
+	return s.weight
+}
+
 func (s *sendStream) Close() error {
 	s.mutex.Lock()
 	if s.canceledWrite {
 		s.mutex.Unlock()
 		return fmt.Errorf("Close called for canceled stream %d", s.streamID)
 	}
+	s.flushCoalescedData()
 	s.finishedWriting = true
 	s.mutex.Unlock()
 
@@ -258,6 +375,7 @@ func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, wr
 	}
 	s.canceledWrite = true
 	s.cancelWriteErr = writeErr
+	s.stopCoalescing()
 	s.signalWrite()
 	s.sender.queueControlFrame(&wire.ResetStreamFrame{
 		StreamID:   s.streamID,
@@ -318,6 +436,7 @@ func (s *sendStream) closeForShutdown(err error) {
 	s.mutex.Lock()
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
+	s.stopCoalescing()
 	s.mutex.Unlock()
 	s.signalWrite()
 	s.ctxCancel()
@@ -485,6 +485,135 @@ var _ = Describe("Send Stream", func() {
 				Expect(str.Context().Done()).To(BeClosed())
 			})
 		})
+		Context("coalescing writes", func() {
+			BeforeEach(func() {
+				str.coalescingDelay = scaleDuration(50 * time.Millisecond)
+			})
+
+			It("coalesces small writes", func() {
+				hasData := make(chan struct{})
+				mockSender.EXPECT().onHasStreamData(streamID).Do(func(protocol.StreamID) { close(hasData) })
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(9))
+				for _, d := range []string{"foo", "bar", "baz"} {
+					n, err := strWithTimeout.Write([]byte(d))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(Equal(3))
+				}
+				Expect(str.hasData()).To(BeFalse())
+				Eventually(hasData).Should(BeClosed())
+				f, hasMoreData := str.popStreamFrame(1000)
+				Expect(f.Data).To(Equal([]byte("foobarbaz")))
+				Expect(hasMoreData).To(BeFalse())
+			})
+
+			It("sends data immediately when no delay is set", func() {
+				str.SetNoDelay(true)
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999)).Times(2)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3)).Times(2)
+				for _, d := range []string{"foo", "bar"} {
+					done := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						_, err := strWithTimeout.Write([]byte(d))
+						Expect(err).ToNot(HaveOccurred())
+						close(done)
+					}()
+					waitForWrite()
+					f, _ := str.popStreamFrame(1000)
+					Expect(f.Data).To(Equal([]byte(d)))
+					Eventually(done).Should(BeClosed())
+				}
+			})
+
+			It("flushes pending data when no delay is set", func() {
+				_, err := strWithTimeout.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				mockSender.EXPECT().onHasStreamData(streamID)
+				str.SetNoDelay(true)
+				Expect(str.hasData()).To(BeTrue())
+			})
+
+			It("flushes", func() {
+				_, err := strWithTimeout.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				mockSender.EXPECT().onHasStreamData(streamID)
+				Expect(str.Flush()).To(Succeed())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+				f, _ := str.popStreamFrame(1000)
+				Expect(f.Data).To(Equal([]byte("foo")))
+				// make sure the timer doesn't fire
+				Consistently(str.hasData, scaleDuration(100*time.Millisecond)).Should(BeFalse())
+			})
+
+			It("sends coalesced data before a large write", func() {
+				_, err := strWithTimeout.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				mockSender.EXPECT().onHasStreamData(streamID)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999)).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+				data := bytes.Repeat([]byte{'a'}, protocol.MaxCoalescedWriteSize)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					n, err := strWithTimeout.Write(data)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(Equal(len(data)))
+					close(done)
+				}()
+				waitForWrite()
+				var received []byte
+				for len(received) < len(data)+3 {
+					f, _ := str.popStreamFrame(500)
+					Expect(f).ToNot(BeNil())
+					received = append(received, f.Data...)
+				}
+				Expect(received).To(Equal(append([]byte("foo"), data...)))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("sends coalesced data before the FIN", func() {
+				_, err := strWithTimeout.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				mockSender.EXPECT().onHasStreamData(streamID)
+				Expect(str.Close()).To(Succeed())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(9999))
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				f, _ := str.popStreamFrame(1000)
+				Expect(f.Data).To(Equal([]byte("foo")))
+				Expect(f.FinBit).To(BeTrue())
+			})
+
+			It("discards coalesced data when writing is canceled", func() {
+				_, err := strWithTimeout.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				Consistently(str.hasData, scaleDuration(100*time.Millisecond)).Should(BeFalse())
+				Expect(str.Flush()).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+			})
+		})
+
+		Context("priorities", func() {
+			It("has a weight of 1 by default", func() {
+				Expect(str.getWeight()).To(Equal(uint8(1)))
+			})
+
+			It("sets the weight", func() {
+				str.SetPriority(42)
+				Expect(str.getWeight()).To(Equal(uint8(42)))
+			})
+
+			It("treats a weight of 0 as 1", func() {
+				str.SetPriority(42)
+				str.SetPriority(0)
+				Expect(str.getWeight()).To(Equal(uint8(1)))
+			})
+		})
 	})
 
 	Context("handling MAX_STREAM_DATA frames", func() {
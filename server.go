@@ -147,6 +147,23 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (*server,
 			return nil, fmt.Errorf("%s is not a valid QUIC version", v)
 		}
 	}
+	if err := validateTransportParameters(config.TransportParameters); err != nil {
+		return nil, err
+	}
+	if err := validateConnectionIDLength(config.ConnectionIDLength); err != nil {
+		return nil, err
+	}
+	if err := validateDSCP(config.DSCP); err != nil {
+		return nil, err
+	}
+	if err := validateInitialRTT(config.InitialRTT); err != nil {
+		return nil, err
+	}
+	if config.DSCP != 0 {
+		if err := setDSCP(conn, config.DSCP); err != nil {
+			return nil, err
+		}
+	}
 
 	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, config.StatelessResetKey)
 	if err != nil {
@@ -210,6 +227,15 @@ var defaultAcceptCookie = func(clientAddr net.Addr, cookie *Cookie) bool {
 	return sourceAddr == cookie.RemoteAddr
 }
 
+// validateInitialRTT checks that the configured initial RTT is not negative.
+// An initial RTT of 0 is valid, and means that the default initial RTT is used.
+func validateInitialRTT(rtt time.Duration) error {
+	if rtt < 0 {
+		return fmt.Errorf("invalid initial RTT: %s (must be positive)", rtt)
+	}
+	return nil
+}
+
 // populateServerConfig populates fields in the quic.Config with their default values, if none are set
 // it may be called with nil
 func populateServerConfig(config *Config) *Config {
@@ -255,6 +281,14 @@ func populateServerConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxAckRanges := config.MaxAckRanges
+	if maxAckRanges <= 0 {
+		maxAckRanges = protocol.DefaultMaxAckRanges
+	}
+	ptoProbePackets := config.PTOProbePackets
+	if ptoProbePackets <= 0 {
+		ptoProbePackets = protocol.DefaultNumPTOProbePackets
+	}
 	connIDLen := config.ConnectionIDLength
 	if connIDLen == 0 {
 		connIDLen = protocol.DefaultConnectionIDLength
@@ -266,10 +300,20 @@ func populateServerConfig(config *Config) *Config {
 		IdleTimeout:                           idleTimeout,
 		AcceptCookie:                          vsa,
 		KeepAlive:                             config.KeepAlive,
+		WriteCoalescingDelay:                  config.WriteCoalescingDelay,
+		EnableGSO:                             config.EnableGSO,
+		PTOProbePackets:                       ptoProbePackets,
+		PTOProbeWithPing:                      config.PTOProbeWithPing,
+		DSCP:                                  config.DSCP,
+		KeyUpdateInterval:                     config.KeyUpdateInterval,
+		KeyUpdateBytes:                        config.KeyUpdateBytes,
+		InitialRTT:                            config.InitialRTT,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
 		MaxIncomingStreams:                    maxIncomingStreams,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
+		MaxAckRanges:                          maxAckRanges,
+		TransportParameters:                   config.TransportParameters,
 		ConnectionIDLength:                    connIDLen,
 		StatelessResetKey:                     config.StatelessResetKey,
 	}
@@ -446,12 +490,14 @@ func (s *server) createNewSession(
 		MaxBidiStreams:                 uint64(s.config.MaxIncomingStreams),
 		MaxUniStreams:                  uint64(s.config.MaxIncomingUniStreams),
 		AckDelayExponent:               protocol.AckDelayExponent,
+		MaxAckDelay:                    protocol.DefaultMaxAckDelay,
 		DisableMigration:               true,
 		StatelessResetToken:            &token,
 		OriginalConnectionID:           origDestConnID,
+		CustomParameters:               marshalTransportParameters(s.config.TransportParameters),
 	}
 	sess, err := s.newSession(
-		&conn{pconn: s.conn, currentAddr: remoteAddr},
+		newConn(s.conn, remoteAddr, s.config.EnableGSO),
 		s.sessionRunner,
 		clientDestConnID,
 		destConnID,
@@ -62,6 +62,28 @@ var _ = Describe("Server", func() {
 		Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
 	})
 
+	It("errors when the Config contains an invalid transport parameter", func() {
+		_, err := Listen(nil, tlsConf, &Config{TransportParameters: []TransportParameter{{ID: 0x42}}})
+		Expect(err).To(MatchError("transport parameter ID 0x42 is not in the private-use range"))
+	})
+
+	It("errors when the Config contains an invalid connection ID length", func() {
+		_, err := Listen(nil, tlsConf, &Config{ConnectionIDLength: 3})
+		Expect(err).To(MatchError("invalid connection ID length: 3 (must be 0, or between 4 and 18)"))
+		_, err = Listen(nil, tlsConf, &Config{ConnectionIDLength: 19})
+		Expect(err).To(MatchError("invalid connection ID length: 19 (must be 0, or between 4 and 18)"))
+	})
+
+	It("errors when the Config contains an invalid DSCP", func() {
+		_, err := Listen(nil, tlsConf, &Config{DSCP: 64})
+		Expect(err).To(MatchError("invalid DSCP: 64 (maximum 63)"))
+	})
+
+	It("errors when the Config contains a negative initial RTT", func() {
+		_, err := Listen(nil, tlsConf, &Config{InitialRTT: -time.Millisecond})
+		Expect(err).To(MatchError("invalid initial RTT: -1ms (must be positive)"))
+	})
+
 	It("fills in default values if options are not set in the Config", func() {
 		ln, err := Listen(conn, tlsConf, &Config{})
 		Expect(err).ToNot(HaveOccurred())
@@ -71,6 +93,9 @@ var _ = Describe("Server", func() {
 		Expect(server.config.IdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
 		Expect(reflect.ValueOf(server.config.AcceptCookie)).To(Equal(reflect.ValueOf(defaultAcceptCookie)))
 		Expect(server.config.KeepAlive).To(BeFalse())
+		Expect(server.config.MaxAckRanges).To(Equal(protocol.DefaultMaxAckRanges))
+		Expect(server.config.PTOProbePackets).To(Equal(protocol.DefaultNumPTOProbePackets))
+		Expect(server.config.PTOProbeWithPing).To(BeFalse())
 		// stop the listener
 		Expect(ln.Close()).To(Succeed())
 	})
@@ -84,6 +109,10 @@ var _ = Describe("Server", func() {
 			HandshakeTimeout:  1337 * time.Hour,
 			IdleTimeout:       42 * time.Minute,
 			KeepAlive:         true,
+			DSCP:              46,
+			KeyUpdateInterval: 1000,
+			KeyUpdateBytes:    1 << 20,
+			InitialRTT:        42 * time.Millisecond,
 			StatelessResetKey: []byte("foobar"),
 		}
 		ln, err := Listen(conn, tlsConf, &config)
@@ -95,6 +124,10 @@ var _ = Describe("Server", func() {
 		Expect(server.config.IdleTimeout).To(Equal(42 * time.Minute))
 		Expect(reflect.ValueOf(server.config.AcceptCookie)).To(Equal(reflect.ValueOf(acceptCookie)))
 		Expect(server.config.KeepAlive).To(BeTrue())
+		Expect(server.config.DSCP).To(BeEquivalentTo(46))
+		Expect(server.config.KeyUpdateInterval).To(BeEquivalentTo(1000))
+		Expect(server.config.KeyUpdateBytes).To(BeEquivalentTo(1 << 20))
+		Expect(server.config.InitialRTT).To(Equal(42 * time.Millisecond))
 		Expect(server.config.StatelessResetKey).To(Equal([]byte("foobar")))
 		// stop the listener
 		Expect(ln.Close()).To(Succeed())
@@ -319,6 +352,50 @@ var _ = Describe("Server", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("uses the configured connection ID length, and accepts all connection ID lengths chosen by the client", func() {
+			serv.config.ConnectionIDLength = 12
+			serv.config.AcceptCookie = func(_ net.Addr, _ *Cookie) bool { return true }
+			for _, destLen := range []int{protocol.MinConnectionIDLenInitial, 13, protocol.MaxConnectionIDLen} {
+				for _, srcLen := range []int{0, protocol.MinConnectionIDLen, protocol.MaxConnectionIDLen} {
+					destConnID, err := protocol.GenerateConnectionID(destLen)
+					Expect(err).ToNot(HaveOccurred())
+					srcConnID, err := protocol.GenerateConnectionID(srcLen)
+					Expect(err).ToNot(HaveOccurred())
+					hdr := &wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeInitial,
+						SrcConnectionID:  srcConnID,
+						DestConnectionID: destConnID,
+						Version:          protocol.VersionTLS,
+					}
+					p := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+					run := make(chan struct{})
+					serv.newSession = func(
+						_ connection,
+						_ sessionRunner,
+						origConnID protocol.ConnectionID,
+						destConnID protocol.ConnectionID,
+						srcConnID protocol.ConnectionID,
+						_ *Config,
+						_ *tls.Config,
+						_ *handshake.TransportParameters,
+						_ utils.Logger,
+						_ protocol.VersionNumber,
+					) (quicSession, error) {
+						Expect(origConnID).To(Equal(hdr.DestConnectionID))
+						Expect(destConnID.Equal(hdr.SrcConnectionID)).To(BeTrue())
+						Expect(srcConnID).To(HaveLen(12))
+						sess := NewMockQuicSession(mockCtrl)
+						sess.EXPECT().handlePacket(p)
+						sess.EXPECT().run().Do(func() { close(run) })
+						return sess, nil
+					}
+					serv.handlePacket(p)
+					Eventually(run).Should(BeClosed())
+				}
+			}
+		})
+
 		It("rejects new connection attempts if the accept queue is full", func() {
 			serv.config.AcceptCookie = func(_ net.Addr, _ *Cookie) bool { return true }
 			senderAddr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 42}
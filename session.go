@@ -10,6 +10,7 @@ import (
 	"net"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
@@ -38,12 +39,13 @@ type streamManager interface {
 	OpenUniStream() (SendStream, error)
 	OpenStreamSync() (Stream, error)
 	OpenUniStreamSync() (SendStream, error)
-	AcceptStream() (Stream, error)
-	AcceptUniStream() (ReceiveStream, error)
+	AcceptStream(context.Context) (Stream, error)
+	AcceptUniStream(context.Context) (ReceiveStream, error)
 	DeleteStream(protocol.StreamID) error
 	UpdateLimits(*handshake.TransportParameters) error
 	HandleMaxStreamsFrame(*wire.MaxStreamsFrame) error
 	CloseWithError(error)
+	ResetAll(protocol.ApplicationErrorCode)
 }
 
 type cryptoStreamHandler interface {
@@ -51,6 +53,9 @@ type cryptoStreamHandler interface {
 	ChangeConnectionID(protocol.ConnectionID) error
 	io.Closer
 	ConnectionState() tls.ConnectionState
+	ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error)
+	SetHandshakeConfirmed()
+	SetLargest1RTTAcked(protocol.PacketNumber)
 }
 
 type receivedPacket struct {
@@ -71,15 +76,35 @@ func (p *receivedPacket) Clone() *receivedPacket {
 }
 
 type closeError struct {
-	err       error
-	remote    bool
-	sendClose bool
+	err          error
+	remote       bool
+	sendClose    bool
+	resetStreams bool // reset all streams before sending the CONNECTION_CLOSE
 }
 
 var errCloseForRecreating = errors.New("closing session in order to recreate it")
 
+// A pingRequest is an outstanding call to Ping.
+type pingRequest struct {
+	// sent are the packets containing a PING frame that were sent after Ping was called
+	sent    []sentPing
+	rttChan chan time.Duration
+}
+
+type sentPing struct {
+	packetNumber protocol.PacketNumber
+	encLevel     protocol.EncryptionLevel
+	sendTime     time.Time
+}
+
 // A Session is a QUIC session
 type session struct {
+	// The following fields are accessed atomically, and are at the start of the struct to ensure 64 bit alignment.
+	// congestionWindow is the congestion window of the sentPacketHandler, updated by the run loop.
+	congestionWindow uint64
+	// maxCongestionWindow is the limit set by the application, passed to the sentPacketHandler by the run loop.
+	maxCongestionWindow uint64
+
 	sessionRunner sessionRunner
 
 	destConnID     protocol.ConnectionID
@@ -141,6 +166,15 @@ type session struct {
 	// pacingDeadline is the time when the next packet should be sent
 	pacingDeadline time.Time
 
+	handshakeTimesMutex sync.Mutex
+	handshakeTimes      HandshakeTimes
+
+	pingMutex    sync.Mutex
+	pingRequests []*pingRequest
+
+	// closeErr is the error that closed the session, set before the context is cancelled
+	closeErr error
+
 	peerParams *handshake.TransportParameters
 
 	timer *utils.Timer
@@ -178,7 +212,7 @@ var newSession = func(
 		version:               v,
 	}
 	s.preSetup()
-	s.sentPacketHandler = ackhandler.NewSentPacketHandler(0, s.rttStats, s.logger)
+	s.sentPacketHandler = ackhandler.NewSentPacketHandler(0, s.rttStats, s.config.PTOProbePackets, s.logger)
 	s.streamsMap = newStreamsMap(
 		s,
 		s.newFlowController,
@@ -186,6 +220,7 @@ var newSession = func(
 		uint64(s.config.MaxIncomingUniStreams),
 		s.perspective,
 		s.version,
+		s.config.WriteCoalescingDelay,
 	)
 	s.framer = newFramer(s.streamsMap, s.version)
 	initialStream := newCryptoStream()
@@ -200,6 +235,8 @@ var newSession = func(
 		params,
 		s.processTransportParameters,
 		tlsConf,
+		s.config.KeyUpdateInterval,
+		s.config.KeyUpdateBytes,
 		logger,
 	)
 	if err != nil {
@@ -255,7 +292,7 @@ var newClientSession = func(
 		version:               v,
 	}
 	s.preSetup()
-	s.sentPacketHandler = ackhandler.NewSentPacketHandler(initialPacketNumber, s.rttStats, s.logger)
+	s.sentPacketHandler = ackhandler.NewSentPacketHandler(initialPacketNumber, s.rttStats, s.config.PTOProbePackets, s.logger)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
 	oneRTTStream := newPostHandshakeCryptoStream(s.framer)
@@ -268,6 +305,8 @@ var newClientSession = func(
 		params,
 		s.processTransportParameters,
 		tlsConf,
+		s.config.KeyUpdateInterval,
+		s.config.KeyUpdateBytes,
 		logger,
 	)
 	if err != nil {
@@ -284,6 +323,7 @@ var newClientSession = func(
 		uint64(s.config.MaxIncomingUniStreams),
 		s.perspective,
 		s.version,
+		s.config.WriteCoalescingDelay,
 	)
 	s.framer = newFramer(s.streamsMap, s.version)
 	s.packer = newPacketPacker(
@@ -305,7 +345,8 @@ var newClientSession = func(
 func (s *session) preSetup() {
 	s.frameParser = wire.NewFrameParser(s.version)
 	s.rttStats = &congestion.RTTStats{}
-	s.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(s.rttStats, s.logger, s.version)
+	s.rttStats.SetInitialRTT(s.config.InitialRTT)
+	s.receivedPacketHandler = ackhandler.NewReceivedPacketHandler(s.rttStats, s.config.MaxAckRanges, s.logger, s.version)
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.InitialMaxData,
 		protocol.ByteCount(s.config.MaxReceiveConnectionFlowControlWindow),
@@ -326,6 +367,7 @@ func (s *session) postSetup() error {
 	now := time.Now()
 	s.lastPacketReceivedTime = now
 	s.sessionCreationTime = now
+	s.handshakeTimes.Start = now
 
 	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
 	return nil
@@ -354,6 +396,7 @@ func (s *session) run() error {
 
 	var closeErr closeError
 
+	s.updateCongestionWindow()
 runLoop:
 	for {
 		// Close immediately if requested
@@ -423,9 +466,15 @@ runLoop:
 			continue
 		}
 
+		s.sentPacketHandler.SetMaxCongestionWindow(protocol.ByteCount(atomic.LoadUint64(&s.maxCongestionWindow)))
 		if err := s.sendPackets(); err != nil {
 			s.closeLocal(err)
 		}
+		// packets might have been batched, in order to send them in a single system call
+		if err := s.conn.Flush(); err != nil {
+			s.closeLocal(err)
+		}
+		s.updateCongestionWindow()
 	}
 
 	s.handleCloseError(closeErr)
@@ -443,6 +492,107 @@ func (s *session) ConnectionState() tls.ConnectionState {
 	return s.cryptoStreamHandler.ConnectionState()
 }
 
+func (s *session) ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error) {
+	return s.cryptoStreamHandler.ExportKeyingMaterial(label, context, length)
+}
+
+func (s *session) ApplicationBytesSent() uint64 {
+	return uint64(s.connFlowController.ApplicationBytesSent())
+}
+
+func (s *session) ApplicationBytesReceived() uint64 {
+	return uint64(s.connFlowController.ApplicationBytesRead())
+}
+
+func (s *session) CongestionWindow() uint64 {
+	return atomic.LoadUint64(&s.congestionWindow)
+}
+
+func (s *session) SetMaxCongestionWindow(cwnd uint64) {
+	atomic.StoreUint64(&s.maxCongestionWindow, cwnd)
+	s.scheduleSending()
+}
+
+func (s *session) HandshakeTimes() HandshakeTimes {
+	s.handshakeTimesMutex.Lock()
+	defer s.handshakeTimesMutex.Unlock()
+	return s.handshakeTimes
+}
+
+func (s *session) recordHandshakeTime(t *time.Time, now time.Time) {
+	s.handshakeTimesMutex.Lock()
+	*t = now
+	s.handshakeTimesMutex.Unlock()
+}
+
+func (s *session) Ping(ctx context.Context) (time.Duration, error) {
+	req := &pingRequest{rttChan: make(chan time.Duration, 1)}
+	s.pingMutex.Lock()
+	s.pingRequests = append(s.pingRequests, req)
+	s.pingMutex.Unlock()
+	s.queueControlFrame(&wire.PingFrame{})
+
+	select {
+	case rtt := <-req.rttChan:
+		return rtt, nil
+	case <-ctx.Done():
+		s.removePingRequest(req)
+		return 0, ctx.Err()
+	case <-s.ctx.Done():
+		return 0, s.closeErr
+	}
+}
+
+func (s *session) removePingRequest(req *pingRequest) {
+	s.pingMutex.Lock()
+	defer s.pingMutex.Unlock()
+	for i, r := range s.pingRequests {
+		if r == req {
+			s.pingRequests = append(s.pingRequests[:i], s.pingRequests[i+1:]...)
+			return
+		}
+	}
+}
+
+// onPingSent is called when a packet containing a PING frame is sent.
+// If that packet is lost, the PING frame is retransmitted in a new packet,
+// so every packet is remembered until one of them is acknowledged.
+func (s *session) onPingSent(pn protocol.PacketNumber, encLevel protocol.EncryptionLevel, sendTime time.Time) {
+	s.pingMutex.Lock()
+	defer s.pingMutex.Unlock()
+	for _, req := range s.pingRequests {
+		req.sent = append(req.sent, sentPing{packetNumber: pn, encLevel: encLevel, sendTime: sendTime})
+	}
+}
+
+// completePingRequests completes all Ping calls for which a packet containing a PING frame was acknowledged.
+func (s *session) completePingRequests(frame *wire.AckFrame, encLevel protocol.EncryptionLevel, rcvTime time.Time) {
+	s.pingMutex.Lock()
+	defer s.pingMutex.Unlock()
+	if len(s.pingRequests) == 0 {
+		return
+	}
+	remaining := s.pingRequests[:0]
+	for _, req := range s.pingRequests {
+		completed := false
+		for _, p := range req.sent {
+			if p.encLevel == encLevel && frame.AcksPacket(p.packetNumber) {
+				req.rttChan <- rcvTime.Sub(p.sendTime)
+				completed = true
+				break
+			}
+		}
+		if !completed {
+			remaining = append(remaining, req)
+		}
+	}
+	s.pingRequests = remaining
+}
+
+func (s *session) updateCongestionWindow() {
+	atomic.StoreUint64(&s.congestionWindow, uint64(s.sentPacketHandler.GetCongestionWindow()))
+}
+
 func (s *session) maybeResetTimer() {
 	var deadline time.Time
 	if s.config.KeepAlive && s.handshakeComplete && !s.keepAlivePingSent {
@@ -475,6 +625,11 @@ func (s *session) idleTimeoutStartTime() time.Time {
 func (s *session) handleHandshakeComplete() {
 	s.handshakeComplete = true
 	s.handshakeCompleteChan = nil // prevent this case from ever being selected again
+	now := time.Now()
+	s.recordHandshakeTime(&s.handshakeTimes.Complete, now)
+	if s.perspective == protocol.PerspectiveServer {
+		s.recordHandshakeTime(&s.handshakeTimes.Confirmed, now)
+	}
 	s.sessionRunner.OnHandshakeComplete(s)
 
 	// The client completes the handshake first (after sending the CFIN).
@@ -486,6 +641,7 @@ func (s *session) handleHandshakeComplete() {
 	if s.perspective == protocol.PerspectiveServer {
 		s.queueControlFrame(&wire.PingFrame{})
 		s.sentPacketHandler.SetHandshakeComplete()
+		s.cryptoStreamHandler.SetHandshakeConfirmed()
 	}
 }
 
@@ -542,6 +698,13 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		}
 	}()
 
+	// When using a zero-length connection ID, packets are routed by their 4-tuple.
+	// Packets sent from a different address don't belong to this connection.
+	if s.srcConnID.Len() == 0 && p.remoteAddr.String() != s.conn.RemoteAddr().String() {
+		s.logger.Debugf("Dropping packet from unexpected address %s (expected %s)", p.remoteAddr, s.conn.RemoteAddr())
+		return false
+	}
+
 	if hdr.Type == protocol.PacketTypeRetry {
 		return s.handleRetryPacket(p, hdr)
 	}
@@ -553,7 +716,9 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		return false
 	}
 	// drop 0-RTT packets
+	// Packets coalesced with the 0-RTT packet are still processed by handlePacketImpl.
 	if hdr.Type == protocol.PacketType0RTT {
+		s.logger.Debugf("Dropping 0-RTT packet (%d bytes).", len(p.data))
 		return false
 	}
 
@@ -566,6 +731,11 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 			s.tryQueueingUndecryptablePacket(p)
 			return false
 		}
+		if qErr, ok := err.(*qerr.QuicError); ok {
+			// The packet was decrypted, but the peer violated the key update rules.
+			s.closeLocal(qErr)
+			return false
+		}
 		// This might be a packet injected by an attacker.
 		// Drop it.
 		s.logger.Debugf("Dropping packet that could not be unpacked. Unpack error: %s", err)
@@ -636,6 +806,9 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 		s.packer.ChangeDestConnectionID(s.destConnID)
 	}
 
+	if !s.receivedFirstPacket {
+		s.recordHandshakeTime(&s.handshakeTimes.FirstResponse, rcvTime)
+	}
 	s.receivedFirstPacket = true
 	s.lastPacketReceivedTime = rcvTime
 	s.firstRetransmittablePacketAfterIdleSentTime = time.Time{}
@@ -646,7 +819,9 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 	if s.perspective == protocol.PerspectiveClient {
 		if !s.receivedFirstForwardSecurePacket && packet.encryptionLevel == protocol.Encryption1RTT {
 			s.receivedFirstForwardSecurePacket = true
+			s.recordHandshakeTime(&s.handshakeTimes.Confirmed, rcvTime)
 			s.sentPacketHandler.SetHandshakeComplete()
+			s.cryptoStreamHandler.SetHandshakeConfirmed()
 		}
 	}
 
@@ -744,6 +919,10 @@ func (s *session) handlePacketAfterClosed(p *receivedPacket) {
 	s.logger.Debugf("Received %d packets after sending CONNECTION_CLOSE. Retransmitting.", s.packetsReceivedAfterClose)
 	if err := s.conn.Write(s.connectionClosePacket.raw); err != nil {
 		s.logger.Debugf("Error retransmitting CONNECTION_CLOSE: %s", err)
+		return
+	}
+	if err := s.conn.Flush(); err != nil {
+		s.logger.Debugf("Error retransmitting CONNECTION_CLOSE: %s", err)
 	}
 }
 
@@ -778,6 +957,9 @@ func (s *session) handleStreamFrame(frame *wire.StreamFrame, encLevel protocol.E
 
 func (s *session) handleMaxDataFrame(frame *wire.MaxDataFrame) {
 	s.connFlowController.UpdateSendWindow(frame.ByteOffset)
+	// Streams that were blocked by connection-level flow control are still queued in the framer.
+	// Make sure they're given a chance to send right away.
+	s.scheduleSending()
 }
 
 func (s *session) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) error {
@@ -830,8 +1012,10 @@ func (s *session) handleAckFrame(frame *wire.AckFrame, pn protocol.PacketNumber,
 	if err := s.sentPacketHandler.ReceivedAck(frame, pn, encLevel, s.lastPacketReceivedTime); err != nil {
 		return err
 	}
+	s.completePingRequests(frame, encLevel, s.lastPacketReceivedTime)
 	if encLevel == protocol.Encryption1RTT {
 		s.receivedPacketHandler.IgnoreBelow(s.sentPacketHandler.GetLowestPacketNotConfirmedAcked())
+		s.cryptoStreamHandler.SetLargest1RTTAcked(frame.LargestAcked())
 	}
 	return nil
 }
@@ -892,6 +1076,18 @@ func (s *session) CloseWithError(code protocol.ApplicationErrorCode, e error) er
 	return nil
 }
 
+// Abort resets all streams and closes the session with the error code
+func (s *session) Abort(code protocol.ApplicationErrorCode, e error) error {
+	s.closeOnce.Do(func() {
+		quicErr := qerr.Error(qerr.ErrorCode(code), e.Error())
+		s.logger.Errorf("Aborting session with error: %s", quicErr)
+		s.sessionRunner.Retire(s.srcConnID)
+		s.closeChan <- closeError{err: quicErr, sendClose: true, remote: false, resetStreams: true}
+	})
+	<-s.ctx.Done()
+	return nil
+}
+
 func (s *session) handleCloseError(closeErr closeError) {
 	if closeErr.err == nil {
 		closeErr.err = qerr.NoError
@@ -903,6 +1099,10 @@ func (s *session) handleCloseError(closeErr closeError) {
 		quicErr = qerr.ToQuicError(closeErr.err)
 	}
 
+	s.closeErr = quicErr
+	if closeErr.resetStreams {
+		s.resetStreams(protocol.ApplicationErrorCode(quicErr.ErrorCode))
+	}
 	s.streamsMap.CloseWithError(quicErr)
 
 	if !closeErr.sendClose {
@@ -917,6 +1117,14 @@ func (s *session) handleCloseError(closeErr closeError) {
 	}
 }
 
+// resetStreams resets all streams, and sends the resulting RESET_STREAM and STOP_SENDING frames.
+func (s *session) resetStreams(code protocol.ApplicationErrorCode) {
+	s.streamsMap.ResetAll(code)
+	if err := s.sendPackets(); err != nil {
+		s.logger.Debugf("Error sending packets when resetting all streams: %s", err)
+	}
+}
+
 func (s *session) processTransportParameters(data []byte) {
 	var params *handshake.TransportParameters
 	var err error
@@ -931,6 +1139,10 @@ func (s *session) processTransportParameters(data []byte) {
 		return
 	}
 	s.logger.Debugf("Received Transport Parameters: %s", params)
+	if err := unmarshalTransportParameters(s.config.TransportParameters, params.CustomParameters); err != nil {
+		s.closeLocal(err)
+		return
+	}
 	s.peerParams = params
 	if err := s.streamsMap.UpdateLimits(params); err != nil {
 		s.closeLocal(err)
@@ -938,6 +1150,7 @@ func (s *session) processTransportParameters(data []byte) {
 	}
 	s.packer.HandleTransportParameters(params)
 	s.frameParser.SetAckDelayExponent(params.AckDelayExponent)
+	s.sentPacketHandler.SetMaxAckDelay(params.MaxAckDelay)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	if params.StatelessResetToken != nil {
 		s.sessionRunner.AddResetToken(*params.StatelessResetToken, s)
@@ -952,7 +1165,7 @@ func (s *session) processTransportParametersForClient(data []byte) (*handshake.T
 
 	// check the Retry token
 	if !params.OriginalConnectionID.Equal(s.origDestConnID) {
-		return nil, fmt.Errorf("expected original_connection_id to equal %s, is %s", s.origDestConnID, params.OriginalConnectionID)
+		return nil, qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected original_connection_id to equal %s, is %s", s.origDestConnID, params.OriginalConnectionID))
 	}
 
 	return params, nil
@@ -1071,6 +1284,16 @@ func (s *session) maybeSendRetransmission() (bool, error) {
 }
 
 func (s *session) sendProbePacket() error {
+	if s.config.PTOProbeWithPing {
+		packet, err := s.packer.PackPingPacket()
+		if err != nil {
+			return err
+		}
+		s.logger.Debugf("Sending a PING as a probe packet.")
+		s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket())
+		return s.sendPackedPacket(packet)
+	}
+
 	p, err := s.sentPacketHandler.DequeueProbePacket()
 	if err != nil {
 		return err
@@ -1116,6 +1339,12 @@ func (s *session) sendPackedPacket(packet *packedPacket) error {
 	if s.firstRetransmittablePacketAfterIdleSentTime.IsZero() && packet.IsRetransmittable() {
 		s.firstRetransmittablePacketAfterIdleSentTime = time.Now()
 	}
+	for _, f := range packet.frames {
+		if _, ok := f.(*wire.PingFrame); ok {
+			s.onPingSent(packet.header.PacketNumber, packet.EncryptionLevel(), time.Now())
+			break
+		}
+	}
 	s.logPacket(packet)
 	return s.conn.Write(packet.raw)
 }
@@ -1135,7 +1364,10 @@ func (s *session) sendConnectionClose(quicErr *qerr.QuicError) error {
 	}
 	s.connectionClosePacket = packet
 	s.logPacket(packet)
-	return s.conn.Write(packet.raw)
+	if err := s.conn.Write(packet.raw); err != nil {
+		return err
+	}
+	return s.conn.Flush()
 }
 
 func (s *session) logPacket(packet *packedPacket) {
@@ -1143,7 +1375,11 @@ func (s *session) logPacket(packet *packedPacket) {
 		// We don't need to allocate the slices for calling the format functions
 		return
 	}
-	s.logger.Debugf("-> Sending packet 0x%x (%d bytes) for connection %s, %s", packet.header.PacketNumber, len(packet.raw), s.srcConnID, packet.EncryptionLevel())
+	if packet.padding > 0 {
+		s.logger.Debugf("-> Sending packet 0x%x (%d bytes, including %d bytes of padding) for connection %s, %s", packet.header.PacketNumber, len(packet.raw), packet.padding, s.srcConnID, packet.EncryptionLevel())
+	} else {
+		s.logger.Debugf("-> Sending packet 0x%x (%d bytes) for connection %s, %s", packet.header.PacketNumber, len(packet.raw), s.srcConnID, packet.EncryptionLevel())
+	}
 	packet.header.Log(s.logger)
 	for _, frame := range packet.frames {
 		wire.LogFrame(s.logger, frame, true)
@@ -1166,12 +1402,12 @@ func (s *session) GetOrOpenStream(id protocol.StreamID) (Stream, error) {
 }
 
 // AcceptStream returns the next stream openend by the peer
-func (s *session) AcceptStream() (Stream, error) {
-	return s.streamsMap.AcceptStream()
+func (s *session) AcceptStream(ctx context.Context) (Stream, error) {
+	return s.streamsMap.AcceptStream(ctx)
 }
 
-func (s *session) AcceptUniStream() (ReceiveStream, error) {
-	return s.streamsMap.AcceptUniStream()
+func (s *session) AcceptUniStream(ctx context.Context) (ReceiveStream, error) {
+	return s.streamsMap.AcceptUniStream(ctx)
 }
 
 // OpenStream opens a stream
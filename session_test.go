@@ -28,6 +28,7 @@ type mockConnection struct {
 	remoteAddr net.Addr
 	localAddr  net.Addr
 	written    chan []byte
+	flushed    int
 }
 
 func newMockConnection() *mockConnection {
@@ -47,6 +48,7 @@ func (m *mockConnection) Write(p []byte) error {
 	}
 	return nil
 }
+func (m *mockConnection) Flush() error                       { m.flushed++; return nil }
 func (m *mockConnection) Read([]byte) (int, net.Addr, error) { panic("not implemented") }
 
 func (m *mockConnection) SetCurrentRemoteAddr(addr net.Addr) {
@@ -105,6 +107,23 @@ var _ = Describe("Session", func() {
 		Eventually(areSessionsRunning).Should(BeFalse())
 	})
 
+	It("uses the initial RTT from the config", func() {
+		pSess, err := newSession(
+			mconn,
+			sessionRunner,
+			protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			protocol.ConnectionID{8, 7, 6, 5, 4, 3, 2, 1},
+			protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+			populateServerConfig(&Config{InitialRTT: 42 * time.Millisecond}),
+			nil, // tls.Config
+			&handshake.TransportParameters{},
+			utils.DefaultLogger,
+			protocol.VersionTLS,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pSess.(*session).rttStats.SmoothedOrInitialRTT()).To(Equal(42 * time.Millisecond))
+	})
+
 	Context("frame handling", func() {
 		Context("handling STREAM frames", func() {
 			It("passes STREAM frames to the stream", func() {
@@ -169,6 +188,7 @@ var _ = Describe("Session", func() {
 				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
 				rph.EXPECT().IgnoreBelow(protocol.PacketNumber(0x42))
 				sess.receivedPacketHandler = rph
+				cryptoSetup.EXPECT().SetLargest1RTTAcked(protocol.PacketNumber(3))
 				Expect(sess.handleAckFrame(ack, 0, protocol.Encryption1RTT)).To(Succeed())
 			})
 		})
@@ -233,6 +253,7 @@ var _ = Describe("Session", func() {
 				offset := protocol.ByteCount(0x800000)
 				connFC.EXPECT().UpdateSendWindow(offset)
 				sess.handleMaxDataFrame(&wire.MaxDataFrame{ByteOffset: offset})
+				Expect(sess.sendingScheduled).To(Receive())
 			})
 
 			It("ignores MAX_STREAM_DATA frames for a closed stream", func() {
@@ -346,8 +367,8 @@ var _ = Describe("Session", func() {
 
 	It("accepts new streams", func() {
 		mstr := NewMockStreamI(mockCtrl)
-		streamManager.EXPECT().AcceptStream().Return(mstr, nil)
-		str, err := sess.AcceptStream()
+		streamManager.EXPECT().AcceptStream(gomock.Any()).Return(mstr, nil)
+		str, err := sess.AcceptStream(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(str).To(Equal(mstr))
 	})
@@ -410,6 +431,41 @@ var _ = Describe("Session", func() {
 			Expect(sess.Context().Done()).To(BeClosed())
 		})
 
+		It("resets all streams before closing, when aborting", func() {
+			testErr := errors.New("kill switch")
+			gomock.InOrder(
+				streamManager.EXPECT().ResetAll(protocol.ApplicationErrorCode(0x1337)),
+				packer.EXPECT().PackPacket().Return(&packedPacket{
+					header: &wire.ExtendedHeader{PacketNumber: 1},
+					raw:    []byte("reset streams"),
+					frames: []wire.Frame{&wire.ResetStreamFrame{StreamID: 4, ErrorCode: 0x1337}},
+					buffer: getPacketBuffer(),
+				}, nil),
+				streamManager.EXPECT().CloseWithError(qerr.Error(0x1337, testErr.Error())),
+				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{raw: []byte("connection close")}, nil),
+			)
+			packer.EXPECT().PackPacket().AnyTimes() // no more packets to send
+			sessionRunner.EXPECT().Retire(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			Expect(sess.Abort(0x1337, testErr)).To(Succeed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.Context().Done()).To(BeClosed())
+			Expect(mconn.written).To(HaveLen(2))
+			Expect(mconn.written).To(Receive(ContainSubstring("reset streams")))
+			Expect(mconn.written).To(Receive(ContainSubstring("connection close")))
+		})
+
+		It("only closes once, when aborting after closing", func() {
+			streamManager.EXPECT().CloseWithError(qerr.Error(qerr.NoError, ""))
+			sessionRunner.EXPECT().Retire(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			Expect(sess.Close()).To(Succeed())
+			Expect(sess.Abort(0x1337, errors.New("kill switch"))).To(Succeed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(mconn.written).To(HaveLen(1))
+		})
+
 		It("closes the session in order to recreate it", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			sessionRunner.EXPECT().Remove(gomock.Any())
@@ -558,6 +614,28 @@ var _ = Describe("Session", func() {
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
+		It("closes the session when unpacking fails with a protocol violation", func() {
+			testErr := qerr.Error(qerr.ProtocolViolation, "peer updated keys too quickly (key phase 2)")
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).Return(nil, testErr)
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+				err := sess.run()
+				Expect(err).To(MatchError(testErr))
+				close(done)
+			}()
+			sessionRunner.EXPECT().Retire(gomock.Any())
+			sess.handlePacket(getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: sess.srcConnID},
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, nil))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("rejects packets with empty payload", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).Return(&unpackedPacket{
 				hdr:  &wire.ExtendedHeader{},
@@ -587,6 +665,7 @@ var _ = Describe("Session", func() {
 				Header: wire.Header{
 					IsLongHeader:     true,
 					Type:             protocol.PacketType0RTT,
+					Version:          sess.version,
 					DestConnectionID: sess.srcConnID,
 				},
 				PacketNumberLen: protocol.PacketNumberLen2,
@@ -594,6 +673,24 @@ var _ = Describe("Session", func() {
 			Expect(sess.handlePacketImpl(getPacket(hdr, nil))).To(BeFalse())
 		})
 
+		It("neither unpacks nor buffers 0-RTT packets, no matter how many are received", func() {
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketType0RTT,
+					Version:          sess.version,
+					DestConnectionID: sess.srcConnID,
+					Length:           2 + 100,
+				},
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}
+			for i := 0; i < 10*protocol.MaxUndecryptablePackets; i++ {
+				hdr.PacketNumber = protocol.PacketNumber(i)
+				Expect(sess.handlePacketImpl(getPacket(hdr, make([]byte, 100)))).To(BeFalse())
+			}
+			Expect(sess.undecryptablePackets).To(BeEmpty())
+		})
+
 		It("ignores packets with a different source connection ID", func() {
 			hdr1 := &wire.ExtendedHeader{
 				Header: wire.Header{
@@ -672,11 +769,11 @@ var _ = Describe("Session", func() {
 		})
 
 		Context("coalesced packets", func() {
-			getPacketWithLength := func(connID protocol.ConnectionID, length protocol.ByteCount) (int /* header length */, *receivedPacket) {
+			getPacketWithType := func(typ protocol.PacketType, connID protocol.ConnectionID, length protocol.ByteCount) (int /* header length */, *receivedPacket) {
 				hdr := &wire.ExtendedHeader{
 					Header: wire.Header{
 						IsLongHeader:     true,
-						Type:             protocol.PacketTypeHandshake,
+						Type:             typ,
 						DestConnectionID: connID,
 						SrcConnectionID:  sess.destConnID,
 						Version:          protocol.VersionTLS,
@@ -691,6 +788,10 @@ var _ = Describe("Session", func() {
 				return int(hdrLen), packet
 			}
 
+			getPacketWithLength := func(connID protocol.ConnectionID, length protocol.ByteCount) (int /* header length */, *receivedPacket) {
+				return getPacketWithType(protocol.PacketTypeHandshake, connID, length)
+			}
+
 			It("cuts packets to the right length", func() {
 				hdrLen, packet := getPacketWithLength(sess.srcConnID, 456)
 				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).DoAndReturn(func(_ *wire.Header, data []byte) (*unpackedPacket, error) {
@@ -744,6 +845,49 @@ var _ = Describe("Session", func() {
 				Expect(sess.undecryptablePackets[0].data).To(HaveLen(hdrLen1 + 456 - 3))
 			})
 
+			It("handles an Initial packet coalesced with a 0-RTT packet", func() {
+				hdrLen1, packet1 := getPacketWithType(protocol.PacketTypeInitial, sess.srcConnID, 456)
+				_, packet2 := getPacketWithType(protocol.PacketType0RTT, sess.srcConnID, 123)
+				// only EXPECT one call to the unpacker, 0-RTT packets are dropped
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, data []byte) (*unpackedPacket, error) {
+					Expect(hdr.Type).To(Equal(protocol.PacketTypeInitial))
+					Expect(data).To(HaveLen(hdrLen1 + 456 - 3))
+					return &unpackedPacket{
+						encryptionLevel: protocol.EncryptionInitial,
+						data:            []byte{0},
+					}, nil
+				})
+				packet1.data = append(packet1.data, packet2.data...)
+				Expect(sess.handlePacketImpl(packet1)).To(BeTrue())
+				Expect(sess.undecryptablePackets).To(BeEmpty())
+			})
+
+			It("processes packets following a 0-RTT packet in the same datagram", func() {
+				_, packet1 := getPacketWithType(protocol.PacketTypeInitial, sess.srcConnID, 456)
+				_, packet2 := getPacketWithType(protocol.PacketType0RTT, sess.srcConnID, 123)
+				hdrLen3, packet3 := getPacketWithType(protocol.PacketTypeHandshake, sess.srcConnID, 234)
+				gomock.InOrder(
+					unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, _ []byte) (*unpackedPacket, error) {
+						Expect(hdr.Type).To(Equal(protocol.PacketTypeInitial))
+						return &unpackedPacket{
+							encryptionLevel: protocol.EncryptionInitial,
+							data:            []byte{0},
+						}, nil
+					}),
+					unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, data []byte) (*unpackedPacket, error) {
+						Expect(hdr.Type).To(Equal(protocol.PacketTypeHandshake))
+						Expect(data).To(HaveLen(hdrLen3 + 234 - 3))
+						return nil, handshake.ErrOpenerNotYetAvailable
+					}),
+				)
+				packet1.data = append(packet1.data, packet2.data...)
+				packet1.data = append(packet1.data, packet3.data...)
+				Expect(sess.handlePacketImpl(packet1)).To(BeTrue())
+				// the Handshake packet is buffered until the keys are available
+				Expect(sess.undecryptablePackets).To(HaveLen(1))
+				Expect(sess.undecryptablePackets[0].data).To(HaveLen(hdrLen3 + 234 - 3))
+			})
+
 			It("ignores coalesced packet parts if the destination connection IDs don't match", func() {
 				wrongConnID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
 				Expect(sess.srcConnID).ToNot(Equal(wrongConnID))
@@ -887,6 +1031,26 @@ var _ = Describe("Session", func() {
 			Expect(sess.sendPackets()).To(Succeed())
 		})
 
+		It("sends PING-only probe packets", func() {
+			sess.config.PTOProbeWithPing = true
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend()
+			sph.EXPECT().SendMode().Return(ackhandler.SendPTO).Times(2)
+			sph.EXPECT().ShouldSendNumPackets().Return(2)
+			packer.EXPECT().PackPingPacket().Return(getPacket(1), nil)
+			packer.EXPECT().PackPingPacket().Return(getPacket(2), nil)
+			var sentPackets []*ackhandler.Packet
+			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
+				sentPackets = append(sentPackets, p)
+			}).Times(2)
+			sess.sentPacketHandler = sph
+			Expect(sess.sendPackets()).To(Succeed())
+			Expect(sentPackets).To(HaveLen(2))
+			Expect(sentPackets[0].PacketNumber).To(Equal(protocol.PacketNumber(1)))
+			Expect(sentPackets[1].PacketNumber).To(Equal(protocol.PacketNumber(2)))
+			Expect(mconn.written).To(HaveLen(2))
+		})
+
 		It("doesn't send when the SentPacketHandler doesn't allow it", func() {
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().SendMode().Return(ackhandler.SendNone)
@@ -902,6 +1066,8 @@ var _ = Describe("Session", func() {
 				sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
 				sph.EXPECT().GetAlarmTimeout().AnyTimes()
 				sph.EXPECT().DequeuePacketForRetransmission().AnyTimes()
+				sph.EXPECT().GetCongestionWindow().AnyTimes()
+				sph.EXPECT().SetMaxCongestionWindow(gomock.Any()).AnyTimes()
 				sess.sentPacketHandler = sph
 				streamManager.EXPECT().CloseWithError(gomock.Any())
 			})
@@ -1037,11 +1203,57 @@ var _ = Describe("Session", func() {
 			})
 		})
 
+		Context("limiting the congestion window", func() {
+			It("returns the congestion window", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(1337))
+				sess.sentPacketHandler = sph
+				sess.updateCongestionWindow()
+				Expect(sess.CongestionWindow()).To(Equal(uint64(1337)))
+			})
+
+			It("passes the maximum congestion window to the sent packet handler", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().GetAlarmTimeout().AnyTimes()
+				sph.EXPECT().TimeUntilSend().AnyTimes()
+				sph.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(5000)).AnyTimes()
+				sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+				sph.EXPECT().ShouldSendNumPackets().AnyTimes().Return(1)
+				sph.EXPECT().SetMaxCongestionWindow(protocol.ByteCount(0)).AnyTimes()
+				limitSet := make(chan struct{})
+				sph.EXPECT().SetMaxCongestionWindow(protocol.ByteCount(1000)).Do(func(protocol.ByteCount) {
+					close(limitSet)
+				}).MinTimes(1)
+				sess.sentPacketHandler = sph
+				packer.EXPECT().PackPacket().AnyTimes()
+
+				go func() {
+					defer GinkgoRecover()
+					cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+					sess.run()
+				}()
+				Eventually(sess.CongestionWindow).Should(Equal(uint64(5000)))
+				sess.SetMaxCongestionWindow(1000)
+				Eventually(limitSet).Should(BeClosed())
+				// the limit doesn't change the congestion window
+				Expect(sess.CongestionWindow()).To(Equal(uint64(5000)))
+				// make the go routine return
+				sessionRunner.EXPECT().Retire(gomock.Any())
+				streamManager.EXPECT().CloseWithError(gomock.Any())
+				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+				cryptoSetup.EXPECT().Close()
+				sess.Close()
+				Eventually(sess.Context().Done()).Should(BeClosed())
+			})
+		})
+
 		Context("scheduling sending", func() {
 			It("sends when scheduleSending is called", func() {
 				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 				sph.EXPECT().GetAlarmTimeout().AnyTimes()
 				sph.EXPECT().TimeUntilSend().AnyTimes()
+				sph.EXPECT().GetCongestionWindow().AnyTimes()
+				sph.EXPECT().SetMaxCongestionWindow(gomock.Any()).AnyTimes()
 				sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
 				sph.EXPECT().ShouldSendNumPackets().AnyTimes().Return(1)
 				sph.EXPECT().SentPacket(gomock.Any())
@@ -1071,6 +1283,8 @@ var _ = Describe("Session", func() {
 				sph.EXPECT().TimeUntilSend().Return(time.Now())
 				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
 				sph.EXPECT().GetAlarmTimeout().AnyTimes()
+				sph.EXPECT().GetCongestionWindow().AnyTimes()
+				sph.EXPECT().SetMaxCongestionWindow(gomock.Any()).AnyTimes()
 				sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
 				sph.EXPECT().ShouldSendNumPackets().Return(1)
 				sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
@@ -1121,6 +1335,7 @@ var _ = Describe("Session", func() {
 			defer GinkgoRecover()
 			sessionRunner.EXPECT().OnHandshakeComplete(gomock.Any())
 			cryptoSetup.EXPECT().RunHandshake()
+			cryptoSetup.EXPECT().SetHandshakeConfirmed()
 			sess.run()
 		}()
 		Consistently(sess.Context().Done()).ShouldNot(BeClosed())
@@ -1133,6 +1348,35 @@ var _ = Describe("Session", func() {
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	It("records the time when the handshake completes", func() {
+		Expect(sess.HandshakeTimes().Start).ToNot(BeZero())
+		Expect(sess.HandshakeTimes().Complete).To(BeZero())
+		packer.EXPECT().PackPacket().AnyTimes()
+		handshakeCompleted := make(chan struct{})
+		start := time.Now()
+		go func() {
+			defer GinkgoRecover()
+			sessionRunner.EXPECT().OnHandshakeComplete(gomock.Any()).Do(func(Session) { close(handshakeCompleted) })
+			cryptoSetup.EXPECT().RunHandshake()
+			// for the server, the handshake is confirmed as soon as it completes
+			cryptoSetup.EXPECT().SetHandshakeConfirmed()
+			sess.run()
+		}()
+		Eventually(handshakeCompleted).Should(BeClosed())
+		times := sess.HandshakeTimes()
+		Expect(times.Complete).To(BeTemporally(">=", start))
+		Expect(times.Complete).To(BeTemporally(">=", times.Start))
+		// for the server, the handshake is confirmed as soon as it completes
+		Expect(times.Confirmed).To(Equal(times.Complete))
+		// make sure the go routine returns
+		sessionRunner.EXPECT().Retire(gomock.Any())
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+		cryptoSetup.EXPECT().Close()
+		Expect(sess.Close()).To(Succeed())
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
 	It("sends a forward-secure packet when the handshake completes", func() {
 		done := make(chan struct{})
 		gomock.InOrder(
@@ -1149,6 +1393,7 @@ var _ = Describe("Session", func() {
 		go func() {
 			defer GinkgoRecover()
 			cryptoSetup.EXPECT().RunHandshake()
+			cryptoSetup.EXPECT().SetHandshakeConfirmed()
 			sess.run()
 		}()
 		Eventually(done).Should(BeClosed())
@@ -1236,6 +1481,58 @@ var _ = Describe("Session", func() {
 			sess.Close()
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
+
+		It("passes custom transport parameters to the application", func() {
+			var received []byte
+			sess.config.TransportParameters = []TransportParameter{{
+				ID: 0xff42,
+				Unmarshal: func(b []byte) error {
+					received = b
+					return nil
+				},
+			}}
+			params := &handshake.TransportParameters{
+				CustomParameters: map[uint16][]byte{0xff42: []byte("foobar")},
+			}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sess.processTransportParameters(params.Marshal())
+			Expect(received).To(Equal([]byte("foobar")))
+		})
+
+		It("passes the max_ack_delay to the sent packet handler", func() {
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			params := &handshake.TransportParameters{MaxAckDelay: 42 * time.Millisecond}
+			streamManager.EXPECT().UpdateLimits(gomock.Any())
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			sph.EXPECT().SetMaxAckDelay(42 * time.Millisecond)
+			sess.processTransportParameters(params.Marshal())
+		})
+
+		It("closes the session if a custom transport parameter can't be unmarshaled", func() {
+			sess.config.TransportParameters = []TransportParameter{{
+				ID:        0xff42,
+				Unmarshal: func([]byte) error { return errors.New("invalid value") },
+			}}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+				err := sess.run()
+				Expect(err).To(MatchError("TRANSPORT_PARAMETER_ERROR: transport parameter 0xff42: invalid value"))
+				close(done)
+			}()
+			params := &handshake.TransportParameters{
+				CustomParameters: map[uint16][]byte{0xff42: []byte("foobar")},
+			}
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			sessionRunner.EXPECT().Retire(gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			cryptoSetup.EXPECT().Close()
+			sess.processTransportParameters(params.Marshal())
+			Eventually(done).Should(BeClosed())
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -1316,6 +1613,90 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	Context("pinging", func() {
+		var sph *mockackhandler.MockSentPacketHandler
+
+		BeforeEach(func() {
+			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().ReceivedAck(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			sph.EXPECT().GetLowestPacketNotConfirmedAcked().AnyTimes()
+			sess.sentPacketHandler = sph
+			cryptoSetup.EXPECT().SetLargest1RTTAcked(gomock.Any()).AnyTimes()
+		})
+
+		numPingRequests := func() int {
+			sess.pingMutex.Lock()
+			defer sess.pingMutex.Unlock()
+			return len(sess.pingRequests)
+		}
+
+		sendPing := func(pn protocol.PacketNumber) {
+			buffer := getPacketBuffer()
+			Expect(sess.sendPackedPacket(&packedPacket{
+				raw:    append(buffer.Slice[:0], []byte("foobar")...),
+				buffer: buffer,
+				header: &wire.ExtendedHeader{PacketNumber: pn},
+				frames: []wire.Frame{&wire.PingFrame{}},
+			})).To(Succeed())
+			Eventually(mconn.written).Should(Receive())
+		}
+
+		It("returns the round-trip time when the PING is acknowledged", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				rtt, err := sess.Ping(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rtt).To(BeNumerically(">=", 25*time.Millisecond))
+				Expect(rtt).To(BeNumerically("<", time.Second))
+				close(done)
+			}()
+			Eventually(numPingRequests).Should(Equal(1))
+			sendPing(10)
+			sess.lastPacketReceivedTime = time.Now().Add(25 * time.Millisecond)
+			// an ACK that doesn't acknowledge the packet containing the PING
+			Expect(sess.handleAckFrame(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 9}}}, 1, protocol.Encryption1RTT)).To(Succeed())
+			Consistently(done).ShouldNot(BeClosed())
+			Expect(sess.handleAckFrame(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 10, Largest: 10}}}, 2, protocol.Encryption1RTT)).To(Succeed())
+			Eventually(done).Should(BeClosed())
+			Expect(numPingRequests()).To(BeZero())
+		})
+
+		It("returns when a retransmission of the PING is acknowledged", func() {
+			rttChan := make(chan time.Duration)
+			go func() {
+				defer GinkgoRecover()
+				rtt, err := sess.Ping(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				rttChan <- rtt
+			}()
+			Eventually(numPingRequests).Should(Equal(1))
+			sendPing(10)
+			sendPing(11) // retransmission, after packet 10 was declared lost
+			sess.lastPacketReceivedTime = time.Now()
+			Expect(sess.handleAckFrame(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 11, Largest: 11}}}, 1, protocol.Encryption1RTT)).To(Succeed())
+			var rtt time.Duration
+			Eventually(rttChan).Should(Receive(&rtt))
+			Expect(rtt).ToNot(BeZero())
+		})
+
+		It("returns the context error when the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := sess.Ping(ctx)
+				Expect(err).To(MatchError(context.Canceled))
+				close(done)
+			}()
+			Eventually(numPingRequests).Should(Equal(1))
+			Consistently(done).ShouldNot(BeClosed())
+			cancel()
+			Eventually(done).Should(BeClosed())
+			Expect(numPingRequests()).To(BeZero())
+		})
+	})
+
 	Context("timeouts", func() {
 		BeforeEach(func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -1390,6 +1771,7 @@ var _ = Describe("Session", func() {
 				defer GinkgoRecover()
 				sessionRunner.EXPECT().OnHandshakeComplete(sess)
 				cryptoSetup.EXPECT().RunHandshake()
+				cryptoSetup.EXPECT().SetHandshakeConfirmed()
 				err := sess.run()
 				nerr, ok := err.(net.Error)
 				Expect(ok).To(BeTrue())
@@ -1487,16 +1869,20 @@ var _ = Describe("Session", func() {
 
 		It("accepts streams", func() {
 			mstr := NewMockStreamI(mockCtrl)
-			streamManager.EXPECT().AcceptStream().Return(mstr, nil)
-			str, err := sess.AcceptStream()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			streamManager.EXPECT().AcceptStream(ctx).Return(mstr, nil)
+			str, err := sess.AcceptStream(ctx)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
 		})
 
 		It("accepts unidirectional streams", func() {
 			mstr := NewMockReceiveStreamI(mockCtrl)
-			streamManager.EXPECT().AcceptUniStream().Return(mstr, nil)
-			str, err := sess.AcceptUniStream()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			streamManager.EXPECT().AcceptUniStream(ctx).Return(mstr, nil)
+			str, err := sess.AcceptUniStream(ctx)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
 		})
@@ -1513,6 +1899,22 @@ var _ = Describe("Session", func() {
 		mconn.remoteAddr = addr
 		Expect(sess.RemoteAddr()).To(Equal(addr))
 	})
+
+	It("exports keying material", func() {
+		cryptoSetup.EXPECT().ExportKeyingMaterial("label", []byte("context"), 16).Return([]byte("keying material"), nil)
+		km, err := sess.ExportKeyingMaterial("label", []byte("context"), 16)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(km).To(Equal([]byte("keying material")))
+	})
+
+	It("returns the number of application bytes sent and received", func() {
+		fc := mocks.NewMockConnectionFlowController(mockCtrl)
+		fc.EXPECT().ApplicationBytesSent().Return(protocol.ByteCount(1337))
+		fc.EXPECT().ApplicationBytesRead().Return(protocol.ByteCount(42))
+		sess.connFlowController = fc
+		Expect(sess.ApplicationBytesSent()).To(Equal(uint64(1337)))
+		Expect(sess.ApplicationBytesReceived()).To(Equal(uint64(42)))
+	})
 })
 
 var _ = Describe("Client Session", func() {
@@ -1574,6 +1976,8 @@ var _ = Describe("Client Session", func() {
 			cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() }).AnyTimes()
 			sess.run()
 		}()
+		// the client confirms the handshake when it receives the first 1-RTT packet
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
 		newConnID := protocol.ConnectionID{1, 3, 3, 7, 1, 3, 3, 7}
 		packer.EXPECT().ChangeDestConnectionID(newConnID)
 		Expect(sess.handlePacketImpl(getPacket(&wire.ExtendedHeader{
@@ -1594,6 +1998,129 @@ var _ = Describe("Client Session", func() {
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	It("records the time of the first response and of the handshake confirmation", func() {
+		unpacker := NewMockUnpacker(mockCtrl)
+		unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, data []byte) (*unpackedPacket, error) {
+			return &unpackedPacket{
+				encryptionLevel: protocol.EncryptionHandshake,
+				hdr:             &wire.ExtendedHeader{Header: *hdr},
+				data:            []byte{0}, // one PADDING frame
+			}, nil
+		})
+		unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, data []byte) (*unpackedPacket, error) {
+			return &unpackedPacket{
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             &wire.ExtendedHeader{Header: *hdr},
+				data:            []byte{0}, // one PADDING frame
+			}, nil
+		})
+		sess.unpacker = unpacker
+		hdr := &wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeHandshake,
+				SrcConnectionID:  sess.destConnID,
+				DestConnectionID: sess.srcConnID,
+				Length:           1,
+			},
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}
+		firstPacket := getPacket(hdr, []byte{0})
+		firstPacket.rcvTime = time.Now().Add(-time.Second)
+		Expect(sess.handlePacketImpl(firstPacket)).To(BeTrue())
+		times := sess.HandshakeTimes()
+		Expect(times.FirstResponse).To(Equal(firstPacket.rcvTime))
+		Expect(times.Confirmed).To(BeZero())
+		hdr.PacketNumber = 1
+		secondPacket := getPacket(hdr, []byte{0})
+		secondPacket.rcvTime = time.Now()
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		Expect(sess.handlePacketImpl(secondPacket)).To(BeTrue())
+		times = sess.HandshakeTimes()
+		Expect(times.FirstResponse).To(Equal(firstPacket.rcvTime))
+		Expect(times.Confirmed).To(Equal(secondPacket.rcvTime))
+	})
+
+	It("switches to a zero-length connection ID chosen by the server", func() {
+		unpacker := NewMockUnpacker(mockCtrl)
+		unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, data []byte) (*unpackedPacket, error) {
+			return &unpackedPacket{
+				encryptionLevel: protocol.EncryptionHandshake,
+				hdr:             &wire.ExtendedHeader{Header: *hdr},
+				data:            []byte{0}, // one PADDING frame
+			}, nil
+		})
+		sess.unpacker = unpacker
+		packer.EXPECT().ChangeDestConnectionID(protocol.ConnectionID(nil))
+		Expect(sess.handlePacketImpl(getPacket(&wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeHandshake,
+				DestConnectionID: sess.srcConnID,
+				Length:           3,
+				Version:          sess.version,
+			},
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}, []byte{0}))).To(BeTrue())
+		Expect(sess.destConnID.Len()).To(BeZero())
+	})
+
+	Context("using a zero-length connection ID", func() {
+		var unpacker *MockUnpacker
+
+		BeforeEach(func() {
+			// This is the session that DialAddr creates when the config doesn't set a ConnectionIDLength.
+			mconn = newMockConnection()
+			mconn.remoteAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+			config := populateClientConfig(&Config{}, true)
+			Expect(config.ConnectionIDLength).To(BeZero())
+			srcConnID, err := protocol.GenerateConnectionID(config.ConnectionIDLength)
+			Expect(err).ToNot(HaveOccurred())
+			sessP, err := newClientSession(
+				mconn,
+				sessionRunner,
+				protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8},
+				srcConnID,
+				config,
+				nil, // tls.Config
+				42,  // initial packet number
+				&handshake.TransportParameters{},
+				protocol.VersionTLS,
+				utils.DefaultLogger,
+				protocol.VersionTLS,
+			)
+			Expect(err).ToNot(HaveOccurred())
+			sess = sessP.(*session)
+			Expect(sess.srcConnID.Len()).To(BeZero())
+			unpacker = NewMockUnpacker(mockCtrl)
+			sess.unpacker = unpacker
+		})
+
+		getShortHeaderPacket := func(remoteAddr net.Addr) *receivedPacket {
+			p := getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: sess.srcConnID},
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, []byte{0})
+			p.remoteAddr = remoteAddr
+			return p
+		}
+
+		It("processes packets sent from the peer's address", func() {
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             &wire.ExtendedHeader{},
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			Expect(sess.handlePacketImpl(getShortHeaderPacket(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}))).To(BeTrue())
+		})
+
+		It("drops packets sent from a different address", func() {
+			// don't EXPECT any calls to unpacker.Unpack()
+			Expect(sess.handlePacketImpl(getShortHeaderPacket(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1338}))).To(BeFalse())
+			Expect(sess.handlePacketImpl(getShortHeaderPacket(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 1337}))).To(BeFalse())
+		})
+	})
+
 	Context("handling Retry", func() {
 		var validRetryHdr *wire.ExtendedHeader
 
@@ -1667,17 +2194,42 @@ var _ = Describe("Client Session", func() {
 				StatelessResetToken:  &[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
 			}
 			_, err := sess.processTransportParametersForClient(params.Marshal())
-			Expect(err).To(MatchError("expected original_connection_id to equal (empty), is 0xdecafbad"))
+			Expect(err).To(MatchError(qerr.Error(qerr.TransportParameterError, "expected original_connection_id to equal (empty), is 0xdecafbad")))
 		})
 
-		It("errors if the TransportParameters contain an original_connection_id, although no Retry was performed", func() {
+		It("errors if the TransportParameters contain a wrong original_connection_id", func() {
 			sess.origDestConnID = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
 			params := &handshake.TransportParameters{
 				OriginalConnectionID: protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
 				StatelessResetToken:  &[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
 			}
 			_, err := sess.processTransportParametersForClient(params.Marshal())
-			Expect(err).To(MatchError("expected original_connection_id to equal 0xdeadbeef, is 0xdecafbad"))
+			Expect(err).To(MatchError(qerr.Error(qerr.TransportParameterError, "expected original_connection_id to equal 0xdeadbeef, is 0xdecafbad")))
+		})
+
+		It("closes the connection with a TRANSPORT_PARAMETER_ERROR", func() {
+			sess.origDestConnID = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
+			params := &handshake.TransportParameters{
+				OriginalConnectionID: protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+				StatelessResetToken:  &[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().Do(func() { <-sess.Context().Done() })
+				err := sess.run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.TransportParameterError))
+				close(done)
+			}()
+			sessionRunner.EXPECT().Retire(gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(f *wire.ConnectionCloseFrame) (*packedPacket, error) {
+				Expect(f.ErrorCode).To(Equal(qerr.TransportParameterError))
+				return &packedPacket{}, nil
+			})
+			cryptoSetup.EXPECT().Close()
+			sess.processTransportParameters(params.Marshal())
+			Eventually(done).Should(BeClosed())
 		})
 	})
 })
@@ -52,6 +52,7 @@ type streamI interface {
 	hasData() bool
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool)
+	getWeight() uint8
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
 }
 
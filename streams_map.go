@@ -1,9 +1,11 @@
 package quic
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
@@ -43,6 +45,7 @@ func newStreamsMap(
 	maxIncomingUniStreams uint64,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
+	writeCoalescingDelay time.Duration,
 ) streamManager {
 	m := &streamsMap{
 		perspective:       perspective,
@@ -50,10 +53,14 @@ func newStreamsMap(
 		sender:            sender,
 	}
 	newBidiStream := func(id protocol.StreamID) streamI {
-		return newStream(id, m.sender, m.newFlowController(id), version)
+		str := newStream(id, m.sender, m.newFlowController(id), version)
+		str.coalescingDelay = writeCoalescingDelay
+		return str
 	}
 	newUniSendStream := func(id protocol.StreamID) sendStreamI {
-		return newSendStream(id, m.sender, m.newFlowController(id), version)
+		str := newSendStream(id, m.sender, m.newFlowController(id), version)
+		str.coalescingDelay = writeCoalescingDelay
+		return str
 	}
 	newUniReceiveStream := func(id protocol.StreamID) receiveStreamI {
 		return newReceiveStream(id, m.sender, m.newFlowController(id), version)
@@ -101,12 +108,12 @@ func (m *streamsMap) OpenUniStreamSync() (SendStream, error) {
 	return m.outgoingUniStreams.OpenStreamSync()
 }
 
-func (m *streamsMap) AcceptStream() (Stream, error) {
-	return m.incomingBidiStreams.AcceptStream()
+func (m *streamsMap) AcceptStream(ctx context.Context) (Stream, error) {
+	return m.incomingBidiStreams.AcceptStream(ctx)
 }
 
-func (m *streamsMap) AcceptUniStream() (ReceiveStream, error) {
-	return m.incomingUniStreams.AcceptStream()
+func (m *streamsMap) AcceptUniStream(ctx context.Context) (ReceiveStream, error) {
+	return m.incomingUniStreams.AcceptStream(ctx)
 }
 
 func (m *streamsMap) DeleteStream(id protocol.StreamID) error {
@@ -190,3 +197,15 @@ func (m *streamsMap) CloseWithError(err error) {
 	m.incomingBidiStreams.CloseWithError(err)
 	m.incomingUniStreams.CloseWithError(err)
 }
+
+// ResetAll cancels reading and writing on all streams, using the same error code.
+func (m *streamsMap) ResetAll(code protocol.ApplicationErrorCode) {
+	cancelBidi := func(str streamI) {
+		str.CancelWrite(code)
+		str.CancelRead(code)
+	}
+	m.outgoingBidiStreams.ForEach(cancelBidi)
+	m.incomingBidiStreams.ForEach(cancelBidi)
+	m.outgoingUniStreams.ForEach(func(str sendStreamI) { str.CancelWrite(code) })
+	m.incomingUniStreams.ForEach(func(str receiveStreamI) { str.CancelRead(code) })
+}
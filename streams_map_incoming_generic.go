@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -11,8 +12,8 @@ import (
 //go:generate genny -in $GOFILE -out streams_map_incoming_bidi.go gen "item=streamI Item=BidiStream streamTypeGeneric=protocol.StreamTypeBidi"
 //go:generate genny -in $GOFILE -out streams_map_incoming_uni.go gen "item=receiveStreamI Item=UniStream streamTypeGeneric=protocol.StreamTypeUni"
 type incomingItemsMap struct {
-	mutex sync.RWMutex
-	cond  sync.Cond
+	mutex         sync.RWMutex
+	newStreamChan chan struct{}
 
 	streams map[protocol.StreamID]item
 	// When a stream is deleted before it was accepted, we can't delete it immediately.
@@ -37,7 +38,8 @@ func newIncomingItemsMap(
 	queueControlFrame func(wire.Frame),
 	newStream func(protocol.StreamID) item,
 ) *incomingItemsMap {
-	m := &incomingItemsMap{
+	return &incomingItemsMap{
+		newStreamChan:      make(chan struct{}, 1),
 		streams:            make(map[protocol.StreamID]item),
 		streamsToDelete:    make(map[protocol.StreamID]struct{}),
 		nextStreamToAccept: nextStreamToAccept,
@@ -47,11 +49,9 @@ func newIncomingItemsMap(
 		newStream:          newStream,
 		queueMaxStreamID:   func(f *wire.MaxStreamsFrame) { queueControlFrame(f) },
 	}
-	m.cond.L = &m.mutex
-	return m
 }
 
-func (m *incomingItemsMap) AcceptStream() (item, error) {
+func (m *incomingItemsMap) AcceptStream(ctx context.Context) (item, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -67,9 +67,20 @@ func (m *incomingItemsMap) AcceptStream() (item, error) {
 		if ok {
 			break
 		}
-		m.cond.Wait()
+		m.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			m.mutex.Lock()
+			return nil, ctx.Err()
+		case <-m.newStreamChan:
+		}
+		m.mutex.Lock()
 	}
 	m.nextStreamToAccept += 4
+	// If the next stream is already available, make sure that another call to AcceptStream doesn't block.
+	if _, ok := m.streams[m.nextStreamToAccept]; ok {
+		m.signalNewStream()
+	}
 	// If this stream was completed before being accepted, we can delete it now.
 	if _, ok := m.streamsToDelete[id]; ok {
 		delete(m.streamsToDelete, id)
@@ -106,14 +117,26 @@ func (m *incomingItemsMap) GetOrOpenStream(id protocol.StreamID) (item, error) {
 	// * highestStream is only modified by this function
 	for newID := m.nextStreamToOpen; newID <= id; newID += 4 {
 		m.streams[newID] = m.newStream(newID)
-		m.cond.Signal()
 	}
+	m.signalNewStream()
 	m.nextStreamToOpen = id + 4
 	s := m.streams[id]
 	m.mutex.Unlock()
 	return s, nil
 }
 
+// signalNewStream wakes up a call to AcceptStream that is waiting for a new stream.
+// It must be called with the mutex held.
+func (m *incomingItemsMap) signalNewStream() {
+	if m.closeErr != nil {
+		return
+	}
+	select {
+	case m.newStreamChan <- struct{}{}:
+	default:
+	}
+}
+
 func (m *incomingItemsMap) DeleteStream(id protocol.StreamID) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -151,10 +174,28 @@ func (m *incomingItemsMap) deleteStream(id protocol.StreamID) error {
 
 func (m *incomingItemsMap) CloseWithError(err error) {
 	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closeErr == nil {
+		// unblock all calls to AcceptStream
+		close(m.newStreamChan)
+	}
 	m.closeErr = err
 	for _, str := range m.streams {
 		str.closeForShutdown(err)
 	}
-	m.mutex.Unlock()
-	m.cond.Broadcast()
+}
+
+// ForEach calls f for all streams in the map.
+// The map is not locked while f is called, so f may delete streams from the map.
+func (m *incomingItemsMap) ForEach(f func(item)) {
+	m.mutex.RLock()
+	streams := make([]item, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	m.mutex.RUnlock()
+	for _, str := range streams {
+		f(str)
+	}
 }
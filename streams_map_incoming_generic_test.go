@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -67,10 +68,10 @@ var _ = Describe("Streams Map (incoming)", func() {
 	It("accepts streams in the right order", func() {
 		_, err := m.GetOrOpenStream(firstNewStream + 4) // open stream 20 and 24
 		Expect(err).ToNot(HaveOccurred())
-		str, err := m.AcceptStream()
+		str, err := m.AcceptStream(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(str.(*mockGenericStream).id).To(Equal(firstNewStream))
-		str, err = m.AcceptStream()
+		str, err = m.AcceptStream(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(str.(*mockGenericStream).id).To(Equal(firstNewStream + 4))
 	})
@@ -90,7 +91,7 @@ var _ = Describe("Streams Map (incoming)", func() {
 		strChan := make(chan item)
 		go func() {
 			defer GinkgoRecover()
-			str, err := m.AcceptStream()
+			str, err := m.AcceptStream(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 			strChan <- str
 		}()
@@ -108,7 +109,7 @@ var _ = Describe("Streams Map (incoming)", func() {
 		strChan := make(chan item)
 		go func() {
 			defer GinkgoRecover()
-			str, err := m.AcceptStream()
+			str, err := m.AcceptStream(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 			strChan <- str
 		}()
@@ -126,7 +127,7 @@ var _ = Describe("Streams Map (incoming)", func() {
 		done := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
-			_, err := m.AcceptStream()
+			_, err := m.AcceptStream(context.Background())
 			Expect(err).To(MatchError(testErr))
 			close(done)
 		}()
@@ -138,10 +139,88 @@ var _ = Describe("Streams Map (incoming)", func() {
 	It("errors AcceptStream immediately if it is closed", func() {
 		testErr := errors.New("test error")
 		m.CloseWithError(testErr)
-		_, err := m.AcceptStream()
+		_, err := m.AcceptStream(context.Background())
 		Expect(err).To(MatchError(testErr))
 	})
 
+	It("unblocks AcceptStream when the context is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			_, err := m.AcceptStream(ctx)
+			Expect(err).To(MatchError(context.Canceled))
+			close(done)
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		cancel()
+		Eventually(done).Should(BeClosed())
+		// the next stream can still be accepted
+		_, err := m.GetOrOpenStream(firstNewStream)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := m.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.(*mockGenericStream).id).To(Equal(firstNewStream))
+	})
+
+	It("errors AcceptStream immediately if the context is already canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := m.AcceptStream(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("unblocks multiple calls to AcceptStream when it is closed", func() {
+		testErr := errors.New("test error")
+		errChan := make(chan error, 3)
+		for i := 0; i < 3; i++ {
+			go func() {
+				_, err := m.AcceptStream(context.Background())
+				errChan <- err
+			}()
+		}
+		Consistently(errChan).ShouldNot(Receive())
+		m.CloseWithError(testErr)
+		for i := 0; i < 3; i++ {
+			Eventually(errChan).Should(Receive(MatchError(testErr)))
+		}
+	})
+
+	It("doesn't panic when it is closed multiple times, or a stream is opened after closing", func() {
+		m.CloseWithError(errors.New("test error"))
+		m.CloseWithError(errors.New("another test error"))
+		_, err := m.GetOrOpenStream(firstNewStream)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("unblocks multiple calls to AcceptStream when multiple streams are opened at once", func() {
+		strChan := make(chan item, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				defer GinkgoRecover()
+				str, err := m.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				strChan <- str
+			}()
+		}
+		Consistently(strChan).ShouldNot(Receive())
+		_, err := m.GetOrOpenStream(firstNewStream + 4) // opens two streams
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(strChan).Should(HaveLen(2))
+	})
+
+	It("iterates over all streams, including streams that were not accepted yet", func() {
+		_, err := m.GetOrOpenStream(firstNewStream + 4) // opens two streams
+		Expect(err).ToNot(HaveOccurred())
+		_, err = m.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		var ids []protocol.StreamID
+		m.ForEach(func(str item) {
+			ids = append(ids, str.(*mockGenericStream).id)
+		})
+		Expect(ids).To(ConsistOf(firstNewStream, firstNewStream+4))
+	})
+
 	It("closes all streams when CloseWithError is called", func() {
 		str1, err := m.GetOrOpenStream(firstNewStream)
 		Expect(err).ToNot(HaveOccurred())
@@ -159,7 +238,7 @@ var _ = Describe("Streams Map (incoming)", func() {
 		mockSender.EXPECT().queueControlFrame(gomock.Any())
 		_, err := m.GetOrOpenStream(firstNewStream)
 		Expect(err).ToNot(HaveOccurred())
-		str, err := m.AcceptStream()
+		str, err := m.AcceptStream(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(str.(*mockGenericStream).id).To(Equal(firstNewStream))
 		Expect(m.DeleteStream(firstNewStream)).To(Succeed())
@@ -172,12 +251,12 @@ var _ = Describe("Streams Map (incoming)", func() {
 		_, err := m.GetOrOpenStream(firstNewStream + 4)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(m.DeleteStream(firstNewStream + 4)).To(Succeed())
-		str, err := m.AcceptStream()
+		str, err := m.AcceptStream(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(str.(*mockGenericStream).id).To(Equal(firstNewStream))
 		// when accepting this stream, it will get deleted, and a MAX_STREAMS frame is queued
 		mockSender.EXPECT().queueControlFrame(gomock.Any())
-		str, err = m.AcceptStream()
+		str, err = m.AcceptStream(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(str.(*mockGenericStream).id).To(Equal(firstNewStream + 4))
 	})
@@ -192,7 +271,7 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(str).To(BeNil())
 		// when accepting this stream, it will get deleted, and a MAX_STREAMS frame is queued
 		mockSender.EXPECT().queueControlFrame(gomock.Any())
-		str, err = m.AcceptStream()
+		str, err = m.AcceptStream(context.Background())
 		Expect(err).ToNot(HaveOccurred())
 		Expect(str).ToNot(BeNil())
 	})
@@ -208,7 +287,7 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(err).ToNot(HaveOccurred())
 		// accept all streams
 		for i := 0; i < 5; i++ {
-			_, err := m.AcceptStream()
+			_, err := m.AcceptStream(context.Background())
 			Expect(err).ToNot(HaveOccurred())
 		}
 		mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
@@ -5,6 +5,7 @@
 package quic
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -13,8 +14,8 @@ import (
 )
 
 type incomingUniStreamsMap struct {
-	mutex sync.RWMutex
-	cond  sync.Cond
+	mutex         sync.RWMutex
+	newStreamChan chan struct{}
 
 	streams map[protocol.StreamID]receiveStreamI
 	// When a stream is deleted before it was accepted, we can't delete it immediately.
@@ -39,7 +40,8 @@ func newIncomingUniStreamsMap(
 	queueControlFrame func(wire.Frame),
 	newStream func(protocol.StreamID) receiveStreamI,
 ) *incomingUniStreamsMap {
-	m := &incomingUniStreamsMap{
+	return &incomingUniStreamsMap{
+		newStreamChan:      make(chan struct{}, 1),
 		streams:            make(map[protocol.StreamID]receiveStreamI),
 		streamsToDelete:    make(map[protocol.StreamID]struct{}),
 		nextStreamToAccept: nextStreamToAccept,
@@ -49,11 +51,9 @@ func newIncomingUniStreamsMap(
 		newStream:          newStream,
 		queueMaxStreamID:   func(f *wire.MaxStreamsFrame) { queueControlFrame(f) },
 	}
-	m.cond.L = &m.mutex
-	return m
 }
 
-func (m *incomingUniStreamsMap) AcceptStream() (receiveStreamI, error) {
+func (m *incomingUniStreamsMap) AcceptStream(ctx context.Context) (receiveStreamI, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -69,9 +69,20 @@ func (m *incomingUniStreamsMap) AcceptStream() (receiveStreamI, error) {
 		if ok {
 			break
 		}
-		m.cond.Wait()
+		m.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			m.mutex.Lock()
+			return nil, ctx.Err()
+		case <-m.newStreamChan:
+		}
+		m.mutex.Lock()
 	}
 	m.nextStreamToAccept += 4
+	// If the next stream is already available, make sure that another call to AcceptStream doesn't block.
+	if _, ok := m.streams[m.nextStreamToAccept]; ok {
+		m.signalNewStream()
+	}
 	// If this stream was completed before being accepted, we can delete it now.
 	if _, ok := m.streamsToDelete[id]; ok {
 		delete(m.streamsToDelete, id)
@@ -108,14 +119,26 @@ func (m *incomingUniStreamsMap) GetOrOpenStream(id protocol.StreamID) (receiveSt
 	// * highestStream is only modified by this function
 	for newID := m.nextStreamToOpen; newID <= id; newID += 4 {
 		m.streams[newID] = m.newStream(newID)
-		m.cond.Signal()
 	}
+	m.signalNewStream()
 	m.nextStreamToOpen = id + 4
 	s := m.streams[id]
 	m.mutex.Unlock()
 	return s, nil
 }
 
+// signalNewStream wakes up a call to AcceptStream that is waiting for a new stream.
+// It must be called with the mutex held.
+func (m *incomingUniStreamsMap) signalNewStream() {
+	if m.closeErr != nil {
+		return
+	}
+	select {
+	case m.newStreamChan <- struct{}{}:
+	default:
+	}
+}
+
 func (m *incomingUniStreamsMap) DeleteStream(id protocol.StreamID) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -153,10 +176,28 @@ func (m *incomingUniStreamsMap) deleteStream(id protocol.StreamID) error {
 
 func (m *incomingUniStreamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closeErr == nil {
+		// unblock all calls to AcceptStream
+		close(m.newStreamChan)
+	}
 	m.closeErr = err
 	for _, str := range m.streams {
 		str.closeForShutdown(err)
 	}
-	m.mutex.Unlock()
-	m.cond.Broadcast()
+}
+
+// ForEach calls f for all streams in the map.
+// The map is not locked while f is called, so f may delete streams from the map.
+func (m *incomingUniStreamsMap) ForEach(f func(receiveStreamI)) {
+	m.mutex.RLock()
+	streams := make([]receiveStreamI, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	m.mutex.RUnlock()
+	for _, str := range streams {
+		f(str)
+	}
 }
@@ -145,3 +145,17 @@ func (m *outgoingBidiStreamsMap) CloseWithError(err error) {
 	m.cond.Broadcast()
 	m.mutex.Unlock()
 }
+
+// ForEach calls f for all streams in the map.
+// The map is not locked while f is called, so f may delete streams from the map.
+func (m *outgoingBidiStreamsMap) ForEach(f func(streamI)) {
+	m.mutex.RLock()
+	streams := make([]streamI, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	m.mutex.RUnlock()
+	for _, str := range streams {
+		f(str)
+	}
+}
@@ -86,6 +86,29 @@ var _ = Describe("Streams Map (outgoing)", func() {
 			Expect(err).To(MatchError("Tried to delete unknown stream 3"))
 		})
 
+		It("iterates over all streams", func() {
+			_, err := m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			var ids []protocol.StreamID
+			m.ForEach(func(str item) {
+				ids = append(ids, str.(*mockGenericStream).id)
+			})
+			Expect(ids).To(ConsistOf(firstNewStream, firstNewStream+4))
+		})
+
+		It("allows deleting streams while iterating", func() {
+			_, err := m.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			m.ForEach(func(str item) {
+				Expect(m.DeleteStream(str.(*mockGenericStream).id)).To(Succeed())
+			})
+			str, err := m.GetStream(firstNewStream)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(BeNil())
+		})
+
 		It("closes all streams when CloseWithError is called", func() {
 			str1, err := m.OpenStream()
 			Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,53 @@
+package quic
+
+import (
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+)
+
+// validateTransportParameters checks that all additional transport parameters use IDs from the private-use range,
+// and that every ID is only used once.
+func validateTransportParameters(params []TransportParameter) error {
+	ids := make(map[uint16]struct{}, len(params))
+	for _, p := range params {
+		if p.ID < protocol.MinPrivateUseTransportParameterID {
+			return fmt.Errorf("transport parameter ID %#x is not in the private-use range", p.ID)
+		}
+		if _, ok := ids[p.ID]; ok {
+			return fmt.Errorf("duplicate transport parameter ID %#x", p.ID)
+		}
+		ids[p.ID] = struct{}{}
+	}
+	return nil
+}
+
+func marshalTransportParameters(params []TransportParameter) map[uint16][]byte {
+	if len(params) == 0 {
+		return nil
+	}
+	m := make(map[uint16][]byte, len(params))
+	for _, p := range params {
+		var val []byte
+		if p.Marshal != nil {
+			val = p.Marshal()
+		}
+		m[p.ID] = val
+	}
+	return m
+}
+
+// unmarshalTransportParameters passes the values received from the peer to the additional transport parameters.
+func unmarshalTransportParameters(params []TransportParameter, received map[uint16][]byte) error {
+	for _, p := range params {
+		val, ok := received[p.ID]
+		if !ok || p.Unmarshal == nil {
+			continue
+		}
+		if err := p.Unmarshal(val); err != nil {
+			return qerr.Error(qerr.TransportParameterError, fmt.Sprintf("transport parameter %#x: %s", p.ID, err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package quic
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Custom Transport Parameters", func() {
+	It("accepts IDs from the private-use range", func() {
+		Expect(validateTransportParameters([]TransportParameter{{ID: 0xff00}, {ID: 0xffff}})).To(Succeed())
+	})
+
+	It("rejects IDs outside of the private-use range", func() {
+		Expect(validateTransportParameters([]TransportParameter{{ID: 0x3}})).To(MatchError("transport parameter ID 0x3 is not in the private-use range"))
+		Expect(validateTransportParameters([]TransportParameter{{ID: 0xfeff}})).To(MatchError("transport parameter ID 0xfeff is not in the private-use range"))
+	})
+
+	It("rejects duplicate IDs", func() {
+		Expect(validateTransportParameters([]TransportParameter{{ID: 0xff42}, {ID: 0xff42}})).To(MatchError("duplicate transport parameter ID 0xff42"))
+	})
+
+	It("marshals", func() {
+		params := []TransportParameter{
+			{ID: 0xff01, Marshal: func() []byte { return []byte("foobar") }},
+			{ID: 0xff02},
+		}
+		Expect(marshalTransportParameters(params)).To(Equal(map[uint16][]byte{
+			0xff01: []byte("foobar"),
+			0xff02: nil,
+		}))
+		Expect(marshalTransportParameters(nil)).To(BeNil())
+	})
+
+	It("unmarshals", func() {
+		var received []byte
+		var called bool
+		params := []TransportParameter{
+			{ID: 0xff01, Unmarshal: func(b []byte) error { received = b; return nil }},
+			{ID: 0xff02, Unmarshal: func([]byte) error { called = true; return nil }},
+			{ID: 0xff03},
+		}
+		Expect(unmarshalTransportParameters(params, map[uint16][]byte{
+			0xff01: []byte("foobar"),
+			0xff03: []byte("raboof"),
+		})).To(Succeed())
+		Expect(received).To(Equal([]byte("foobar")))
+		Expect(called).To(BeFalse())
+	})
+
+	It("returns a TRANSPORT_PARAMETER_ERROR when unmarshaling fails", func() {
+		params := []TransportParameter{
+			{ID: 0xff01, Unmarshal: func([]byte) error { return errors.New("invalid value") }},
+		}
+		err := unmarshalTransportParameters(params, map[uint16][]byte{0xff01: []byte("foobar")})
+		Expect(err).To(MatchError("TRANSPORT_PARAMETER_ERROR: transport parameter 0xff01: invalid value"))
+	})
+})